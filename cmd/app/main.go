@@ -2,31 +2,128 @@ package main
 
 import (
 	"chats-api/internal/config"
+	"chats-api/internal/importer"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
 	"chats-api/internal/server"
-	"github.com/joho/godotenv"
+	"chats-api/internal/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"strings"
 )
 
+const usage = `usage: chats-api [command] [flags]
+
+commands:
+  serve          run the API server (default)
+  config print   print the effective configuration with secrets redacted
+  import         import chats from a file: chats-api import [-format f] [-title t] [-dry-run] FILE [flags]
+
+run "chats-api <command> -h" to list the flags`
+
 func main() {
-	if os.Getenv("APP_ENV") != "docker" {
-		if err := godotenv.Load(); err != nil {
-			log.Fatal("error loading .env file ", err.Error())
+	if err := run(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
 			return
 		}
+		log.Fatal(err.Error())
 	}
+}
 
-	conf, err := config.NewConfig()
+func run(args []string) error {
+	cmd := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "serve":
+		return serve(args)
+	case "config":
+		if len(args) == 0 || args[0] != "print" {
+			return errors.New("usage: chats-api config print [flags]")
+		}
+		return printConfig(args[1:])
+	case "import":
+		return importChats(args)
+	case "help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n%s", cmd, usage)
+	}
+}
+
+func serve(args []string) error {
+	conf, err := config.Load(args)
 	if err != nil {
-		log.Fatal("error loading config ", err.Error())
+		return errors.New("error loading config " + err.Error())
 	}
+
 	srv, err := server.NewServer(conf)
 	if err != nil {
-		log.Fatal("error initializing server ", err.Error())
+		return errors.New("error initializing server " + err.Error())
 	}
 
 	if err := srv.Start(); err != nil {
-		log.Fatal("error starting server ", err.Error())
+		return errors.New("error starting server " + err.Error())
+	}
+	return nil
+}
+
+func printConfig(args []string) error {
+	conf, err := config.Load(args)
+	if err != nil {
+		return errors.New("error loading config " + err.Error())
+	}
+
+	return config.Print(os.Stdout, conf)
+}
+
+// importChats takes its own flags, then the file, then the usual config flags.
+func importChats(args []string) error {
+	fs := flag.NewFlagSet("chats-api import", flag.ContinueOnError)
+	format := fs.String("format", importer.FormatNDJSON, "input format: ndjson, slack or telegram")
+	title := fs.String("title", "", "chat title for a single Slack channel file")
+	dryRun := fs.Bool("dry-run", false, "validate the file and report what would be imported")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: chats-api import [-format f] [-title t] [-dry-run] FILE [flags]")
+	}
+
+	conf, err := config.Load(fs.Args()[1:])
+	if err != nil {
+		return errors.New("error loading config " + err.Error())
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	db, err := model.NewDB(conf.PostgresConf)
+	if err != nil {
+		return errors.New("db error: " + err.Error())
+	}
+
+	report, err := services.NewImportService(repository.NewChatsRepo(db)).Import(context.Background(), *format, f, *title, *dryRun)
+	var invalid *services.ImportError
+	if errors.As(err, &invalid) {
+		return fmt.Errorf("error reading %s: %w", fs.Arg(0), invalid.Err)
+	}
+	if err != nil {
+		return errors.New("error importing " + err.Error())
 	}
 
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
 }
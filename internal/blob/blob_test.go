@@ -0,0 +1,69 @@
+package blob_test
+
+import (
+	"chats-api/internal/blob"
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testStore(t *testing.T, store blob.Store) {
+	ctx := context.Background()
+	const key = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	exists, err := store.Exists(ctx, key)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	_, err = store.Get(ctx, key)
+	require.ErrorIs(t, err, blob.ErrNotFound)
+
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("test"), 4, "text/plain"))
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("test"), 4, "text/plain"))
+
+	exists, err = store.Exists(ctx, key)
+	require.NoError(t, err)
+	require.True(t, exists)
+
+	rc, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	b, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	require.Equal(t, "test", string(b))
+
+	require.NoError(t, store.Delete(ctx, key))
+	require.NoError(t, store.Delete(ctx, key))
+
+	exists, err = store.Exists(ctx, key)
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func TestLocalStore(t *testing.T) {
+	store, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	testStore(t, store)
+}
+
+// Run against MinIO from docker-compose with
+// S3_TEST_ENDPOINT=localhost:9000 S3_TEST_ACCESS_KEY=minioadmin S3_TEST_SECRET_KEY=minioadmin.
+func TestS3Store(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT is not set")
+	}
+
+	store, err := blob.NewS3Store(context.Background(), blob.S3Config{
+		Endpoint:  endpoint,
+		Bucket:    "chats-api-test",
+		AccessKey: os.Getenv("S3_TEST_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_TEST_SECRET_KEY"),
+	})
+	require.NoError(t, err)
+	testStore(t, store)
+}
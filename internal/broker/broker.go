@@ -0,0 +1,23 @@
+package broker
+
+import (
+	"context"
+)
+
+// Message is an event published to a subject (a NATS subject or a Kafka
+// topic). Id stays the same when a message is published again, so brokers
+// and consumers can drop duplicates. Messages with the same Key keep their
+// relative order where the broker supports it.
+type Message struct {
+	Id      string
+	Subject string
+	Key     string
+	Payload []byte
+}
+
+// Broker publishes messages. Publish returns only once the broker has
+// accepted every message; on error any of them may have been published.
+type Broker interface {
+	Publish(ctx context.Context, messages []Message) error
+	Close() error
+}
@@ -0,0 +1,71 @@
+package broker_test
+
+import (
+	"chats-api/internal/broker"
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory(t *testing.T) {
+	b := broker.NewMemory()
+	var got []broker.Message
+	cancel := b.Subscribe(func(m broker.Message) { got = append(got, m) })
+
+	msgs := []broker.Message{
+		{Id: "1", Subject: "chats.chat.created", Key: "c-1", Payload: []byte(`{}`)},
+		{Id: "2", Subject: "chats.message.created", Key: "c-1", Payload: []byte(`{}`)},
+	}
+	require.NoError(t, b.Publish(context.Background(), msgs))
+	require.Equal(t, msgs, got)
+
+	cancel()
+	require.NoError(t, b.Publish(context.Background(), msgs[:1]))
+	require.Len(t, got, 2)
+}
+
+// TestNATS runs against a JetStream-enabled server, e.g.
+// NATS_TEST_URL=nats://localhost:4222.
+func TestNATS(t *testing.T) {
+	url := os.Getenv("NATS_TEST_URL")
+	if url == "" {
+		t.Skip("NATS_TEST_URL is not set")
+	}
+	ctx := context.Background()
+
+	b, err := broker.NewNATS(ctx, broker.NATSConfig{Url: url, Stream: "CHATS_TEST", Subject: "chats-test"})
+	require.NoError(t, err)
+	defer b.Close()
+
+	conn, err := nats.Connect(url)
+	require.NoError(t, err)
+	defer conn.Close()
+	sub, err := conn.SubscribeSync("chats-test.>")
+	require.NoError(t, err)
+
+	require.NoError(t, b.Publish(ctx, []broker.Message{{Id: time.Now().Format(time.RFC3339Nano), Subject: "chats-test.message.created", Payload: []byte(`{"id":"1"}`)}}))
+	msg, err := sub.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	require.Equal(t, `{"id":"1"}`, string(msg.Data))
+}
+
+// TestKafka runs against a broker that auto-creates topics, e.g.
+// KAFKA_TEST_BROKERS=localhost:9092.
+func TestKafka(t *testing.T) {
+	brokers := os.Getenv("KAFKA_TEST_BROKERS")
+	if brokers == "" {
+		t.Skip("KAFKA_TEST_BROKERS is not set")
+	}
+
+	b := broker.NewKafka(broker.KafkaConfig{Brokers: strings.Split(brokers, ",")})
+	defer b.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	require.NoError(t, b.Publish(ctx, []broker.Message{{Id: "1", Subject: "chats-test.message.created", Key: "c-1", Payload: []byte(`{"id":"1"}`)}}))
+}
@@ -0,0 +1,44 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type KafkaConfig struct {
+	Brokers []string
+}
+
+type kafkaBroker struct {
+	writer *kafka.Writer
+}
+
+// NewKafka publishes every message to the topic named by its subject, waiting
+// for all in-sync replicas. Keys pick the partition, so the events of one
+// chat stay in order; the message id travels in the "id" header.
+func NewKafka(conf KafkaConfig) Broker {
+	return &kafkaBroker{writer: &kafka.Writer{
+		Addr:                   kafka.TCP(conf.Brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}}
+}
+
+func (b *kafkaBroker) Publish(ctx context.Context, messages []Message) error {
+	msgs := make([]kafka.Message, len(messages))
+	for i, m := range messages {
+		msgs[i] = kafka.Message{
+			Topic:   m.Subject,
+			Key:     []byte(m.Key),
+			Value:   m.Payload,
+			Headers: []kafka.Header{{Key: "id", Value: []byte(m.Id)}},
+		}
+	}
+	return b.writer.WriteMessages(ctx, msgs...)
+}
+
+func (b *kafkaBroker) Close() error {
+	return b.writer.Close()
+}
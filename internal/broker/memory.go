@@ -0,0 +1,51 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory hands messages to in-process subscribers. It is meant for tests and
+// single-instance deployments: nothing survives a restart.
+type Memory struct {
+	mu     sync.RWMutex
+	nextId int
+	subs   map[int]func(Message)
+}
+
+func NewMemory() *Memory {
+	return &Memory{subs: map[int]func(Message){}}
+}
+
+func (m *Memory) Publish(ctx context.Context, messages []Message) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, msg := range messages {
+		for _, fn := range m.subs {
+			fn(msg)
+		}
+	}
+	return nil
+}
+
+// Subscribe calls fn for every published message until the returned cancel
+// function is called. fn runs synchronously in Publish and must not block.
+func (m *Memory) Subscribe(fn func(Message)) (cancel func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := m.nextId
+	m.nextId++
+	m.subs[id] = fn
+
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		delete(m.subs, id)
+	}
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+type NATSConfig struct {
+	Url     string
+	Stream  string
+	Subject string
+}
+
+type natsBroker struct {
+	conn *nats.Conn
+	js   jetstream.JetStream
+}
+
+// NewNATS publishes to a JetStream stream, created when missing, that
+// captures every subject under conf.Subject. Message ids go into Nats-Msg-Id,
+// so the stream drops republished duplicates within its dedupe window.
+func NewNATS(ctx context.Context, conf NATSConfig) (Broker, error) {
+	conn, err := nats.Connect(conf.Url, nats.Name("chats-api"))
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     conf.Stream,
+		Subjects: []string{conf.Subject + ".>"},
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &natsBroker{conn: conn, js: js}, nil
+}
+
+func (b *natsBroker) Publish(ctx context.Context, messages []Message) error {
+	futures := make([]jetstream.PubAckFuture, 0, len(messages))
+	for _, m := range messages {
+		msg := nats.NewMsg(m.Subject)
+		msg.Data = m.Payload
+		f, err := b.js.PublishMsgAsync(msg, jetstream.WithMsgID(m.Id))
+		if err != nil {
+			return err
+		}
+		futures = append(futures, f)
+	}
+
+	for _, f := range futures {
+		select {
+		case <-f.Ok():
+		case err := <-f.Err():
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *natsBroker) Close() error {
+	return b.conn.Drain()
+}
@@ -2,47 +2,345 @@ package config
 
 import (
 	"errors"
-	"os"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	ApiVersion string
-	ApiPort    string
-	*PostgresConf
+	ApiVersion    string             `yaml:"api_version" toml:"api_version" env:"API_VERSION" flag:"api-version" default:"v1" usage:"API version used in the route prefix"`
+	ApiPort       string             `yaml:"api_port" toml:"api_port" env:"API_PORT" flag:"api-port" default:"8080" usage:"port the HTTP server listens on"`
+	PostgresConf  *PostgresConf      `yaml:"db" toml:"db"`
+	Chats         *ChatsConf         `yaml:"chats" toml:"chats"`
+	Sync          *SyncConf          `yaml:"sync" toml:"sync"`
+	Retention     *RetentionConf     `yaml:"retention" toml:"retention"`
+	Admin         *AdminConf         `yaml:"admin" toml:"admin"`
+	Attachments   *AttachmentsConf   `yaml:"attachments" toml:"attachments"`
+	Webhooks      *WebhooksConf      `yaml:"webhooks" toml:"webhooks"`
+	Outbox        *OutboxConf        `yaml:"outbox" toml:"outbox"`
+	IncomingHooks *IncomingHooksConf `yaml:"incoming_hooks" toml:"incoming_hooks"`
+	Bots          *BotsConf          `yaml:"bots" toml:"bots"`
+	Realtime      *RealtimeConf      `yaml:"realtime" toml:"realtime"`
+	Scheduled     *ScheduledConf     `yaml:"scheduled" toml:"scheduled"`
+	Notifications *NotificationsConf `yaml:"notifications" toml:"notifications"`
 }
 
 type PostgresConf struct {
-	DbName   string
-	Host     string
-	Port     string
-	User     string
-	Password string
-}
-
-func NewConfig() (*Config, error) {
-	dbName := os.Getenv("DB_NAME")
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	apiVersion := os.Getenv("API_VERSION")
-	apiPort := os.Getenv("API_PORT")
-
-	if len(dbName) == 0 || len(host) == 0 || len(port) == 0 || len(user) == 0 || len(password) == 0 {
-		return nil, errors.New("error getting some DB env")
-	}
-
-	pConf := &PostgresConf{
-		DbName:   dbName,
-		Host:     host,
-		Port:     port,
-		User:     user,
-		Password: password,
-	}
-
-	return &Config{
-		ApiVersion:   apiVersion,
-		ApiPort:      apiPort,
-		PostgresConf: pConf,
-	}, nil
+	DbName   string `yaml:"name" toml:"name" env:"DB_NAME" flag:"db-name" default:"chats" usage:"database name"`
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST" flag:"db-host" default:"localhost" usage:"database host"`
+	Port     string `yaml:"port" toml:"port" env:"DB_PORT" flag:"db-port" default:"5432" usage:"database port"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER" flag:"db-user" default:"chats" usage:"database user"`
+	Password string `yaml:"password" toml:"password" env:"DB_PASSWORD" flag:"db-password" secret:"true" usage:"database password"`
+	TimeZone string `yaml:"timezone" toml:"timezone" env:"DB_TIMEZONE" flag:"db-timezone" default:"UTC" usage:"session time zone; legacy timestamps without zone are read in it"`
+	Reset    bool   `yaml:"reset" toml:"reset" env:"DB_RESET" flag:"db-reset" default:"false" usage:"roll back all migrations before applying them on start, erasing all data"`
+}
+
+type ChatsConf struct {
+	RestoreGracePeriod time.Duration `yaml:"restore_grace_period" toml:"restore_grace_period" env:"CHATS_RESTORE_GRACE_PERIOD" flag:"chats-restore-grace-period" default:"168h" usage:"how long a deleted chat stays in the trash and can be restored"`
+	PurgeInterval      time.Duration `yaml:"purge_interval" toml:"purge_interval" env:"CHATS_PURGE_INTERVAL" flag:"chats-purge-interval" default:"1h" usage:"how often chats past the grace period are purged"`
+	MaxPins            int           `yaml:"max_pins" toml:"max_pins" env:"CHATS_MAX_PINS" flag:"chats-max-pins" default:"50" usage:"how many messages can be pinned in one chat"`
+}
+
+type SyncConf struct {
+	ChangeLogRetention time.Duration `yaml:"change_log_retention" toml:"change_log_retention" env:"SYNC_CHANGE_LOG_RETENTION" flag:"sync-change-log-retention" default:"720h" usage:"how long sync changes are kept before clients must fully resync"`
+	PruneInterval      time.Duration `yaml:"prune_interval" toml:"prune_interval" env:"SYNC_PRUNE_INTERVAL" flag:"sync-prune-interval" default:"1h" usage:"how often the sync change log is pruned"`
+	SequenceInterval   time.Duration `yaml:"sequence_interval" toml:"sequence_interval" env:"SYNC_SEQUENCE_INTERVAL" flag:"sync-sequence-interval" default:"100ms" usage:"how often committed changes and events are ordered for sync, webhooks and subscribers"`
+}
+
+type RetentionConf struct {
+	Interval      time.Duration `yaml:"interval" toml:"interval" env:"RETENTION_INTERVAL" flag:"retention-interval" default:"5m" usage:"how often messages outside chat retention policies are purged"`
+	BatchSize     int           `yaml:"batch_size" toml:"batch_size" env:"RETENTION_BATCH_SIZE" flag:"retention-batch-size" default:"1000" usage:"how many messages are deleted per transaction when purging"`
+	SweepInterval time.Duration `yaml:"sweep_interval" toml:"sweep_interval" env:"RETENTION_SWEEP_INTERVAL" flag:"retention-sweep-interval" default:"30s" usage:"how often messages past their expires_in are deleted"`
+}
+
+type AdminConf struct {
+	Token string `yaml:"token" toml:"token" env:"ADMIN_TOKEN" flag:"admin-token" secret:"true" usage:"bearer token for /admin endpoints; they are disabled when empty"`
+}
+
+type AttachmentsConf struct {
+	Storage         string        `yaml:"storage" toml:"storage" env:"ATTACHMENTS_STORAGE" flag:"attachments-storage" default:"local" usage:"blob storage for attachments: local or s3"`
+	LocalDir        string        `yaml:"local_dir" toml:"local_dir" env:"ATTACHMENTS_LOCAL_DIR" flag:"attachments-local-dir" default:"data/attachments" usage:"directory for attachments with local storage"`
+	S3Endpoint      string        `yaml:"s3_endpoint" toml:"s3_endpoint" env:"ATTACHMENTS_S3_ENDPOINT" flag:"attachments-s3-endpoint" usage:"host:port of the S3-compatible service"`
+	S3Bucket        string        `yaml:"s3_bucket" toml:"s3_bucket" env:"ATTACHMENTS_S3_BUCKET" flag:"attachments-s3-bucket" default:"chats-attachments" usage:"bucket for attachments"`
+	S3Region        string        `yaml:"s3_region" toml:"s3_region" env:"ATTACHMENTS_S3_REGION" flag:"attachments-s3-region" usage:"bucket region"`
+	S3AccessKey     string        `yaml:"s3_access_key" toml:"s3_access_key" env:"ATTACHMENTS_S3_ACCESS_KEY" flag:"attachments-s3-access-key" usage:"S3 access key"`
+	S3SecretKey     string        `yaml:"s3_secret_key" toml:"s3_secret_key" env:"ATTACHMENTS_S3_SECRET_KEY" flag:"attachments-s3-secret-key" secret:"true" usage:"S3 secret key"`
+	S3UseSSL        bool          `yaml:"s3_use_ssl" toml:"s3_use_ssl" env:"ATTACHMENTS_S3_USE_SSL" flag:"attachments-s3-use-ssl" default:"true" usage:"connect to S3 over HTTPS"`
+	MaxSize         int64         `yaml:"max_size" toml:"max_size" env:"ATTACHMENTS_MAX_SIZE" flag:"attachments-max-size" default:"26214400" usage:"largest accepted file in bytes"`
+	AllowedTypes    []string      `yaml:"allowed_types" toml:"allowed_types" env:"ATTACHMENTS_ALLOWED_TYPES" flag:"attachments-allowed-types" default:"image/jpeg,image/png,image/gif,image/webp,application/pdf,text/plain" usage:"comma-separated MIME types accepted for upload, type/* allowed"`
+	SigningKey      string        `yaml:"signing_key" toml:"signing_key" env:"ATTACHMENTS_SIGNING_KEY" flag:"attachments-signing-key" secret:"true" usage:"key for signed download URLs; a random one is used when empty"`
+	UrlTTL          time.Duration `yaml:"url_ttl" toml:"url_ttl" env:"ATTACHMENTS_URL_TTL" flag:"attachments-url-ttl" default:"15m" usage:"how long signed download URLs stay valid"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" toml:"cleanup_interval" env:"ATTACHMENTS_CLEANUP_INTERVAL" flag:"attachments-cleanup-interval" default:"1h" usage:"how often unattached uploads and unused blobs are removed"`
+	CleanupAfter    time.Duration `yaml:"cleanup_after" toml:"cleanup_after" env:"ATTACHMENTS_CLEANUP_AFTER" flag:"attachments-cleanup-after" default:"24h" usage:"how long uploads not attached to a message are kept"`
+}
+
+type IncomingHooksConf struct {
+	RateLimit int `yaml:"rate_limit" toml:"rate_limit" env:"INCOMING_HOOKS_RATE_LIMIT" flag:"incoming-hooks-rate-limit" default:"60" usage:"posts per minute allowed to one incoming webhook"`
+	Burst     int `yaml:"burst" toml:"burst" env:"INCOMING_HOOKS_BURST" flag:"incoming-hooks-burst" default:"10" usage:"posts one incoming webhook can make at once"`
+}
+
+type BotsConf struct {
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" env:"BOTS_TIMEOUT" flag:"bots-timeout" default:"5s" usage:"how long a bot has to answer a command"`
+}
+
+type RealtimeConf struct {
+	Interval    time.Duration `yaml:"interval" toml:"interval" env:"REALTIME_INTERVAL" flag:"realtime-interval" default:"250ms" usage:"how often new events are pushed to connected clients"`
+	BatchSize   int           `yaml:"batch_size" toml:"batch_size" env:"REALTIME_BATCH_SIZE" flag:"realtime-batch-size" default:"500" usage:"how many events are read at once for connected clients"`
+	BufferSize  int           `yaml:"buffer_size" toml:"buffer_size" env:"REALTIME_BUFFER_SIZE" flag:"realtime-buffer-size" default:"256" usage:"how many events a client may fall behind before it is disconnected"`
+	TypingTTL   time.Duration `yaml:"typing_ttl" toml:"typing_ttl" env:"REALTIME_TYPING_TTL" flag:"realtime-typing-ttl" default:"6s" usage:"how long a typing indicator lasts unless the client repeats it"`
+	PresenceTTL time.Duration `yaml:"presence_ttl" toml:"presence_ttl" env:"REALTIME_PRESENCE_TTL" flag:"realtime-presence-ttl" default:"60s" usage:"how long a user stays online in a chat unless the client repeats it"`
+}
+
+type ScheduledConf struct {
+	Interval  time.Duration `yaml:"interval" toml:"interval" env:"SCHEDULED_INTERVAL" flag:"scheduled-interval" default:"1s" usage:"how often due scheduled messages are posted"`
+	BatchSize int           `yaml:"batch_size" toml:"batch_size" env:"SCHEDULED_BATCH_SIZE" flag:"scheduled-batch-size" default:"100" usage:"how many scheduled messages are claimed at once"`
+	Lease     time.Duration `yaml:"lease" toml:"lease" env:"SCHEDULED_LEASE" flag:"scheduled-lease" default:"1m" usage:"how long a claimed message is left to its instance before another one retries it"`
+}
+
+type NotificationsConf struct {
+	Interval  time.Duration `yaml:"interval" toml:"interval" env:"NOTIFICATIONS_INTERVAL" flag:"notifications-interval" default:"10s" usage:"how often @all notifications cut short are retried"`
+	BatchSize int           `yaml:"batch_size" toml:"batch_size" env:"NOTIFICATIONS_BATCH_SIZE" flag:"notifications-batch-size" default:"10" usage:"how many messages with @all are retried at once"`
+}
+
+type WebhooksConf struct {
+	Interval       time.Duration `yaml:"interval" toml:"interval" env:"WEBHOOKS_INTERVAL" flag:"webhooks-interval" default:"2s" usage:"how often new events are queued and due deliveries are sent"`
+	Timeout        time.Duration `yaml:"timeout" toml:"timeout" env:"WEBHOOKS_TIMEOUT" flag:"webhooks-timeout" default:"10s" usage:"how long an endpoint has to respond"`
+	MaxAttempts    int           `yaml:"max_attempts" toml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" flag:"webhooks-max-attempts" default:"10" usage:"attempts per delivery before it is given up"`
+	BackoffBase    time.Duration `yaml:"backoff_base" toml:"backoff_base" env:"WEBHOOKS_BACKOFF_BASE" flag:"webhooks-backoff-base" default:"10s" usage:"delay before the first retry, doubled for every next one"`
+	BackoffMax     time.Duration `yaml:"backoff_max" toml:"backoff_max" env:"WEBHOOKS_BACKOFF_MAX" flag:"webhooks-backoff-max" default:"1h" usage:"longest delay between retries"`
+	DisableAfter   int           `yaml:"disable_after" toml:"disable_after" env:"WEBHOOKS_DISABLE_AFTER" flag:"webhooks-disable-after" default:"50" usage:"failed attempts in a row after which a webhook is disabled"`
+	KeepDeliveries time.Duration `yaml:"keep_deliveries" toml:"keep_deliveries" env:"WEBHOOKS_KEEP_DELIVERIES" flag:"webhooks-keep-deliveries" default:"168h" usage:"how long finished deliveries and their attempts are kept"`
+	PruneInterval  time.Duration `yaml:"prune_interval" toml:"prune_interval" env:"WEBHOOKS_PRUNE_INTERVAL" flag:"webhooks-prune-interval" default:"1h" usage:"how often old deliveries are pruned"`
+}
+
+type OutboxConf struct {
+	Broker          string        `yaml:"broker" toml:"broker" env:"OUTBOX_BROKER" flag:"outbox-broker" default:"none" usage:"where events are published: none, nats or kafka"`
+	Subject         string        `yaml:"subject" toml:"subject" env:"OUTBOX_SUBJECT" flag:"outbox-subject" default:"chats" usage:"prefix of event subjects and topics, e.g. chats.message.created"`
+	NatsUrl         string        `yaml:"nats_url" toml:"nats_url" env:"OUTBOX_NATS_URL" flag:"outbox-nats-url" default:"nats://localhost:4222" usage:"NATS server URL"`
+	NatsStream      string        `yaml:"nats_stream" toml:"nats_stream" env:"OUTBOX_NATS_STREAM" flag:"outbox-nats-stream" default:"CHATS" usage:"JetStream stream that stores the events"`
+	KafkaBrokers    []string      `yaml:"kafka_brokers" toml:"kafka_brokers" env:"OUTBOX_KAFKA_BROKERS" flag:"outbox-kafka-brokers" default:"localhost:9092" usage:"comma-separated Kafka bootstrap brokers"`
+	Interval        time.Duration `yaml:"interval" toml:"interval" env:"OUTBOX_INTERVAL" flag:"outbox-interval" default:"1s" usage:"how often pending events are published"`
+	BatchSize       int           `yaml:"batch_size" toml:"batch_size" env:"OUTBOX_BATCH_SIZE" flag:"outbox-batch-size" default:"100" usage:"how many events are published at once"`
+	KeepFor         time.Duration `yaml:"keep_for" toml:"keep_for" env:"OUTBOX_KEEP_FOR" flag:"outbox-keep-for" default:"24h" usage:"how long published events stay in the outbox"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval" toml:"cleanup_interval" env:"OUTBOX_CLEANUP_INTERVAL" flag:"outbox-cleanup-interval" default:"1h" usage:"how often published events are removed"`
+}
+
+var apiVersionRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// sampleAdminToken is the token an older config.sample.yaml shipped with;
+// a copy of it would open the admin endpoints to anyone.
+const sampleAdminToken = "change-me"
+
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.ApiVersion == "" {
+		errs = append(errs, errors.New("api_version must not be empty"))
+	} else if !apiVersionRe.MatchString(c.ApiVersion) {
+		errs = append(errs, fmt.Errorf("api_version %q may only contain letters, digits, '.', '_' and '-'", c.ApiVersion))
+	}
+	if err := validatePort("api_port", c.ApiPort); err != nil {
+		errs = append(errs, err)
+	}
+
+	db := c.PostgresConf
+	if db.DbName == "" {
+		errs = append(errs, errors.New("db.name must not be empty"))
+	}
+	if db.Host == "" {
+		errs = append(errs, errors.New("db.host must not be empty"))
+	}
+	if err := validatePort("db.port", db.Port); err != nil {
+		errs = append(errs, err)
+	}
+	if db.User == "" {
+		errs = append(errs, errors.New("db.user must not be empty"))
+	}
+	if db.Password == "" {
+		errs = append(errs, errors.New("db.password must not be empty"))
+	}
+	if _, err := time.LoadLocation(db.TimeZone); err != nil || db.TimeZone == "" || db.TimeZone == "Local" {
+		errs = append(errs, fmt.Errorf("db.timezone must be an IANA time zone name, got %q", db.TimeZone))
+	}
+
+	if c.Admin.Token == sampleAdminToken {
+		errs = append(errs, fmt.Errorf("admin.token must not be the placeholder %q", sampleAdminToken))
+	}
+
+	if c.Chats.RestoreGracePeriod <= 0 {
+		errs = append(errs, errors.New("chats.restore_grace_period must be positive"))
+	}
+	if c.Chats.PurgeInterval <= 0 {
+		errs = append(errs, errors.New("chats.purge_interval must be positive"))
+	}
+	if c.Chats.MaxPins <= 0 {
+		errs = append(errs, errors.New("chats.max_pins must be positive"))
+	}
+	if c.Sync.ChangeLogRetention <= 0 {
+		errs = append(errs, errors.New("sync.change_log_retention must be positive"))
+	}
+	if c.Sync.PruneInterval <= 0 {
+		errs = append(errs, errors.New("sync.prune_interval must be positive"))
+	}
+	if c.Sync.SequenceInterval <= 0 {
+		errs = append(errs, errors.New("sync.sequence_interval must be positive"))
+	}
+	if c.Retention.Interval <= 0 {
+		errs = append(errs, errors.New("retention.interval must be positive"))
+	}
+	if c.Retention.SweepInterval <= 0 {
+		errs = append(errs, errors.New("retention.sweep_interval must be positive"))
+	}
+	if c.Retention.BatchSize <= 0 {
+		errs = append(errs, errors.New("retention.batch_size must be positive"))
+	}
+
+	att := c.Attachments
+	switch att.Storage {
+	case "local":
+		if att.LocalDir == "" {
+			errs = append(errs, errors.New("attachments.local_dir must not be empty"))
+		}
+	case "s3":
+		if att.S3Endpoint == "" {
+			errs = append(errs, errors.New("attachments.s3_endpoint must not be empty"))
+		}
+		if att.S3Bucket == "" {
+			errs = append(errs, errors.New("attachments.s3_bucket must not be empty"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("attachments.storage must be local or s3, got %q", att.Storage))
+	}
+	if att.MaxSize <= 0 {
+		errs = append(errs, errors.New("attachments.max_size must be positive"))
+	}
+	if len(att.AllowedTypes) == 0 {
+		errs = append(errs, errors.New("attachments.allowed_types must not be empty"))
+	}
+	if att.UrlTTL <= 0 {
+		errs = append(errs, errors.New("attachments.url_ttl must be positive"))
+	}
+	if att.CleanupInterval <= 0 {
+		errs = append(errs, errors.New("attachments.cleanup_interval must be positive"))
+	}
+	if att.CleanupAfter <= 0 {
+		errs = append(errs, errors.New("attachments.cleanup_after must be positive"))
+	}
+
+	hooks := c.Webhooks
+	if hooks.Interval <= 0 {
+		errs = append(errs, errors.New("webhooks.interval must be positive"))
+	}
+	if hooks.Timeout <= 0 {
+		errs = append(errs, errors.New("webhooks.timeout must be positive"))
+	}
+	if hooks.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("webhooks.max_attempts must be positive"))
+	}
+	if hooks.BackoffBase <= 0 {
+		errs = append(errs, errors.New("webhooks.backoff_base must be positive"))
+	}
+	if hooks.BackoffMax < hooks.BackoffBase {
+		errs = append(errs, errors.New("webhooks.backoff_max must not be less than webhooks.backoff_base"))
+	}
+	if hooks.DisableAfter <= 0 {
+		errs = append(errs, errors.New("webhooks.disable_after must be positive"))
+	}
+	if hooks.KeepDeliveries <= 0 {
+		errs = append(errs, errors.New("webhooks.keep_deliveries must be positive"))
+	}
+	if hooks.PruneInterval <= 0 {
+		errs = append(errs, errors.New("webhooks.prune_interval must be positive"))
+	}
+
+	out := c.Outbox
+	switch out.Broker {
+	case "none":
+	case "nats":
+		if out.NatsUrl == "" {
+			errs = append(errs, errors.New("outbox.nats_url must not be empty"))
+		}
+		if out.NatsStream == "" {
+			errs = append(errs, errors.New("outbox.nats_stream must not be empty"))
+		}
+	case "kafka":
+		if len(out.KafkaBrokers) == 0 {
+			errs = append(errs, errors.New("outbox.kafka_brokers must not be empty"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("outbox.broker must be none, nats or kafka, got %q", out.Broker))
+	}
+	if out.Subject == "" {
+		errs = append(errs, errors.New("outbox.subject must not be empty"))
+	}
+	if out.Interval <= 0 {
+		errs = append(errs, errors.New("outbox.interval must be positive"))
+	}
+	if out.BatchSize <= 0 {
+		errs = append(errs, errors.New("outbox.batch_size must be positive"))
+	}
+	if out.KeepFor <= 0 {
+		errs = append(errs, errors.New("outbox.keep_for must be positive"))
+	}
+	if out.CleanupInterval <= 0 {
+		errs = append(errs, errors.New("outbox.cleanup_interval must be positive"))
+	}
+
+	in := c.IncomingHooks
+	if in.RateLimit <= 0 {
+		errs = append(errs, errors.New("incoming_hooks.rate_limit must be positive"))
+	}
+	if in.Burst <= 0 {
+		errs = append(errs, errors.New("incoming_hooks.burst must be positive"))
+	}
+	if c.Bots.Timeout <= 0 {
+		errs = append(errs, errors.New("bots.timeout must be positive"))
+	}
+
+	rt := c.Realtime
+	if rt.Interval <= 0 {
+		errs = append(errs, errors.New("realtime.interval must be positive"))
+	}
+	if rt.BatchSize <= 0 {
+		errs = append(errs, errors.New("realtime.batch_size must be positive"))
+	}
+	if rt.BufferSize <= 0 {
+		errs = append(errs, errors.New("realtime.buffer_size must be positive"))
+	}
+	if rt.TypingTTL <= 0 {
+		errs = append(errs, errors.New("realtime.typing_ttl must be positive"))
+	}
+	if rt.PresenceTTL <= 0 {
+		errs = append(errs, errors.New("realtime.presence_ttl must be positive"))
+	}
+
+	sc := c.Scheduled
+	if sc.Interval <= 0 {
+		errs = append(errs, errors.New("scheduled.interval must be positive"))
+	}
+	if sc.BatchSize <= 0 {
+		errs = append(errs, errors.New("scheduled.batch_size must be positive"))
+	}
+	if sc.Lease <= 0 {
+		errs = append(errs, errors.New("scheduled.lease must be positive"))
+	}
+
+	nc := c.Notifications
+	if nc.Interval <= 0 {
+		errs = append(errs, errors.New("notifications.interval must be positive"))
+	}
+	if nc.BatchSize <= 0 {
+		errs = append(errs, errors.New("notifications.batch_size must be positive"))
+	}
+
+	return errors.Join(errs...)
+}
+
+func validatePort(name, port string) error {
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("%s must be a number between 1 and 65535, got %q", name, port)
+	}
+	return nil
 }
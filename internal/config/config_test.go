@@ -0,0 +1,126 @@
+package config_test
+
+import (
+	"bytes"
+	"chats-api/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+api_version: v2
+api_port: "9000"
+db:
+  host: file-host
+  password: from-file
+`)
+	t.Setenv("API_PORT", "9100")
+	t.Setenv("DB_HOST", "env-host")
+
+	conf, err := config.Load([]string{"-config", path, "-db-host", "flag-host"})
+	require.NoError(t, err)
+
+	require.Equal(t, "v2", conf.ApiVersion)
+	require.Equal(t, "9100", conf.ApiPort)
+	require.Equal(t, "flag-host", conf.PostgresConf.Host)
+	require.Equal(t, "from-file", conf.PostgresConf.Password)
+	require.Equal(t, "5432", conf.PostgresConf.Port)
+}
+
+func TestLoad_BoolFlag(t *testing.T) {
+	conf, err := config.Load([]string{"-db-password", "p", "-db-reset"})
+	require.NoError(t, err)
+	require.True(t, conf.PostgresConf.Reset)
+
+	conf, err = config.Load([]string{"-db-password", "p", "-db-reset=false"})
+	require.NoError(t, err)
+	require.False(t, conf.PostgresConf.Reset)
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+api_version = "v3"
+
+[db]
+password = "secret"
+`)
+
+	conf, err := config.Load([]string{"-config", path})
+	require.NoError(t, err)
+	require.Equal(t, "v3", conf.ApiVersion)
+	require.Equal(t, "secret", conf.PostgresConf.Password)
+}
+
+func TestLoad_Validation(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		file        string
+		expectedErr []string
+	}{
+		{
+			name:        "missing password",
+			expectedErr: []string{"db.password must not be empty"},
+		},
+		{
+			name: "aggregated errors",
+			args: []string{"-api-version", "v1/x", "-api-port", "0", "-db-password", "p"},
+			expectedErr: []string{
+				`api_version "v1/x" may only contain`,
+				`api_port must be a number between 1 and 65535, got "0"`,
+			},
+		},
+		{
+			name:        "unknown key in file",
+			file:        "api_versoin: v1\n",
+			expectedErr: []string{"field api_versoin not found"},
+		},
+		{
+			name:        "placeholder admin token",
+			args:        []string{"-db-password", "p", "-admin-token", "change-me"},
+			expectedErr: []string{`admin.token must not be the placeholder "change-me"`},
+		},
+		{
+			name:        "unknown flag",
+			args:        []string{"-no-such-flag"},
+			expectedErr: []string{"flag provided but not defined"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			args := test.args
+			if test.file != "" {
+				args = append(args, "-config", writeFile(t, "config.yaml", test.file))
+			}
+
+			_, err := config.Load(args)
+			require.Error(t, err)
+			for _, msg := range test.expectedErr {
+				require.Contains(t, err.Error(), msg)
+			}
+		})
+	}
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	conf, err := config.Load([]string{"-db-password", "hunter2"})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, config.Print(&buf, conf))
+
+	require.NotContains(t, buf.String(), "hunter2")
+	require.Contains(t, buf.String(), "password: '[REDACTED]'")
+	require.Contains(t, buf.String(), "api_version: v1")
+}
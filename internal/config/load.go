@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the effective configuration. Sources are applied in order of
+// increasing precedence: defaults, config file, environment, command-line flags.
+func Load(args []string) (*Config, error) {
+	conf := &Config{}
+	fields := collectFields(reflect.ValueOf(conf).Elem(), "")
+
+	for _, f := range fields {
+		if def, ok := f.tag.Lookup("default"); ok {
+			if err := setValue(f.value, def); err != nil {
+				return nil, fmt.Errorf("bad default for %s: %w", f.path, err)
+			}
+		}
+	}
+
+	fs := flag.NewFlagSet("chats-api", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to a YAML or TOML config file (env CONFIG_FILE)")
+	flagValues := make(map[string]*flagValue)
+	for _, f := range fields {
+		if name := f.tag.Get("flag"); name != "" {
+			flagValues[name] = &flagValue{isBool: f.value.Kind() == reflect.Bool}
+			fs.Var(flagValues[name], name, f.tag.Get("usage"))
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	if err := loadDotEnv(); err != nil {
+		return nil, err
+	}
+
+	path := *configPath
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := loadFile(conf, path); err != nil {
+			return nil, err
+		}
+	}
+
+	setFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	var errs []error
+	for _, f := range fields {
+		if name := f.tag.Get("env"); name != "" {
+			if v, ok := os.LookupEnv(name); ok && v != "" {
+				if err := setValue(f.value, v); err != nil {
+					errs = append(errs, fmt.Errorf("env %s: %w", name, err))
+				}
+			}
+		}
+		if name := f.tag.Get("flag"); name != "" && setFlags[name] {
+			if err := setValue(f.value, flagValues[name].value); err != nil {
+				errs = append(errs, fmt.Errorf("flag -%s: %w", name, err))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	return conf, nil
+}
+
+// Print writes the configuration as YAML with secret values redacted.
+func Print(w io.Writer, conf *Config) error {
+	node, err := toNode(reflect.ValueOf(conf).Elem())
+	if err != nil {
+		return err
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// flagValue keeps the text of a flag until it is applied over the other
+// sources. Flags of bool fields may be given without a value, as in -db-reset.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string     { return f.value }
+func (f *flagValue) Set(s string) error { f.value = s; return nil }
+func (f *flagValue) IsBoolFlag() bool   { return f.isBool }
+
+type field struct {
+	path  string
+	value reflect.Value
+	tag   reflect.StructTag
+}
+
+func collectFields(v reflect.Value, prefix string) []field {
+	var fields []field
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		path := prefix + yamlName(sf)
+
+		if sf.Type.Kind() == reflect.Ptr && sf.Type.Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				fv.Set(reflect.New(sf.Type.Elem()))
+			}
+			fields = append(fields, collectFields(fv.Elem(), path+".")...)
+			continue
+		}
+		if sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(fv, path+".")...)
+			continue
+		}
+
+		fields = append(fields, field{path: path, value: fv, tag: sf.Tag})
+	}
+
+	return fields
+}
+
+func yamlName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+	if name == "" {
+		return strings.ToLower(sf.Name)
+	}
+	return name
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func loadDotEnv() error {
+	if _, err := os.Stat(".env"); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err := godotenv.Load(); err != nil {
+		return errors.New("error loading .env file: " + err.Error())
+	}
+	return nil
+}
+
+func loadFile(conf *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.New("error reading config file: " + err.Error())
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(conf); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error parsing %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), conf)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, k := range undecoded {
+				keys[i] = k.String()
+			}
+			return fmt.Errorf("error parsing %s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q", ext)
+	}
+
+	return nil
+}
+
+func toNode(v reflect.Value) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		fv := v.Field(i)
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: yamlName(sf)}
+
+		if fv.Kind() == reflect.Ptr {
+			fv = fv.Elem()
+		}
+
+		var value *yaml.Node
+		var err error
+		switch {
+		case fv.Kind() == reflect.Struct:
+			value, err = toNode(fv)
+		case sf.Tag.Get("secret") == "true" && !fv.IsZero():
+			value = &yaml.Node{Kind: yaml.ScalarNode, Value: "[REDACTED]"}
+		default:
+			value = &yaml.Node{}
+			err = value.Encode(fv.Interface())
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		node.Content = append(node.Content, key, value)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,203 @@
+package export
+
+import (
+	"chats-api/internal/model"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatHTML   = "html"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Encoder writes a chat transcript incrementally: Begin once, Message for
+// every message in order, then End.
+type Encoder interface {
+	ContentType() string
+	Extension() string
+	Begin(chat *model.Chat) error
+	Message(message *model.Message) error
+	End() error
+}
+
+func NewEncoder(format string, w io.Writer) (Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonEncoder{w: w}, nil
+	case FormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	case FormatHTML:
+		return &htmlEncoder{w: w}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Record is one line of an NDJSON export: the chat first, then its messages.
+type Record struct {
+	Type    string         `json:"type"`
+	Chat    *model.Chat    `json:"chat,omitempty"`
+	Message *model.Message `json:"message,omitempty"`
+}
+
+const (
+	RecordChat    = "chat"
+	RecordMessage = "message"
+)
+
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) ContentType() string { return "application/json" }
+func (e *jsonEncoder) Extension() string   { return "json" }
+
+func (e *jsonEncoder) Begin(chat *model.Chat) error {
+	b, err := json.Marshal(chat)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(e.w, `{"chat":`+string(b)+`,"messages":[`)
+	return err
+}
+
+func (e *jsonEncoder) Message(message *model.Message) error {
+	b, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		b = append([]byte{','}, b...)
+	}
+	e.count++
+	_, err = e.w.Write(b)
+	return err
+}
+
+func (e *jsonEncoder) End() error {
+	_, err := io.WriteString(e.w, "]}\n")
+	return err
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func (e *ndjsonEncoder) ContentType() string { return "application/x-ndjson" }
+func (e *ndjsonEncoder) Extension() string   { return "ndjson" }
+
+func (e *ndjsonEncoder) Begin(chat *model.Chat) error {
+	return e.enc.Encode(Record{Type: RecordChat, Chat: chat})
+}
+
+func (e *ndjsonEncoder) Message(message *model.Message) error {
+	return e.enc.Encode(Record{Type: RecordMessage, Message: message})
+}
+
+func (e *ndjsonEncoder) End() error { return nil }
+
+type csvEncoder struct {
+	w    *csv.Writer
+	chat *model.Chat
+}
+
+func (e *csvEncoder) ContentType() string { return "text/csv; charset=utf-8" }
+func (e *csvEncoder) Extension() string   { return "csv" }
+
+func (e *csvEncoder) Begin(chat *model.Chat) error {
+	e.chat = chat
+	return e.w.Write([]string{"chat_id", "chat_title", "message_id", "seq", "sender_id", "client_msg_id", "created_at", "text"})
+}
+
+func (e *csvEncoder) Message(message *model.Message) error {
+	clientMsgId := ""
+	if message.ClientMsgId != nil {
+		clientMsgId = *message.ClientMsgId
+	}
+	err := e.w.Write([]string{
+		e.chat.PublicId,
+		csvText(e.chat.Title),
+		message.PublicId,
+		strconv.FormatInt(message.Seq, 10),
+		csvText(message.SenderId),
+		csvText(clientMsgId),
+		message.CreatedAt.Format(time.RFC3339Nano),
+		csvText(message.Text),
+	})
+	if err != nil {
+		return err
+	}
+	// Flush per row so the transcript streams instead of buffering in memory.
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvEncoder) End() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvText keeps a spreadsheet from running user text as a formula: a cell
+// starting with =, +, -, @, a tab or a carriage return gets a leading '.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+var htmlTemplates = template.Must(template.New("transcript").Parse(`
+{{define "begin"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; }
+.message { margin: 0.5em 0; }
+.meta { color: #666; font-size: 0.85em; }
+.text { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Chat {{.PublicId}}, created {{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+{{end}}
+{{define "message"}}<div class="message" id="{{.PublicId}}">
+<div class="meta">#{{.Seq}} {{.SenderId}} {{.CreatedAt.Format "2006-01-02 15:04:05 MST"}}</div>
+<div class="text">{{.Text}}</div>
+</div>
+{{end}}`))
+
+type htmlEncoder struct {
+	w io.Writer
+}
+
+func (e *htmlEncoder) ContentType() string { return "text/html; charset=utf-8" }
+func (e *htmlEncoder) Extension() string   { return "html" }
+
+func (e *htmlEncoder) Begin(chat *model.Chat) error {
+	return htmlTemplates.ExecuteTemplate(e.w, "begin", chat)
+}
+
+func (e *htmlEncoder) Message(message *model.Message) error {
+	return htmlTemplates.ExecuteTemplate(e.w, "message", message)
+}
+
+func (e *htmlEncoder) End() error {
+	_, err := io.WriteString(e.w, "</body>\n</html>\n")
+	return err
+}
@@ -0,0 +1,113 @@
+package export_test
+
+import (
+	"bufio"
+	"bytes"
+	"chats-api/internal/export"
+	"chats-api/internal/model"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func transcript(t *testing.T, format string) string {
+	t.Helper()
+
+	createdAt := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	chat := &model.Chat{PublicId: "0192f3c4-5b6a-7d8e-9f01-23456789abcd", Title: "Family <3", CreatedAt: createdAt}
+	messages := []*model.Message{
+		{PublicId: "0192f3c4-6000-7000-8000-000000000001", ChatPublicId: chat.PublicId, Seq: 1, SenderId: "u-1", Text: "Hello, \"world\"", CreatedAt: createdAt},
+		{PublicId: "0192f3c4-6000-7000-8000-000000000002", ChatPublicId: chat.PublicId, Seq: 2, SenderId: "u-2", Text: "<b>hi</b>\nthere", CreatedAt: createdAt},
+	}
+
+	var buf bytes.Buffer
+	enc, err := export.NewEncoder(format, &buf)
+	require.NoError(t, err)
+	require.NoError(t, enc.Begin(chat))
+	for _, m := range messages {
+		require.NoError(t, enc.Message(m))
+	}
+	require.NoError(t, enc.End())
+
+	return buf.String()
+}
+
+func TestEncoder_JSON(t *testing.T) {
+	var out struct {
+		Chat     model.Chat
+		Messages []model.Message
+	}
+	require.NoError(t, json.Unmarshal([]byte(transcript(t, export.FormatJSON)), &out))
+	require.Equal(t, "Family <3", out.Chat.Title)
+	require.Len(t, out.Messages, 2)
+	require.Equal(t, int64(2), out.Messages[1].Seq)
+}
+
+func TestEncoder_NDJSON(t *testing.T) {
+	var records []export.Record
+	scanner := bufio.NewScanner(bytes.NewBufferString(transcript(t, export.FormatNDJSON)))
+	for scanner.Scan() {
+		var rec export.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		records = append(records, rec)
+	}
+
+	require.Len(t, records, 3)
+	require.Equal(t, export.RecordChat, records[0].Type)
+	require.Equal(t, export.RecordMessage, records[2].Type)
+	require.Equal(t, "<b>hi</b>\nthere", records[2].Message.Text)
+}
+
+func TestEncoder_CSV(t *testing.T) {
+	rows, err := csv.NewReader(bytes.NewBufferString(transcript(t, export.FormatCSV))).ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, rows, 3)
+	require.Equal(t, "message_id", rows[0][2])
+	require.Equal(t, []string{
+		"0192f3c4-5b6a-7d8e-9f01-23456789abcd", "Family <3", "0192f3c4-6000-7000-8000-000000000001",
+		"1", "u-1", "", "2026-01-02T15:04:05Z", "Hello, \"world\"",
+	}, rows[1])
+}
+
+func TestEncoder_CSVEscapesFormulas(t *testing.T) {
+	chat := &model.Chat{PublicId: "0192f3c4-5b6a-7d8e-9f01-23456789abcd", Title: "=HYPERLINK(\"http://x\")"}
+	texts := []string{"=1+2", "+1", "-1", "@SUM(A1)", "\tx", "a=b"}
+
+	var buf bytes.Buffer
+	enc, err := export.NewEncoder(export.FormatCSV, &buf)
+	require.NoError(t, err)
+	require.NoError(t, enc.Begin(chat))
+	for _, text := range texts {
+		require.NoError(t, enc.Message(&model.Message{SenderId: "@bob", Text: text}))
+	}
+	require.NoError(t, enc.End())
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, len(texts)+1)
+	require.Equal(t, "'=HYPERLINK(\"http://x\")", rows[1][1])
+	require.Equal(t, "'@bob", rows[1][4])
+	var got []string
+	for _, row := range rows[1:] {
+		got = append(got, row[7])
+	}
+	require.Equal(t, []string{"'=1+2", "'+1", "'-1", "'@SUM(A1)", "'\tx", "a=b"}, got)
+}
+
+func TestEncoder_HTMLEscapesText(t *testing.T) {
+	out := transcript(t, export.FormatHTML)
+
+	require.Contains(t, out, "<title>Family &lt;3</title>")
+	require.Contains(t, out, "&lt;b&gt;hi&lt;/b&gt;")
+	require.NotContains(t, out, "<b>hi</b>")
+	require.Contains(t, out, "</html>")
+}
+
+func TestNewEncoder_UnknownFormat(t *testing.T) {
+	_, err := export.NewEncoder("xml", &bytes.Buffer{})
+	require.ErrorIs(t, err, export.ErrUnknownFormat)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"chats-api/internal/blob"
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (h *Handler) HandleAttachmentsUpload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling upload attachments")
+
+		chatPublicId := r.PathValue("id")
+		if !model.IsPublicId(chatPublicId) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidChatId)
+			h.logger.Error("chat id is invalid")
+			return
+		}
+
+		parts, err := r.MultipartReader()
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidUpload, err.Error())
+			h.logger.Error("upload is not multipart/form-data")
+			return
+		}
+
+		chatId, err := h.resolveChat(r, chatPublicId)
+		if errors.Is(err, repository.ErrChatNotFound) {
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to resolve chat with id %s: %v", chatPublicId, err))
+			return
+		}
+
+		h.uploadAttachments(w, r, parts, chatId, chatPublicId, callerId(r))
+	}
+}
+
+// uploadAttachments stores every "file" part of the upload in the chat.
+func (h *Handler) uploadAttachments(w http.ResponseWriter, r *http.Request, parts *multipart.Reader, chatId int64, chatPublicId, uploaderId string) {
+	attachments := []*model.Attachment{}
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidUpload, err.Error())
+			h.logger.Error("failed to read upload")
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		if len(attachments) == maxAttachmentsPerMessage {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "file")
+			h.logger.Error("too many files in upload")
+			return
+		}
+
+		attachment, err := h.attachments.Upload(r.Context(), services.NewAttachment{
+			ChatId:     chatId,
+			UploaderId: uploaderId,
+			FileName:   part.FileName(),
+			Content:    part,
+		})
+		var tooLarge *services.AttachmentTooLargeError
+		if errors.As(err, &tooLarge) {
+			writeError(w, r, http.StatusRequestEntityTooLarge, i18n.AttachmentTooLarge, tooLarge.Limit)
+			h.logger.Error(fmt.Sprintf("file %s is too large", part.FileName()))
+			return
+		}
+		var badType *services.AttachmentTypeError
+		if errors.As(err, &badType) {
+			writeError(w, r, http.StatusUnsupportedMediaType, i18n.AttachmentType, badType.ContentType)
+			h.logger.Error(fmt.Sprintf("file %s has type %s that is not allowed", part.FileName(), badType.ContentType))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to upload file to chat %s: %v", chatPublicId, err))
+			return
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	if len(attachments) == 0 {
+		writeError(w, r, http.StatusBadRequest, i18n.InvalidUpload, "no file parts")
+		h.logger.Error("upload has no files")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachments)
+	h.logger.Info(fmt.Sprintf("successfully uploaded %d files to chat %s", len(attachments), chatPublicId))
+}
+
+func (h *Handler) HandleAttachmentsDownload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling download attachment")
+
+		publicId := r.PathValue("id")
+		expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+		if !model.IsPublicId(publicId) || err != nil {
+			writeError(w, r, http.StatusForbidden, i18n.InvalidSignature)
+			h.logger.Error("download link is malformed")
+			return
+		}
+
+		download, err := h.attachments.Open(r.Context(), publicId, r.URL.Query().Get("variant"), expires, r.URL.Query().Get("sig"))
+		if errors.Is(err, services.ErrInvalidSignature) {
+			writeError(w, r, http.StatusForbidden, i18n.InvalidSignature)
+			h.logger.Error(fmt.Sprintf("download link for attachment %s is invalid or expired", publicId))
+			return
+		}
+		if errors.Is(err, repository.ErrAttachmentNotFound) || errors.Is(err, blob.ErrNotFound) {
+			writeError(w, r, http.StatusNotFound, i18n.AttachmentNotFound)
+			h.logger.Error(fmt.Sprintf("attachment %s not found", publicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to open attachment %s: %v", publicId, err))
+			return
+		}
+		defer download.Content.Close()
+
+		// Only images are shown inline; anything else is downloaded so an
+		// uploaded file can never run as a page of this origin.
+		disposition := "attachment"
+		if strings.HasPrefix(download.ContentType, "image/") {
+			disposition = "inline"
+		}
+
+		w.Header().Set("Content-Type", download.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(download.Size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": download.FileName}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", "private, max-age=300")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, download.Content); err != nil {
+			h.logger.Error(fmt.Sprintf("failed to send attachment %s: %v", publicId, err))
+			return
+		}
+		h.logger.Info(fmt.Sprintf("successfully sent attachment %s", publicId))
+	}
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func WithBots(bots services.BotsService) Option {
+	return func(h *Handler) {
+		h.bots = bots
+	}
+}
+
+// Commands run in the background, at most maxRunningCommands at a time and
+// each within commandTimeout; a command beyond the limit is dropped.
+const (
+	maxRunningCommands = 64
+	commandTimeout     = time.Minute
+)
+
+func WithCommands(commands services.CommandsService) Option {
+	return func(h *Handler) {
+		h.commands = commands
+		h.commandSlots = make(chan struct{}, maxRunningCommands)
+	}
+}
+
+// runCommand dispatches the slash command of a new message in the background,
+// so the sender never waits for a bot and leaving does not cancel the
+// command. The message is already stored, so a failing command is only
+// logged.
+func (h *Handler) runCommand(message *model.Message) {
+	if h.commands == nil {
+		return
+	}
+	select {
+	case h.commandSlots <- struct{}{}:
+	default:
+		h.logger.Error(fmt.Sprintf("too many commands running, dropped the command of message %s", message.PublicId))
+		return
+	}
+
+	go func() {
+		defer func() { <-h.commandSlots }()
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+
+		reply, err := h.commands.Dispatch(ctx, message)
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("command of message %s failed: %v", message.PublicId, err))
+			return
+		}
+		if reply != nil {
+			h.logger.Info(fmt.Sprintf("answered command of message %s with message %s", message.PublicId, reply.PublicId))
+		}
+	}()
+}
+
+func (h *Handler) HandleBotsCreate() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling create bot")
+
+		type BotCommandReq struct {
+			Command     string `json:"command"`
+			Description string `json:"description"`
+		}
+		type CreateBotReq struct {
+			Name     string          `json:"name"`
+			Endpoint string          `json:"endpoint"`
+			Commands []BotCommandReq `json:"commands"`
+		}
+
+		var req CreateBotReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+
+		input := services.NewBot{Name: req.Name, Endpoint: req.Endpoint}
+		for _, c := range req.Commands {
+			input.Commands = append(input.Commands, model.BotCommand{Command: c.Command, Description: c.Description})
+		}
+
+		bot, err := h.bots.Create(r.Context(), input)
+		var validationErr *services.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			writeValidationError(w, r, err)
+			h.logger.Error("bot request is invalid")
+			return
+		case errors.Is(err, repository.ErrBotNameTaken):
+			writeError(w, r, http.StatusConflict, i18n.BotNameTaken)
+			h.logger.Error(fmt.Sprintf("bot with name %s already exists", req.Name))
+			return
+		case errors.Is(err, repository.ErrCommandTaken):
+			writeError(w, r, http.StatusConflict, i18n.CommandTaken)
+			h.logger.Error(fmt.Sprintf("commands of bot %s are taken by another bot", req.Name))
+			return
+		case err != nil:
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to create bot: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(bot)
+		h.logger.Info(fmt.Sprintf("successfully created bot %s", bot.Name))
+	})
+}
+
+func (h *Handler) HandleBotsList() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list bots")
+
+		bots, err := h.bots.List(r.Context())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to list bots: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bots)
+	})
+}
+
+func (h *Handler) HandleBotsDelete() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling delete bot")
+
+		publicId, ok := h.botId(w, r)
+		if !ok {
+			return
+		}
+
+		err := h.bots.Delete(r.Context(), publicId)
+		if !h.checkBotErr(w, r, publicId, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		h.logger.Info(fmt.Sprintf("successfully deleted bot %s", publicId))
+	})
+}
+
+// HandleBotsRotateKey issues a new API key; the old one stops working.
+func (h *Handler) HandleBotsRotateKey() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling rotate bot key")
+
+		publicId, ok := h.botId(w, r)
+		if !ok {
+			return
+		}
+
+		bot, err := h.bots.RotateKey(r.Context(), publicId)
+		if !h.checkBotErr(w, r, publicId, err) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bot)
+		h.logger.Info(fmt.Sprintf("successfully rotated key of bot %s", publicId))
+	})
+}
+
+func (h *Handler) botId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	publicId := r.PathValue("id")
+	if !model.IsPublicId(publicId) {
+		writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "id")
+		h.logger.Error("bot id is invalid")
+		return "", false
+	}
+	return publicId, true
+}
+
+func (h *Handler) checkBotErr(w http.ResponseWriter, r *http.Request, publicId string, err error) bool {
+	if errors.Is(err, repository.ErrBotNotFound) {
+		writeError(w, r, http.StatusNotFound, i18n.BotNotFound)
+		h.logger.Error(fmt.Sprintf("bot with id %s not found", publicId))
+		return false
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+		h.logger.Error(fmt.Sprintf("bot %s request failed: %v", publicId, err))
+		return false
+	}
+	return true
+}
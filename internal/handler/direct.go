@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HandleDirectOpen opens the direct chat of the caller with the {userId} user,
+// creating it on first use. Either of the two gets the same chat.
+func (h *Handler) HandleDirectOpen() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling open direct chat")
+
+		userId, ok := h.callerUser(w, r)
+		if !ok {
+			return
+		}
+		otherId := r.PathValue("userId")
+
+		chat, created, err := h.chats.OpenDirectChat(r.Context(), userId, otherId)
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(w, r, err)
+			h.logger.Error(fmt.Sprintf("cannot open direct chat with %q", otherId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to open direct chat of %q with %q: %v", userId, otherId, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if created {
+			w.WriteHeader(http.StatusCreated)
+		}
+		json.NewEncoder(w).Encode(chat)
+		h.logger.Info(fmt.Sprintf("opened direct chat %s of %q with %q", chat.PublicId, userId, otherId))
+	}
+}
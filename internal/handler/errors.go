@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type errorResponse struct {
+	Error  string       `json:"error"`
+	Code   string       `json:"code"`
+	Fields []fieldError `json:"fields,omitempty"`
+}
+
+type fieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeValidationError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErr *services.ValidationError
+	if !errors.As(err, &validationErr) {
+		writeError(w, r, http.StatusBadRequest, i18n.ValidationFailed, err.Error())
+		return
+	}
+
+	lang := i18n.FromRequest(r)
+	resp := errorResponse{Code: i18n.ValidationFailed}
+	for _, f := range validationErr.Fields {
+		msg := i18n.TranslateField(lang, f.Field, f.Code, f.Limit)
+		resp.Fields = append(resp.Fields, fieldError{Field: f.Field, Code: f.Code, Message: msg})
+		if resp.Error == "" {
+			resp.Error = msg
+		} else {
+			resp.Error += "; " + msg
+		}
+	}
+
+	writeJSONError(w, lang, http.StatusBadRequest, resp)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, args ...any) {
+	lang := i18n.FromRequest(r)
+	writeJSONError(w, lang, status, errorResponse{
+		Error: i18n.Translate(lang, code, args...),
+		Code:  code,
+	})
+}
+
+func writeJSONError(w http.ResponseWriter, lang string, status int, resp errorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", lang)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
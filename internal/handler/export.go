@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"chats-api/internal/export"
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+func (h *Handler) HandleChatsExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling export chat")
+
+		chatPublicId := r.PathValue("id")
+		if !model.IsPublicId(chatPublicId) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidChatId)
+			h.logger.Error("chat id is invalid")
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = export.FormatJSON
+		}
+		enc, err := export.NewEncoder(format, w)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "format")
+			h.logger.Error("export format is invalid")
+			return
+		}
+
+		var filter repository.MessagesFilter
+		for name, dst := range map[string]*time.Time{"from": &filter.Since, "to": &filter.Until} {
+			v := r.URL.Query().Get(name)
+			if v == "" {
+				continue
+			}
+			t, ok := parseExportTime(v, name == "to")
+			if !ok {
+				writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, name)
+				h.logger.Error(name + " is invalid")
+				return
+			}
+			*dst = t
+		}
+
+		chatId, err := h.resolveChat(r, chatPublicId)
+		if errors.Is(err, repository.ErrChatNotFound) {
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to resolve chat with id %s: %v", chatPublicId, err))
+			return
+		}
+
+		chat, err := h.chats.GetChat(chatId)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to get chat with id %s: %v", chatPublicId, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", enc.ContentType())
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": exportFilename(chat, enc.Extension()),
+		}))
+		w.WriteHeader(http.StatusOK)
+
+		// The status is already sent, so a failure mid-stream can only cut the
+		// transcript short.
+		err = enc.Begin(chat)
+		if err == nil {
+			err = h.messages.ExportMessages(r.Context(), chatId, filter, enc.Message)
+		}
+		if err == nil {
+			err = enc.End()
+		}
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("failed to export chat with id %s: %v", chatPublicId, err))
+			return
+		}
+
+		h.logger.Info(fmt.Sprintf("successfully exported chat with id %s as %s", chatPublicId, format))
+	}
+}
+
+// parseExportTime accepts RFC 3339 timestamps and plain dates. A plain date
+// used as an upper bound includes the whole day.
+func parseExportTime(v string, upper bool) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, true
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+func exportFilename(chat *model.Chat, ext string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			return r
+		case unicode.IsSpace(r):
+			return '_'
+		}
+		return -1
+	}, chat.Title)
+	if name == "" {
+		name = chat.PublicId
+	}
+
+	return fmt.Sprintf("%s-%s.%s", name, model.Now().Format("20060102"), ext)
+}
@@ -1,29 +1,86 @@
 package handler
 
 import (
+	"chats-api/internal/i18n"
 	"chats-api/internal/model"
+	"chats-api/internal/realtime"
 	"chats-api/internal/repository"
 	"chats-api/internal/services"
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
+)
+
+const (
+	maxClientMsgIdLen        = 100
+	maxAttachmentsPerMessage = 10
 )
 
 type Handler struct {
-	chats    services.ChatsService
-	messages services.MessagesService
-	logger   *slog.Logger
+	chats         services.ChatsService
+	messages      services.MessagesService
+	sync          services.SyncService
+	imports       services.ImportService
+	attachments   services.AttachmentsService
+	webhooks      services.WebhooksService
+	members       services.MembersService
+	hooks         services.IncomingHooksService
+	bots          services.BotsService
+	commands      services.CommandsService
+	commandSlots  chan struct{}
+	reads         services.ReadsService
+	hub           *realtime.Hub
+	presence      *realtime.Presence
+	notifications services.NotificationsService
+	pins          services.PinsService
+	scheduled     services.ScheduledService
+	logger        *slog.Logger
+
+	adminToken string
+}
+
+type Option func(*Handler)
+
+func WithSync(sync services.SyncService) Option {
+	return func(h *Handler) {
+		h.sync = sync
+	}
+}
+
+func WithImport(imports services.ImportService) Option {
+	return func(h *Handler) {
+		h.imports = imports
+	}
+}
+
+func WithAttachments(attachments services.AttachmentsService) Option {
+	return func(h *Handler) {
+		h.attachments = attachments
+	}
+}
+
+func WithAdminToken(token string) Option {
+	return func(h *Handler) {
+		h.adminToken = token
+	}
 }
 
-func NewHandler(chats services.ChatsService, messages services.MessagesService, logger *slog.Logger) *Handler {
-	return &Handler{
+func NewHandler(chats services.ChatsService, messages services.MessagesService, logger *slog.Logger, opts ...Option) *Handler {
+	h := &Handler{
 		chats:    chats,
 		messages: messages,
 		logger:   logger,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
 }
 
 func (h *Handler) HandleChatsCreate() http.HandlerFunc {
@@ -32,6 +89,9 @@ func (h *Handler) HandleChatsCreate() http.HandlerFunc {
 
 		type CreateChatReq struct {
 			Title string `json:"title"`
+			// Type is group, the default, or broadcast; direct chats are
+			// opened with HandleDirectOpen.
+			Type string `json:"type"`
 		}
 
 		var req CreateChatReq
@@ -39,21 +99,33 @@ func (h *Handler) HandleChatsCreate() http.HandlerFunc {
 		decoder.DisallowUnknownFields()
 
 		if err := decoder.Decode(&req); err != nil {
-			writeError(w, http.StatusBadRequest, "invalid json body: "+err.Error())
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
 			h.logger.Error("got invalid json body")
 			return
 		}
 
+		chatType := cmp.Or(req.Type, model.ChatGroup)
+		if chatType != model.ChatGroup && chatType != model.ChatBroadcast {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "type")
+			h.logger.Error(fmt.Sprintf("cannot create chat of type %q", req.Type))
+			return
+		}
+
 		title, err := h.chats.ValidateChatCreate(req.Title)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, err.Error())
+			writeValidationError(w, r, err)
 			h.logger.Error("chat request is invalid")
 			return
 		}
 
-		chat, err := h.chats.CreateChat(r.Context(), title)
+		chat, err := h.chats.CreateChat(r.Context(), title, chatType, callerId(r))
+		if errors.Is(err, repository.ErrChatTitleTaken) {
+			writeError(w, r, http.StatusConflict, i18n.ChatTitleTaken)
+			h.logger.Error(fmt.Sprintf("chat with title %s already exists", title))
+			return
+		}
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, err.Error())
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
 			h.logger.Error(fmt.Sprintf("failed to create chat %v", err))
 			return
 		}
@@ -69,59 +141,169 @@ func (h *Handler) HandleMessagesCreate() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		h.logger.Info("handling create chat")
 
-		chatIdStr := r.PathValue("id")
-		chatId, err := strconv.Atoi(chatIdStr)
-		if err != nil || chatId == 0 {
-			writeError(w, http.StatusBadRequest, "invalid chat_id")
+		chatPublicId := r.PathValue("id")
+		if !model.IsPublicId(chatPublicId) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidChatId)
 			h.logger.Error("chat id is invalid")
 			return
 		}
 
 		type CreateMessageReq struct {
-			Text string `json:"text"`
+			Text          string   `json:"text"`
+			ClientMsgId   string   `json:"client_msg_id"`
+			AttachmentIds []string `json:"attachment_ids"`
+			ReplyTo       string   `json:"reply_to"`
+			// SendAt schedules the message instead of posting it now.
+			SendAt *time.Time `json:"send_at"`
+			// ExpiresIn is how many seconds the message lives.
+			ExpiresIn *int64 `json:"expires_in"`
 		}
 
 		var req CreateMessageReq
 		decoder := json.NewDecoder(r.Body)
 		decoder.DisallowUnknownFields()
 		if err := decoder.Decode(&req); err != nil {
-			writeError(w, http.StatusBadRequest, "invalid json body: "+err.Error())
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
 			h.logger.Error("got invalid json body")
 			return
 		}
 
-		if err := h.messages.ValidateMessageCreate(req.Text); err != nil {
-			writeError(w, http.StatusBadRequest, err.Error())
-			h.logger.Error("message request is invalid")
+		text, ok := h.messageText(w, r, req.Text, req.AttachmentIds)
+		if !ok {
 			return
 		}
 
-		message, err := h.messages.CreateMessage(r.Context(), req.Text, chatId)
+		if len(req.ClientMsgId) > maxClientMsgIdLen {
+			writeError(w, r, http.StatusBadRequest, i18n.ClientMsgIdTooLong)
+			h.logger.Error("client_msg_id is too long")
+			return
+		}
+		if req.ReplyTo != "" && !model.IsPublicId(req.ReplyTo) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "reply_to")
+			h.logger.Error("reply_to is invalid")
+			return
+		}
+		if req.ExpiresIn != nil && (*req.ExpiresIn <= 0 || *req.ExpiresIn > services.MaxSeconds) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "expires_in")
+			h.logger.Error("expires_in is out of range")
+			return
+		}
+
+		chatId, err := h.resolveChat(r, chatPublicId)
 		if errors.Is(err, repository.ErrChatNotFound) {
-			writeError(w, http.StatusNotFound, err.Error())
-			h.logger.Error(fmt.Sprintf("chat with id %d not found", chatId))
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
 			return
 		}
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, err.Error())
-			h.logger.Error(fmt.Sprintf("failed to create message %v", err.Error()))
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to resolve chat with id %s: %v", chatPublicId, err))
+			return
+		}
+
+		input := services.NewMessage{
+			ChatId:        chatId,
+			SenderId:      callerId(r),
+			ClientMsgId:   req.ClientMsgId,
+			Text:          text,
+			AttachmentIds: req.AttachmentIds,
+			ReplyTo:       req.ReplyTo,
+		}
+		if req.ExpiresIn != nil {
+			input.ExpiresIn = time.Duration(*req.ExpiresIn) * time.Second
+		}
+		bot := callerBot(r)
+		if bot != nil {
+			input.SenderName = bot.Name
+		}
+		if req.SendAt != nil {
+			h.scheduleMessage(w, r, chatPublicId, input, *req.SendAt)
 			return
 		}
 
+		message, err := h.messages.CreateMessage(r.Context(), input)
+		// Bots cannot run commands, so two bots never answer each other forever.
+		if err == nil && bot == nil {
+			h.runCommand(message)
+		}
+		h.writeCreatedMessage(w, r, chatPublicId, req.ClientMsgId, message, err)
+	}
+}
+
+// messageText checks the attachment ids and validates the text of a new
+// message, which may go without text when it has attachments.
+func (h *Handler) messageText(w http.ResponseWriter, r *http.Request, text string, attachmentIds []string) (string, bool) {
+	if len(attachmentIds) > maxAttachmentsPerMessage {
+		writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "attachment_ids")
+		h.logger.Error("too many attachments")
+		return "", false
+	}
+	for _, id := range attachmentIds {
+		if !model.IsPublicId(id) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "attachment_ids")
+			h.logger.Error("attachment id is invalid")
+			return "", false
+		}
+	}
+
+	if text == "" && len(attachmentIds) > 0 {
+		return "", true
+	}
+	text, err := h.messages.ValidateMessageCreate(text)
+	if err != nil {
+		writeValidationError(w, r, err)
+		h.logger.Error("message request is invalid")
+		return "", false
+	}
+	return text, true
+}
+
+func (h *Handler) writeCreatedMessage(w http.ResponseWriter, r *http.Request, chatPublicId, clientMsgId string, message *model.Message, err error) {
+	if errors.Is(err, repository.ErrDuplicateMessage) {
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(message)
-		h.logger.Info(fmt.Sprintf("successfully created message in chat %d with id: %d", chatId, message.Id))
+		h.logger.Info(fmt.Sprintf("message with client_msg_id %s already exists in chat %s", clientMsgId, chatPublicId))
+		return
+	}
+	if errors.Is(err, repository.ErrChatNotFound) {
+		writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+		h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+		return
+	}
+	if errors.Is(err, repository.ErrChatReadOnly) {
+		writeError(w, r, http.StatusForbidden, i18n.ChatReadOnly)
+		h.logger.Error(fmt.Sprintf("%s cannot post to chat %s", callerId(r), chatPublicId))
+		return
+	}
+	if errors.Is(err, repository.ErrAttachmentNotFound) {
+		writeError(w, r, http.StatusBadRequest, i18n.AttachmentNotFound)
+		h.logger.Error(fmt.Sprintf("attachments of new message in chat %s not found", chatPublicId))
+		return
+	}
+	if errors.Is(err, repository.ErrReplyNotFound) {
+		writeError(w, r, http.StatusBadRequest, i18n.ReplyNotFound)
+		h.logger.Error(fmt.Sprintf("message replied to in chat %s not found", chatPublicId))
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+		h.logger.Error(fmt.Sprintf("failed to create message %v", err.Error()))
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+	h.logger.Info(fmt.Sprintf("successfully created message in chat %s with id: %s", chatPublicId, message.PublicId))
 }
 
 func (h *Handler) HandleMessagesGet() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		h.logger.Info("handling get chat")
-		chatIdStr := r.PathValue("id")
-		chatId, err := strconv.Atoi(chatIdStr)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid chat_id")
+		chatPublicId := r.PathValue("id")
+		if !model.IsPublicId(chatPublicId) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidChatId)
 			h.logger.Error("chat id is invalid")
 			return
 		}
@@ -131,8 +313,8 @@ func (h *Handler) HandleMessagesGet() http.HandlerFunc {
 
 		if limitStr != "" {
 			l, err := strconv.Atoi(limitStr)
-			if err != nil {
-				writeError(w, http.StatusBadRequest, "invalid limit")
+			if err != nil || l < 1 {
+				writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "limit")
 				h.logger.Error("limit is invalid")
 				return
 			}
@@ -143,27 +325,54 @@ func (h *Handler) HandleMessagesGet() http.HandlerFunc {
 			limit = l
 		}
 
+		page := repository.MessagesPage{Limit: limit}
+		for name, dst := range map[string]*int64{"before": &page.BeforeSeq, "after": &page.AfterSeq} {
+			v := r.URL.Query().Get(name)
+			if v == "" {
+				continue
+			}
+			seq, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || seq < 0 {
+				writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, name)
+				h.logger.Error(name + " is invalid")
+				return
+			}
+			*dst = seq
+		}
+
 		type Response struct {
 			Chat     *model.Chat      `json:"chat"`
 			Messages []*model.Message `json:"messages"`
 		}
 
+		chatId, err := h.resolveChat(r, chatPublicId)
+		if errors.Is(err, repository.ErrChatNotFound) {
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to resolve chat with id %s: %v", chatPublicId, err))
+			return
+		}
+
 		chat, err := h.chats.GetChat(chatId)
 		if errors.Is(err, repository.ErrChatNotFound) {
-			writeError(w, http.StatusNotFound, err.Error())
-			h.logger.Error(fmt.Sprintf("chat with id %d not found", chatId))
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
 			return
 		}
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, err.Error())
-			h.logger.Error(fmt.Sprintf("failed to get chat with id %d: %v", chatId, err))
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to get chat with id %s: %v", chatPublicId, err))
 			return
 		}
 
-		messages, err := h.messages.GetAllMessagesFromChat(chatId, limit)
+		messages, err := h.messages.GetAllMessagesFromChat(r.Context(), chatId, page)
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, err.Error())
-			h.logger.Error(fmt.Sprintf("failed to get messages from chat with id %d: %v", chatId, err))
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to get messages from chat with id %s: %v", chatPublicId, err))
 			return
 		}
 		resp := Response{
@@ -173,43 +382,325 @@ func (h *Handler) HandleMessagesGet() http.HandlerFunc {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(&resp)
-		h.logger.Info(fmt.Sprintf("successfully fetched chat with id %d and limit %d", chatId, limit))
+		h.logger.Info(fmt.Sprintf("successfully fetched chat with id %s and limit %d", chatPublicId, limit))
+	}
+}
+
+func (h *Handler) HandleChatsList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list chats")
+
+		limit, ok := parseLimit(r, 50, 100)
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "limit")
+			h.logger.Error("limit is invalid")
+			return
+		}
+
+		after := r.URL.Query().Get("after")
+		if after != "" && !model.IsPublicId(after) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "after")
+			h.logger.Error("after is invalid")
+			return
+		}
+
+		chats, err := h.chats.ListChats(r.Context(), callerId(r), after, limit)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to list chats: %v", err))
+			return
+		}
+
+		type Response struct {
+			Chats []*model.Chat `json:"chats"`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(&Response{Chats: chats})
+		h.logger.Info(fmt.Sprintf("successfully listed %d chats", len(chats)))
 	}
 }
 
+// HandleChatsDelete moves the chat to the trash. Only admins of the chat can
+// delete it, and only the admin token those without members.
 func (h *Handler) HandleChatsDelete() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		h.logger.Info("handling delete chat")
 
-		chatIdStr := r.PathValue("id")
-		chatId, err := strconv.Atoi(chatIdStr)
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		err := h.chats.DeleteChat(chatId)
+		if errors.Is(err, repository.ErrChatNotFound) {
+			writeError(w, r, http.StatusNoContent, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to delete chat with id %s: %v", chatPublicId, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNoContent)
+		h.logger.Info(fmt.Sprintf("successfully deleted chat with id %s", chatPublicId))
+	}
+}
+
+func (h *Handler) HandleChatsTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list deleted chats")
+
+		limit, ok := parseLimit(r, 50, 100)
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "limit")
+			h.logger.Error("limit is invalid")
+			return
+		}
+
+		// The admin token sees the whole trash, users the chats they are
+		// admins of.
+		var chats []*model.Chat
+		var err error
+		if h.hasAdminToken(r) {
+			chats, err = h.chats.ListDeletedChats(r.Context(), limit)
+		} else {
+			userId, ok := h.callerUser(w, r)
+			if !ok {
+				return
+			}
+			chats, err = h.chats.ListDeletedChatsFor(r.Context(), userId, limit)
+		}
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid chat_id")
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to list deleted chats: %v", err))
+			return
+		}
+
+		type Response struct {
+			Chats []*model.Chat `json:"chats"`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(&Response{Chats: chats})
+		h.logger.Info(fmt.Sprintf("successfully listed %d deleted chats", len(chats)))
+	}
+}
+
+func (h *Handler) HandleChatsRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling restore chat")
+
+		chatPublicId := r.PathValue("id")
+		if !model.IsPublicId(chatPublicId) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidChatId)
 			h.logger.Error("chat id is invalid")
 			return
 		}
 
-		if err := h.chats.DeleteChat(chatId); errors.Is(err, repository.ErrChatNotFound) {
-			writeError(w, http.StatusNoContent, err.Error())
-			h.logger.Error(fmt.Sprintf("chat with id %d not found", chatId))
+		// Users can restore only the chats they are admins of; any other chat
+		// is not in their trash.
+		var chat *model.Chat
+		var err error
+		if h.hasAdminToken(r) {
+			chat, err = h.chats.RestoreChat(r.Context(), chatPublicId)
+		} else {
+			userId, ok := h.callerUser(w, r)
+			if !ok {
+				return
+			}
+			chat, err = h.chats.RestoreChatFor(r.Context(), chatPublicId, userId)
+		}
+		if errors.Is(err, repository.ErrChatNotInTrash) {
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotInTrash)
+			h.logger.Error(fmt.Sprintf("chat with id %s cannot be restored", chatPublicId))
+			return
+		}
+		if errors.Is(err, repository.ErrDirectChatReopened) {
+			writeError(w, r, http.StatusConflict, i18n.DirectChatReopened)
+			h.logger.Error(fmt.Sprintf("users of direct chat %s have opened a new one", chatPublicId))
+			return
+		}
+		if errors.Is(err, repository.ErrChatTitleTaken) {
+			writeError(w, r, http.StatusConflict, i18n.ChatTitleTaken)
+			h.logger.Error(fmt.Sprintf("title of chat %s is taken by another chat", chatPublicId))
 			return
 		}
 		if err != nil {
-			writeError(w, http.StatusInternalServerError, err.Error())
-			h.logger.Error(fmt.Sprintf("failed to delete chat with id %d: %v", chatId, err))
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to restore chat with id %s: %v", chatPublicId, err))
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusNoContent)
-		h.logger.Info(fmt.Sprintf("successfully deleted chat with id %d", chatId))
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(chat)
+		h.logger.Info(fmt.Sprintf("successfully restored chat with id %s", chatPublicId))
 	}
 }
 
-func writeError(w http.ResponseWriter, code int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(map[string]string{
-		"error": msg,
-	})
+// HandleChatsRetention sets how long the chat keeps its messages. Purging
+// cannot be undone, so only admins of the chat can set it.
+func (h *Handler) HandleChatsRetention() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling set chat retention")
+
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		type RetentionReq struct {
+			MaxAgeSeconds *int64 `json:"max_age_seconds"`
+			MaxMessages   *int64 `json:"max_messages"`
+		}
+
+		var req RetentionReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+
+		chat, err := h.chats.SetRetention(r.Context(), chatId, model.RetentionPolicy{
+			MaxAgeSeconds: req.MaxAgeSeconds,
+			MaxMessages:   req.MaxMessages,
+		})
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(w, r, err)
+			h.logger.Error("retention request is invalid")
+			return
+		}
+		if errors.Is(err, repository.ErrChatNotFound) {
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to set retention of chat with id %s: %v", chatPublicId, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(chat)
+		h.logger.Info(fmt.Sprintf("successfully set retention of chat with id %s", chatPublicId))
+	}
+}
+
+// HandleChatsExpiration makes messages posted to the chat from now on
+// disappear after expires_in seconds; null stops it.
+func (h *Handler) HandleChatsExpiration() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling set chat expiration")
+
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		type ExpirationReq struct {
+			ExpiresIn *int64 `json:"expires_in"`
+		}
+
+		var req ExpirationReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+
+		chat, err := h.chats.SetExpiresIn(r.Context(), chatId, req.ExpiresIn)
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(w, r, err)
+			h.logger.Error("expiration request is invalid")
+			return
+		}
+		if errors.Is(err, repository.ErrChatNotFound) {
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to set expiration of chat with id %s: %v", chatPublicId, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(chat)
+		h.logger.Info(fmt.Sprintf("successfully set expiration of chat with id %s", chatPublicId))
+	}
+}
+
+func (h *Handler) HandleSync() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling sync")
+
+		limit, ok := parseLimit(r, 500, 1000)
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "limit")
+			h.logger.Error("limit is invalid")
+			return
+		}
+
+		result, err := h.sync.Sync(r.Context(), callerId(r), r.URL.Query().Get("since"), limit)
+		if errors.Is(err, services.ErrInvalidSyncToken) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidSyncToken)
+			h.logger.Error("sync token is invalid")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to sync: %v", err))
+			return
+		}
+
+		if result.ResyncRequired {
+			lang := i18n.FromRequest(r)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Language", lang)
+			w.WriteHeader(http.StatusGone)
+			json.NewEncoder(w).Encode(map[string]any{
+				"error":           i18n.Translate(lang, i18n.ResyncRequired),
+				"code":            i18n.ResyncRequired,
+				"resync_required": true,
+				"next":            result.Next,
+			})
+			h.logger.Warn("sync token is too old, requested full resync")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+		h.logger.Info(fmt.Sprintf("successfully synced up to %s", result.Next))
+	}
+}
+
+func parseLimit(r *http.Request, def, max int) (int, bool) {
+	limitStr := r.URL.Query().Get("limit")
+	if limitStr == "" {
+		return def, true
+	}
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 {
+		return 0, false
+	}
+
+	return min(limit, max), true
 }
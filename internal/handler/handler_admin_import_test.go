@@ -0,0 +1,122 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/importer"
+	"chats-api/internal/services"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockImportService struct {
+	mock.Mock
+}
+
+func (m *MockImportService) Import(ctx context.Context, format string, r io.Reader, title string, dryRun bool) (*services.ImportReport, error) {
+	body, _ := io.ReadAll(r)
+	args := m.Called(format, string(body), title, dryRun)
+	report, _ := args.Get(0).(*services.ImportReport)
+	return report, args.Error(1)
+}
+
+func TestHandler_HandleAdminImport(t *testing.T) {
+	const body = `{"type":"chat","chat":{"Title":"Family"}}
+{"type":"note"}
+`
+
+	tests := []struct {
+		name           string
+		token          string
+		query          string
+		setupMock      func(*MockImportService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "missing token",
+			query:          "?format=ndjson",
+			setupMock:      func(m *MockImportService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"code":"unauthorized"`,
+		},
+		{
+			name:           "wrong token",
+			token:          "guess",
+			query:          "?format=ndjson",
+			setupMock:      func(m *MockImportService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"code":"unauthorized"`,
+		},
+		{
+			name:  "unknown format",
+			token: "secret",
+			query: "?format=xml",
+			setupMock: func(m *MockImportService) {
+				m.On("Import", "xml", body, "", false).Return(nil, &services.ImportError{Err: importer.ErrUnknownFormat})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"error":"invalid format"`,
+		},
+		{
+			name:  "broken file",
+			token: "secret",
+			query: "?format=telegram",
+			setupMock: func(m *MockImportService) {
+				m.On("Import", "telegram", body, "", false).Return(nil, &services.ImportError{Err: errors.New("invalid telegram export: unexpected EOF")})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"invalid_import"`,
+		},
+		{
+			name:  "storage failure",
+			token: "secret",
+			query: "?format=ndjson",
+			setupMock: func(m *MockImportService) {
+				m.On("Import", "ndjson", body, "", false).Return(nil, errors.New("connection reset"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+			expectedBody:   `"code":"internal_error"`,
+		},
+		{
+			name:  "dry run",
+			token: "secret",
+			query: "?format=ndjson&dry_run=true&title=Family",
+			setupMock: func(m *MockImportService) {
+				m.On("Import", "ndjson", body, "Family", true).Return(&services.ImportReport{DryRun: true, Chats: 1,
+					Errors: []importer.RecordError{{Record: "line 2", Chat: "Family", Error: `unknown record type "note"`}}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"dry_run":true,"chats":1,"messages":0,"errors":[{"record":"line 2","chat":"Family","error":"unknown record type \"note\""}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockImports := new(MockImportService)
+			test.setupMock(mockImports)
+
+			h := handler.NewHandler(new(MockChatsService), new(MockMessagesService), slog.Default(),
+				handler.WithImport(mockImports), handler.WithAdminToken("secret"))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/import"+test.query, strings.NewReader(body))
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			w := httptest.NewRecorder()
+			h.HandleAdminImport()(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockImports.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockBotsService struct {
+	mock.Mock
+}
+
+func (m *MockBotsService) Create(ctx context.Context, input services.NewBot) (*model.Bot, error) {
+	args := m.Called(input)
+	bot, _ := args.Get(0).(*model.Bot)
+	return bot, args.Error(1)
+}
+
+func (m *MockBotsService) List(ctx context.Context) ([]*model.Bot, error) {
+	args := m.Called()
+	bots, _ := args.Get(0).([]*model.Bot)
+	return bots, args.Error(1)
+}
+
+func (m *MockBotsService) Delete(ctx context.Context, publicId string) error {
+	return m.Called(publicId).Error(0)
+}
+
+func (m *MockBotsService) RotateKey(ctx context.Context, publicId string) (*model.Bot, error) {
+	args := m.Called(publicId)
+	bot, _ := args.Get(0).(*model.Bot)
+	return bot, args.Error(1)
+}
+
+func (m *MockBotsService) Authorize(ctx context.Context, key string) (*model.Bot, error) {
+	args := m.Called(key)
+	bot, _ := args.Get(0).(*model.Bot)
+	return bot, args.Error(1)
+}
+
+type MockCommandsService struct {
+	mock.Mock
+}
+
+func (m *MockCommandsService) Dispatch(ctx context.Context, message *model.Message) (*model.Message, error) {
+	args := m.Called(message)
+	reply, _ := args.Get(0).(*model.Message)
+	return reply, args.Error(1)
+}
+
+// quietT lets expectations be polled for without failing the test.
+type quietT struct{}
+
+func (quietT) Logf(string, ...any)   {}
+func (quietT) Errorf(string, ...any) {}
+func (quietT) FailNow()              {}
+
+// blockingCommands answers once released and reports whether its context
+// was still alive by then.
+type blockingCommands struct {
+	release chan struct{}
+	done    chan error
+}
+
+func (c *blockingCommands) Dispatch(ctx context.Context, message *model.Message) (*model.Message, error) {
+	<-c.release
+	c.done <- ctx.Err()
+	return nil, nil
+}
+
+func TestHandler_MessagesCreate_Commands(t *testing.T) {
+	chatId := model.NewPublicId()
+	bot := &model.Bot{PublicId: "b-1", Name: "deploybot"}
+
+	tests := []struct {
+		name           string
+		authorization  string
+		setupMocks     func(*MockBotsService, *MockMessagesService, *MockCommandsService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "user command is dispatched",
+			setupMocks: func(b *MockBotsService, m *MockMessagesService, c *MockCommandsService) {
+				m.On("ValidateMessageCreate", "/help").Return("/help", nil)
+				message := &model.Message{PublicId: "m-1", SenderId: "alice", Text: "/help"}
+				m.On("CreateMessage", services.NewMessage{ChatId: 7, SenderId: "alice", Text: "/help"}).Return(message, nil)
+				c.On("Dispatch", message).Return(&model.Message{PublicId: "m-2"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"Id":"m-1"`,
+		},
+		{
+			name:          "bot posts in its name without running commands",
+			authorization: "Bot bk_key",
+			setupMocks: func(b *MockBotsService, m *MockMessagesService, c *MockCommandsService) {
+				b.On("Authorize", "bk_key").Return(bot, nil)
+				m.On("ValidateMessageCreate", "/help").Return("/help", nil)
+				m.On("CreateMessage", services.NewMessage{ChatId: 7, SenderId: "bot:b-1", SenderName: "deploybot", Text: "/help"}).
+					Return(&model.Message{PublicId: "m-1", SenderId: "bot:b-1", Text: "/help"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"SenderId":"bot:b-1"`,
+		},
+		{
+			name:          "invalid bot key",
+			authorization: "Bot bk_wrong",
+			setupMocks: func(b *MockBotsService, m *MockMessagesService, c *MockCommandsService) {
+				b.On("Authorize", "bk_wrong").Return(nil, repository.ErrBotNotFound)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"code":"invalid_bot_key"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(7), nil).Maybe()
+			mockBots := new(MockBotsService)
+			mockMessages := new(MockMessagesService)
+			mockCommands := new(MockCommandsService)
+			test.setupMocks(mockBots, mockMessages, mockCommands)
+
+			h := handler.NewHandler(mockChats, mockMessages, slog.Default(),
+				handler.WithBots(mockBots), handler.WithCommands(mockCommands))
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /api/v1/chats/{id}/messages", h.HandleMessagesCreate())
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/chats/"+chatId+"/messages", strings.NewReader(`{"text":"/help"}`))
+			req.Header.Set("X-User-Id", "alice")
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			w := httptest.NewRecorder()
+			h.Authenticate(mux).ServeHTTP(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockBots.AssertExpectations(t)
+			mockMessages.AssertExpectations(t)
+			require.Eventually(t, func() bool { return mockCommands.AssertExpectations(quietT{}) }, time.Second, 5*time.Millisecond)
+			mockCommands.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_MessagesCreate_CommandRunsInBackground(t *testing.T) {
+	chatId := model.NewPublicId()
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(7), nil)
+	mockMessages := new(MockMessagesService)
+	mockMessages.On("ValidateMessageCreate", "/deploy").Return("/deploy", nil)
+	mockMessages.On("CreateMessage", services.NewMessage{ChatId: 7, SenderId: "alice", Text: "/deploy"}).
+		Return(&model.Message{PublicId: "m-1", SenderId: "alice", Text: "/deploy"}, nil)
+	commands := &blockingCommands{release: make(chan struct{}), done: make(chan error, 1)}
+
+	h := handler.NewHandler(mockChats, mockMessages, slog.Default(), handler.WithCommands(commands))
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/chats/{id}/messages", h.HandleMessagesCreate())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/api/v1/chats/"+chatId+"/messages", strings.NewReader(`{"text":"/deploy"}`))
+	req.Header.Set("X-User-Id", "alice")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	// The response is written while the bot has not answered yet, and the
+	// client going away does not cancel the command.
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	cancel()
+	close(commands.release)
+	require.NoError(t, <-commands.done)
+}
@@ -27,21 +27,82 @@ func (m *MockChatsService) ValidateChatCreate(title string) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockChatsService) CreateChat(ctx context.Context, title string) (*model.Chat, error) {
-	args := m.Called(title)
+func (m *MockChatsService) CreateChat(ctx context.Context, title, chatType, creatorId string) (*model.Chat, error) {
+	args := m.Called(title, chatType, creatorId)
 	return args.Get(0).(*model.Chat), args.Error(1)
 }
 
-func (m *MockChatsService) GetChat(id int) (*model.Chat, error) {
+func (m *MockChatsService) OpenDirectChat(ctx context.Context, userId, otherId string) (*model.Chat, bool, error) {
+	args := m.Called(userId, otherId)
+	chat, _ := args.Get(0).(*model.Chat)
+	return chat, args.Bool(1), args.Error(2)
+}
+
+func (m *MockChatsService) GetChat(id int64) (*model.Chat, error) {
 	args := m.Called(id)
 	return args.Get(0).(*model.Chat), args.Error(1)
 }
 
-func (m *MockChatsService) DeleteChat(id int) error {
+func (m *MockChatsService) ResolveChatId(ctx context.Context, publicId string) (int64, error) {
+	args := m.Called(publicId)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockChatsService) ResolveChatIdFor(ctx context.Context, publicId, userId string) (int64, error) {
+	args := m.Called(publicId, userId)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockChatsService) ListChats(ctx context.Context, userId, afterPublicId string, limit int) ([]*model.Chat, error) {
 	//TODO implement me
 	panic("implement me")
 }
 
+func (m *MockChatsService) DeleteChat(id int64) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockChatsService) RestoreChat(ctx context.Context, publicId string) (*model.Chat, error) {
+	args := m.Called(publicId)
+	chat, _ := args.Get(0).(*model.Chat)
+	return chat, args.Error(1)
+}
+
+func (m *MockChatsService) RestoreChatFor(ctx context.Context, publicId, userId string) (*model.Chat, error) {
+	args := m.Called(publicId, userId)
+	chat, _ := args.Get(0).(*model.Chat)
+	return chat, args.Error(1)
+}
+
+func (m *MockChatsService) ListDeletedChatsFor(ctx context.Context, userId string, limit int) ([]*model.Chat, error) {
+	args := m.Called(userId, limit)
+	chats, _ := args.Get(0).([]*model.Chat)
+	return chats, args.Error(1)
+}
+
+func (m *MockChatsService) ListDeletedChats(ctx context.Context, limit int) ([]*model.Chat, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m *MockChatsService) PurgeDeletedChats(ctx context.Context) (int64, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m *MockChatsService) SetRetention(ctx context.Context, id int64, policy model.RetentionPolicy) (*model.Chat, error) {
+	args := m.Called(id, policy)
+	chat, _ := args.Get(0).(*model.Chat)
+	return chat, args.Error(1)
+}
+
+func (m *MockChatsService) SetExpiresIn(ctx context.Context, id int64, expiresIn *int64) (*model.Chat, error) {
+	args := m.Called(id, expiresIn)
+	chat, _ := args.Get(0).(*model.Chat)
+	return chat, args.Error(1)
+}
+
 func TestHandler_HandleChatsCreate(t *testing.T) {
 
 	tests := []struct {
@@ -84,15 +145,41 @@ func TestHandler_HandleChatsCreate(t *testing.T) {
 			setupMock: func(m *MockChatsService) {
 				m.On("ValidateChatCreate", "Family Chat").
 					Return("Family Chat", nil)
-				m.On("CreateChat", "Family Chat").
+				m.On("CreateChat", "Family Chat", "group", "").
 					Return(&model.Chat{
 						Id:        1,
+						PublicId:  "0192f3c4-5b6a-7d8e-9f01-23456789abcd",
 						Title:     "Family Chat",
 						CreatedAt: time.Now(),
 					}, nil)
 			},
 			expectedCode: http.StatusCreated,
-			expectedBody: `"Id":1,"Title":"Family Chat"`,
+			expectedBody: `"Id":"0192f3c4-5b6a-7d8e-9f01-23456789abcd","Title":"Family Chat"`,
+		},
+		{
+			name:          "broadcast chat",
+			requestedBody: `{"title":"News","type":"broadcast"}`,
+			setupMock: func(m *MockChatsService) {
+				m.On("ValidateChatCreate", "News").
+					Return("News", nil)
+				m.On("CreateChat", "News", "broadcast", "").
+					Return(&model.Chat{
+						Id:        1,
+						PublicId:  "0192f3c4-5b6a-7d8e-9f01-23456789abcd",
+						Title:     "News",
+						Type:      model.ChatBroadcast,
+						CreatedAt: time.Now(),
+					}, nil)
+			},
+			expectedCode: http.StatusCreated,
+			expectedBody: `"Type":"broadcast"`,
+		},
+		{
+			name:          "direct chats are not created here",
+			requestedBody: `{"title":"","type":"direct"}`,
+			setupMock:     func(m *MockChatsService) {},
+			expectedCode:  http.StatusBadRequest,
+			expectedBody:  `type`,
 		},
 	}
 
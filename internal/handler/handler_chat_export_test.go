@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_HandleChatsExport(t *testing.T) {
+	const chatID = "0192f3c4-5b6a-7d8e-9f01-23456789abcd"
+	chat := &model.Chat{Id: 1, PublicId: chatID, Title: "Семья"}
+	messages := []*model.Message{
+		{PublicId: "0192f3c4-6000-7000-8000-000000000001", ChatPublicId: chatID, Seq: 1, SenderId: "u-1", Text: "Hello"},
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		setupMocks     func(*MockChatsService, *MockMessagesService)
+		expectedStatus int
+		expectedType   string
+		expectedBody   string
+	}{
+		{
+			name:           "unknown format",
+			query:          "?format=xml",
+			setupMocks:     func(c *MockChatsService, m *MockMessagesService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"error":"invalid format"`,
+		},
+		{
+			name:           "invalid date",
+			query:          "?from=yesterday",
+			setupMocks:     func(c *MockChatsService, m *MockMessagesService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"error":"invalid from"`,
+		},
+		{
+			name:  "ndjson with date range",
+			query: "?format=ndjson&from=2026-01-01&to=2026-01-31",
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				c.On("ResolveChatIdFor", chatID, mock.Anything).Return(int64(1), nil)
+				c.On("GetChat", int64(1)).Return(chat, nil)
+				m.On("ExportMessages", int64(1), repository.MessagesFilter{
+					Since: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+					Until: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+				}).Return(messages, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedType:   "application/x-ndjson",
+			expectedBody:   `{"type":"message","message":{"Id":"0192f3c4-6000-7000-8000-000000000001"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			mockMessages := new(MockMessagesService)
+			test.setupMocks(mockChats, mockMessages)
+
+			h := handler.NewHandler(mockChats, mockMessages, slog.Default())
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET "+apiPrefix+"/{id}/export", h.HandleChatsExport())
+
+			req := httptest.NewRequest(http.MethodGet, apiPrefix+"/"+chatID+"/export"+test.query, nil)
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			if test.expectedType != "" {
+				require.Equal(t, test.expectedType, w.Header().Get("Content-Type"))
+				require.Contains(t, w.Header().Get("Content-Disposition"), "attachment; filename*=utf-8''%D0%A1%D0%B5%D0%BC%D1%8C%D1%8F-")
+			}
+			mockChats.AssertExpectations(t)
+			mockMessages.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_HandleChatsRestore(t *testing.T) {
+	const chatID = "0192f3c4-5b6a-7d8e-9f01-23456789abcd"
+
+	tests := []struct {
+		name           string
+		chatID         string
+		userId         string
+		setupMock      func(*MockChatsService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid chat id",
+			chatID:         "42",
+			userId:         "alice",
+			setupMock:      func(m *MockChatsService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"invalid_chat_id"`,
+		},
+		{
+			name:           "anonymous",
+			chatID:         chatID,
+			userId:         "",
+			setupMock:      func(m *MockChatsService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"code":"user_required"`,
+		},
+		{
+			name:   "grace period expired",
+			chatID: chatID,
+			userId: "alice",
+			setupMock: func(m *MockChatsService) {
+				m.On("RestoreChatFor", chatID, "alice").Return(nil, repository.ErrChatNotInTrash)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"chat_not_in_trash"`,
+		},
+		{
+			name:   "title taken by another chat",
+			chatID: chatID,
+			userId: "alice",
+			setupMock: func(m *MockChatsService) {
+				m.On("RestoreChatFor", chatID, "alice").Return(nil, repository.ErrChatTitleTaken)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `"code":"chat_title_taken"`,
+		},
+		{
+			name:   "direct chat opened again meanwhile",
+			chatID: chatID,
+			userId: "alice",
+			setupMock: func(m *MockChatsService) {
+				m.On("RestoreChatFor", chatID, "alice").Return(nil, repository.ErrDirectChatReopened)
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `"code":"direct_chat_reopened"`,
+		},
+		{
+			name:   "successful restore",
+			chatID: chatID,
+			userId: "alice",
+			setupMock: func(m *MockChatsService) {
+				m.On("RestoreChatFor", chatID, "alice").Return(&model.Chat{Id: 1, PublicId: chatID, Title: "Family"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Id":"` + chatID + `","Title":"Family"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			test.setupMock(mockChats)
+
+			h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default())
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST "+apiPrefix+"/{id}/restore", h.HandleChatsRestore())
+
+			req := httptest.NewRequest(http.MethodPost, apiPrefix+"/"+test.chatID+"/restore", nil)
+			if test.userId != "" {
+				req.Header.Set("X-User-Id", test.userId)
+			}
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code)
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			require.NotContains(t, w.Body.String(), "DeletedAt")
+			mockChats.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleChatsDelete(t *testing.T) {
+	const chatID = "0192f3c4-5b6a-7d8e-9f01-23456789abcd"
+
+	tests := []struct {
+		name           string
+		isAdmin        bool
+		setupMock      func(*MockChatsService)
+		expectedStatus int
+	}{
+		{
+			name:           "not an admin",
+			setupMock:      func(m *MockChatsService) {},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:    "admin",
+			isAdmin: true,
+			setupMock: func(m *MockChatsService) {
+				m.On("DeleteChat", int64(1)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			mockChats.On("ResolveChatIdFor", chatID, "alice").Return(int64(1), nil)
+			test.setupMock(mockChats)
+			mockMembers := new(MockMembersService)
+			mockMembers.On("IsAdmin", int64(1), "alice").Return(test.isAdmin, nil)
+
+			h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(), handler.WithMembers(mockMembers))
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("DELETE "+apiPrefix+"/{id}", h.HandleChatsDelete())
+
+			req := httptest.NewRequest(http.MethodDelete, apiPrefix+"/"+chatID, nil)
+			req.Header.Set("X-User-Id", "alice")
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			mockChats.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleChatsTrash(t *testing.T) {
+	mockChats := new(MockChatsService)
+	mockChats.On("ListDeletedChatsFor", "alice", 50).Return([]*model.Chat{{PublicId: "c-1", Title: "Family"}}, nil)
+
+	h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default())
+
+	req := httptest.NewRequest(http.MethodGet, apiPrefix+"/trash", nil)
+	w := httptest.NewRecorder()
+	h.HandleChatsTrash()(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code, w.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, apiPrefix+"/trash", nil)
+	req.Header.Set("X-User-Id", "alice")
+	w = httptest.NewRecorder()
+	h.HandleChatsTrash()(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"Id":"c-1","Title":"Family"`)
+	mockChats.AssertExpectations(t)
+}
@@ -0,0 +1,158 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/services"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_HandleChatsRetention(t *testing.T) {
+	const chatID = "0192f3c4-5b6a-7d8e-9f01-23456789abcd"
+	maxMessages := int64(100)
+	negative := int64(-1)
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		isAdmin        bool
+		setupMock      func(*MockChatsService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "not an admin",
+			requestBody:    `{"max_messages":1}`,
+			setupMock:      func(m *MockChatsService) {},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `"code":"forbidden"`,
+		},
+		{
+			name:           "unknown field",
+			requestBody:    `{"max_days":3}`,
+			isAdmin:        true,
+			setupMock:      func(m *MockChatsService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"invalid_json"`,
+		},
+		{
+			name:        "negative limit",
+			requestBody: `{"max_messages":-1}`,
+			isAdmin:     true,
+			setupMock: func(m *MockChatsService) {
+				m.On("SetRetention", int64(1), model.RetentionPolicy{MaxMessages: &negative}).
+					Return(nil, &services.ValidationError{Fields: []services.FieldError{
+						{Field: "max_messages", Code: services.CodeNotPositive},
+					}})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"fields":[{"field":"max_messages","code":"not_positive","message":"max_messages must be positive"}]`,
+		},
+		{
+			name:        "successful update",
+			requestBody: `{"max_messages":100}`,
+			isAdmin:     true,
+			setupMock: func(m *MockChatsService) {
+				m.On("SetRetention", int64(1), model.RetentionPolicy{MaxMessages: &maxMessages}).
+					Return(&model.Chat{Id: 1, PublicId: chatID, Title: "Family", Retention: model.RetentionPolicy{MaxMessages: &maxMessages}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Retention":{"MaxAgeSeconds":null,"MaxMessages":100}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			mockChats.On("ResolveChatIdFor", chatID, "alice").Return(int64(1), nil)
+			test.setupMock(mockChats)
+			mockMembers := new(MockMembersService)
+			mockMembers.On("IsAdmin", int64(1), "alice").Return(test.isAdmin, nil)
+
+			h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(), handler.WithMembers(mockMembers))
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT "+apiPrefix+"/{id}/retention", h.HandleChatsRetention())
+
+			req := httptest.NewRequest(http.MethodPut, apiPrefix+"/"+chatID+"/retention", strings.NewReader(test.requestBody))
+			req.Header.Set("X-User-Id", "alice")
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockChats.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleChatsExpiration(t *testing.T) {
+	const chatID = "0192f3c4-5b6a-7d8e-9f01-23456789abcd"
+	day := int64(86400)
+	zero := int64(0)
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		setupMock      func(*MockChatsService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "not positive",
+			requestBody: `{"expires_in":0}`,
+			setupMock: func(m *MockChatsService) {
+				m.On("SetExpiresIn", int64(1), &zero).
+					Return(nil, &services.ValidationError{Fields: []services.FieldError{{Field: "expires_in", Code: services.CodeNotPositive}}})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"field":"expires_in","code":"not_positive"`,
+		},
+		{
+			name:        "set",
+			requestBody: `{"expires_in":86400}`,
+			setupMock: func(m *MockChatsService) {
+				m.On("SetExpiresIn", int64(1), &day).Return(&model.Chat{PublicId: chatID, Title: "secret", ExpiresIn: &day}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"ExpiresIn":86400`,
+		},
+		{
+			name:        "cleared",
+			requestBody: `{"expires_in":null}`,
+			setupMock: func(m *MockChatsService) {
+				m.On("SetExpiresIn", int64(1), (*int64)(nil)).Return(&model.Chat{PublicId: chatID, Title: "secret"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Title":"secret"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			mockChats.On("ResolveChatId", chatID).Return(int64(1), nil)
+			test.setupMock(mockChats)
+
+			h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(), handler.WithAdminToken("secret"))
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT "+apiPrefix+"/{id}/expiration", h.HandleChatsExpiration())
+
+			req := httptest.NewRequest(http.MethodPut, apiPrefix+"/"+chatID+"/expiration", strings.NewReader(test.requestBody))
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockChats.AssertExpectations(t)
+		})
+	}
+}
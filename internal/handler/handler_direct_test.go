@@ -0,0 +1,157 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/realtime"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_HandleDirectOpen(t *testing.T) {
+	chatId := model.NewPublicId()
+	key := model.DirectKey("alice", "bob")
+	chat := &model.Chat{PublicId: chatId, Type: model.ChatDirect, DirectKey: &key}
+
+	tests := []struct {
+		name           string
+		userId         string
+		otherId        string
+		setupMock      func(*MockChatsService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "anonymous",
+			otherId:        "bob",
+			setupMock:      func(m *MockChatsService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"code":"user_required"`,
+		},
+		{
+			name:    "invalid user id",
+			userId:  "alice",
+			otherId: strings.Repeat("b", 101),
+			setupMock: func(m *MockChatsService) {
+				m.On("OpenDirectChat", "alice", strings.Repeat("b", 101)).
+					Return(nil, false, &services.ValidationError{Fields: []services.FieldError{
+						{Field: "user_id", Code: services.CodeTooLong, Limit: 100},
+					}})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"field":"user_id","code":"too_long"`,
+		},
+		{
+			name:    "first opened",
+			userId:  "alice",
+			otherId: "bob",
+			setupMock: func(m *MockChatsService) {
+				m.On("OpenDirectChat", "alice", "bob").Return(chat, true, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"Id":"` + chatId + `","Title":"","Type":"direct"`,
+		},
+		{
+			name:    "opened again by the other user",
+			userId:  "bob",
+			otherId: "alice",
+			setupMock: func(m *MockChatsService) {
+				m.On("OpenDirectChat", "bob", "alice").Return(chat, false, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Id":"` + chatId + `"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			test.setupMock(mockChats)
+
+			h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default())
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/dm/"+test.otherId, nil)
+			req.SetPathValue("userId", test.otherId)
+			if test.userId != "" {
+				req.Header.Set("X-User-Id", test.userId)
+			}
+			w := httptest.NewRecorder()
+			h.HandleDirectOpen()(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			require.NotContains(t, w.Body.String(), "DirectKey")
+			mockChats.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_DirectChatHiddenFromOthers(t *testing.T) {
+	chatId := model.NewPublicId()
+
+	routes := []struct {
+		name    string
+		method  string
+		pattern string
+		path    string
+		body    string
+		handle  func(*handler.Handler) http.HandlerFunc
+	}{
+		{"post message", http.MethodPost, "POST /api/v1/chats/{id}/messages", "/messages", `{"text":"hi"}`, (*handler.Handler).HandleMessagesCreate},
+		{"get history", http.MethodGet, "GET /api/v1/chats/{id}", "", "", (*handler.Handler).HandleMessagesGet},
+		{"export", http.MethodGet, "GET /api/v1/chats/{id}/export", "/export", "", (*handler.Handler).HandleChatsExport},
+		{"pins", http.MethodGet, "GET /api/v1/chats/{id}/pins", "/pins", "", (*handler.Handler).HandlePinsList},
+		{"online", http.MethodGet, "GET /api/v1/chats/{id}/online", "/online", "", (*handler.Handler).HandleChatsOnline},
+	}
+
+	for _, route := range routes {
+		t.Run(route.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			mockChats.On("ResolveChatIdFor", chatId, "mallory").Return(int64(0), repository.ErrChatNotFound)
+			mockMessages := new(MockMessagesService)
+			mockMessages.On("ValidateMessageCreate", "hi").Return("hi", nil).Maybe()
+
+			h := handler.NewHandler(mockChats, mockMessages, slog.Default())
+			mux := http.NewServeMux()
+			mux.HandleFunc(route.pattern, route.handle(h))
+
+			req := httptest.NewRequest(route.method, "/api/v1/chats/"+chatId+route.path, strings.NewReader(route.body))
+			req.Header.Set("X-User-Id", "mallory")
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), `"code":"chat_not_found"`)
+			mockChats.AssertExpectations(t)
+		})
+	}
+
+	t.Run("realtime subscribe", func(t *testing.T) {
+		mockChats := new(MockChatsService)
+		mockChats.On("ResolveChatIdFor", chatId, "mallory").Return(int64(0), repository.ErrChatNotFound)
+
+		hub := realtime.NewHub(8)
+		h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(),
+			handler.WithRealtime(hub, realtime.NewPresence(hub, time.Minute, time.Minute)))
+		server := httptest.NewServer(h.HandleRealtime())
+		defer server.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, resp, err := websocket.Dial(ctx, server.URL+"?chat_id="+chatId, &websocket.DialOptions{HTTPHeader: http.Header{"X-User-Id": {"mallory"}}})
+		require.Error(t, err)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+		mockChats.AssertExpectations(t)
+	})
+}
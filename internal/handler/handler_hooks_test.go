@@ -0,0 +1,182 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockMembersService struct {
+	mock.Mock
+}
+
+func (m *MockMembersService) Role(ctx context.Context, chatId int64, userId string) (string, error) {
+	args := m.Called(chatId, userId)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMembersService) IsAdmin(ctx context.Context, chatId int64, userId string) (bool, error) {
+	args := m.Called(chatId, userId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMembersService) SetRole(ctx context.Context, chatId int64, userId, role string) (*model.ChatMember, error) {
+	args := m.Called(chatId, userId, role)
+	member, _ := args.Get(0).(*model.ChatMember)
+	return member, args.Error(1)
+}
+
+func (m *MockMembersService) Remove(ctx context.Context, chatId int64, userId string) error {
+	return m.Called(chatId, userId).Error(0)
+}
+
+func (m *MockMembersService) List(ctx context.Context, chatId int64) ([]*model.ChatMember, error) {
+	args := m.Called(chatId)
+	members, _ := args.Get(0).([]*model.ChatMember)
+	return members, args.Error(1)
+}
+
+type MockIncomingHooksService struct {
+	mock.Mock
+}
+
+func (m *MockIncomingHooksService) Create(ctx context.Context, chatId int64, name, createdBy string) (*model.IncomingHook, error) {
+	args := m.Called(chatId, name, createdBy)
+	hook, _ := args.Get(0).(*model.IncomingHook)
+	return hook, args.Error(1)
+}
+
+func (m *MockIncomingHooksService) List(ctx context.Context, chatId int64) ([]*model.IncomingHook, error) {
+	args := m.Called(chatId)
+	hooks, _ := args.Get(0).([]*model.IncomingHook)
+	return hooks, args.Error(1)
+}
+
+func (m *MockIncomingHooksService) Revoke(ctx context.Context, chatId int64, publicId string) error {
+	return m.Called(chatId, publicId).Error(0)
+}
+
+func (m *MockIncomingHooksService) Authorize(ctx context.Context, token string) (*model.IncomingHook, error) {
+	args := m.Called(token)
+	hook, _ := args.Get(0).(*model.IncomingHook)
+	return hook, args.Error(1)
+}
+
+func (m *MockIncomingHooksService) ValidateSenderName(name string) (string, error) {
+	args := m.Called(name)
+	return args.String(0), args.Error(1)
+}
+
+func TestHandler_HandleHookPost(t *testing.T) {
+	hook := &model.IncomingHook{Id: 1, PublicId: "h-1", ChatId: 7, ChatPublicId: "c-1"}
+
+	tests := []struct {
+		name           string
+		body           string
+		setupMocks     func(*MockIncomingHooksService, *MockMessagesService)
+		expectedStatus int
+		expectedBody   string
+		retryAfter     string
+	}{
+		{
+			name: "unknown token",
+			body: `{"text":"build passed"}`,
+			setupMocks: func(h *MockIncomingHooksService, m *MockMessagesService) {
+				h.On("Authorize", "ih_token").Return(nil, repository.ErrHookNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"hook_not_found"`,
+		},
+		{
+			name: "rate limited",
+			body: `{"text":"build passed"}`,
+			setupMocks: func(h *MockIncomingHooksService, m *MockMessagesService) {
+				h.On("Authorize", "ih_token").Return(nil, &services.RateLimitError{RetryAfter: 3 * time.Second})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   `"code":"rate_limited"`,
+			retryAfter:     "3",
+		},
+		{
+			name: "empty text",
+			body: `{"text":""}`,
+			setupMocks: func(h *MockIncomingHooksService, m *MockMessagesService) {
+				h.On("Authorize", "ih_token").Return(hook, nil)
+				m.On("ValidateMessageCreate", "").
+					Return("", &services.ValidationError{Fields: []services.FieldError{{Field: "text", Code: services.CodeRequired}}})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"validation_failed"`,
+		},
+		{
+			name: "posted as the hook",
+			body: `{"text":"build passed","username":"CI"}`,
+			setupMocks: func(h *MockIncomingHooksService, m *MockMessagesService) {
+				h.On("Authorize", "ih_token").Return(hook, nil)
+				h.On("ValidateSenderName", "CI").Return("CI", nil)
+				m.On("ValidateMessageCreate", "build passed").Return("build passed", nil)
+				name := "CI"
+				m.On("CreateMessage", services.NewMessage{ChatId: 7, SenderId: "hook:h-1", SenderName: "CI", Text: "build passed"}).
+					Return(&model.Message{PublicId: "m-1", ChatPublicId: "c-1", SenderId: "hook:h-1", SenderName: &name, Text: "build passed"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"SenderName":"CI"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockHooks := new(MockIncomingHooksService)
+			mockMessages := new(MockMessagesService)
+			test.setupMocks(mockHooks, mockMessages)
+
+			h := handler.NewHandler(new(MockChatsService), mockMessages, slog.Default(), handler.WithIncomingHooks(mockHooks))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/hooks/ih_token", strings.NewReader(test.body))
+			req.SetPathValue("token", "ih_token")
+			w := httptest.NewRecorder()
+			h.HandleHookPost()(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			require.Equal(t, test.retryAfter, w.Header().Get("Retry-After"))
+			mockHooks.AssertExpectations(t)
+			mockMessages.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleHooksCreate_RequiresChatAdmin(t *testing.T) {
+	chatId := model.NewPublicId()
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(7), nil)
+	mockMembers := new(MockMembersService)
+	mockMembers.On("IsAdmin", int64(7), "bob").Return(false, nil)
+	mockHooks := new(MockIncomingHooksService)
+
+	h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(),
+		handler.WithMembers(mockMembers), handler.WithIncomingHooks(mockHooks))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/chats/"+chatId+"/hooks", strings.NewReader(`{"name":"CI"}`))
+	req.SetPathValue("id", chatId)
+	req.Header.Set("X-User-Id", "bob")
+	w := httptest.NewRecorder()
+	h.HandleHooksCreate()(w, req)
+
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Contains(t, w.Body.String(), `"code":"forbidden"`)
+	mockHooks.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	mockMembers.AssertExpectations(t)
+}
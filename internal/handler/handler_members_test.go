@@ -0,0 +1,49 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_HandleMembers_DirectChat(t *testing.T) {
+	chatId := model.NewPublicId()
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(7), nil)
+	mockMembers := new(MockMembersService)
+	mockMembers.On("IsAdmin", int64(7), "alice").Return(true, nil)
+	mockMembers.On("SetRole", int64(7), "carol", model.RoleMember).Return(nil, repository.ErrDirectChat)
+	mockMembers.On("Remove", int64(7), "bob").Return(repository.ErrDirectChat)
+
+	h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(), handler.WithMembers(mockMembers))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/chats/"+chatId+"/members/carol", strings.NewReader(`{"role":"member"}`))
+	req.SetPathValue("id", chatId)
+	req.SetPathValue("userId", "carol")
+	req.Header.Set("X-User-Id", "alice")
+	w := httptest.NewRecorder()
+	h.HandleMembersSet()(w, req)
+
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"code":"direct_chat"`)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/v1/chats/"+chatId+"/members/bob", nil)
+	req.SetPathValue("id", chatId)
+	req.SetPathValue("userId", "bob")
+	req.Header.Set("X-User-Id", "alice")
+	w = httptest.NewRecorder()
+	h.HandleMembersRemove()(w, req)
+
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"code":"direct_chat"`)
+	mockMembers.AssertExpectations(t)
+}
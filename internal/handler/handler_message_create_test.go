@@ -3,6 +3,8 @@ package handler_test
 import (
 	"chats-api/internal/handler"
 	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
 	"context"
 	"errors"
 	"fmt"
@@ -11,6 +13,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -20,22 +23,38 @@ type MockMessagesService struct {
 	mock.Mock
 }
 
-func (m *MockMessagesService) ValidateMessageCreate(text string) error {
+func (m *MockMessagesService) ValidateMessageCreate(text string) (string, error) {
 	args := m.Called(text)
-	return args.Error(0)
+	return args.String(0), args.Error(1)
 }
 
-func (m *MockMessagesService) CreateMessage(ctx context.Context, text string, chatId int) (*model.Message, error) {
-	//TODO implement me
-	panic("implement me")
+func (m *MockMessagesService) CreateMessage(ctx context.Context, input services.NewMessage) (*model.Message, error) {
+	args := m.Called(input)
+	message, _ := args.Get(0).(*model.Message)
+	return message, args.Error(1)
 }
 
-func (m *MockMessagesService) GetAllMessagesFromChat(id int, limit int) ([]*model.Message, error) {
+func (m *MockMessagesService) GetAllMessagesFromChat(ctx context.Context, id int64, page repository.MessagesPage) ([]*model.Message, error) {
 	//TODO implement me
 	panic("implement me")
 }
 
+func (m *MockMessagesService) ExportMessages(ctx context.Context, chatId int64, filter repository.MessagesFilter, fn func(*model.Message) error) error {
+	args := m.Called(chatId, filter)
+	if messages, ok := args.Get(0).([]*model.Message); ok {
+		for _, message := range messages {
+			if err := fn(message); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
 func TestHandler_HandleMessagesCreate(t *testing.T) {
+	const chatID = "0192f3c4-5b6a-7d8e-9f01-23456789abcd"
+	clientMsgId := "c-1"
+
 	tests := []struct {
 		name           string
 		chatID         string
@@ -50,7 +69,7 @@ func TestHandler_HandleMessagesCreate(t *testing.T) {
 			requestBody:    `{"text":"Hello"}`,
 			setupMocks:     func(c *MockChatsService, m *MockMessagesService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"invalid chat_id"}`,
+			expectedBody:   `{"error":"invalid chat_id","code":"invalid_chat_id"}`,
 		},
 		{
 			name:           "invalid chat id - zero",
@@ -58,11 +77,19 @@ func TestHandler_HandleMessagesCreate(t *testing.T) {
 			requestBody:    `{"text":"Hello"}`,
 			setupMocks:     func(c *MockChatsService, m *MockMessagesService) {},
 			expectedStatus: http.StatusBadRequest,
-			expectedBody:   `{"error":"invalid chat_id"}`,
+			expectedBody:   `{"error":"invalid chat_id","code":"invalid_chat_id"}`,
+		},
+		{
+			name:           "invalid chat id - integer",
+			chatID:         "1",
+			requestBody:    `{"text":"Hello"}`,
+			setupMocks:     func(c *MockChatsService, m *MockMessagesService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid chat_id","code":"invalid_chat_id"}`,
 		},
 		{
 			name:        "invalid json body",
-			chatID:      "1",
+			chatID:      chatID,
 			requestBody: `{bad json`,
 			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
 
@@ -72,26 +99,179 @@ func TestHandler_HandleMessagesCreate(t *testing.T) {
 		},
 		{
 			name:        "empty message text",
-			chatID:      "1",
+			chatID:      chatID,
 			requestBody: `{"text":""}`,
 			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
 				m.On("ValidateMessageCreate", "").
-					Return(errors.New("message text is required"))
+					Return("", errors.New("message text is required"))
 			},
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   `message text is required`,
 		},
 		{
 			name:        "message text too long",
-			chatID:      "1",
+			chatID:      chatID,
 			requestBody: `{"text":"` + strings.Repeat("a", 5001) + `"}`,
 			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
 				m.On("ValidateMessageCreate", strings.Repeat("a", 5001)).
-					Return(errors.New("message text is too long"))
+					Return("", errors.New("message text is too long"))
 			},
 			expectedStatus: http.StatusBadRequest,
 			expectedBody:   `"message text is too long`,
 		},
+		{
+			name:        "client_msg_id too long",
+			chatID:      chatID,
+			requestBody: `{"text":"Hello","client_msg_id":"` + strings.Repeat("a", 101) + `"}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"client_msg_id is too long","code":"client_msg_id_too_long"}`,
+		},
+		{
+			name:        "successful create message",
+			chatID:      chatID,
+			requestBody: `{"text":"Hello","client_msg_id":"c-1"}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+				c.On("ResolveChatIdFor", chatID, mock.Anything).Return(int64(1), nil)
+				m.On("CreateMessage", services.NewMessage{ChatId: 1, SenderId: "u-1", ClientMsgId: "c-1", Text: "Hello"}).
+					Return(&model.Message{Id: 7, PublicId: "0192f3c4-6000-7000-8000-000000000007", ChatId: 1, ChatPublicId: chatID, Seq: 3, SenderId: "u-1", ClientMsgId: &clientMsgId, Text: "Hello"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"ClientMsgId":"c-1"`,
+		},
+		{
+			name:        "duplicate client_msg_id returns existing message",
+			chatID:      chatID,
+			requestBody: `{"text":"Hello","client_msg_id":"c-1"}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+				c.On("ResolveChatIdFor", chatID, mock.Anything).Return(int64(1), nil)
+				m.On("CreateMessage", services.NewMessage{ChatId: 1, SenderId: "u-1", ClientMsgId: "c-1", Text: "Hello"}).
+					Return(&model.Message{Id: 7, PublicId: "0192f3c4-6000-7000-8000-000000000007", ChatId: 1, ChatPublicId: chatID, Seq: 3, SenderId: "u-1", ClientMsgId: &clientMsgId, Text: "Hello"},
+						repository.ErrDuplicateMessage)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Id":"0192f3c4-6000-7000-8000-000000000007","ChatId":"` + chatID + `","Seq":3`,
+		},
+		{
+			name:           "invalid attachment id",
+			chatID:         chatID,
+			requestBody:    `{"text":"Hello","attachment_ids":["42"]}`,
+			setupMocks:     func(c *MockChatsService, m *MockMessagesService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid attachment_ids","code":"invalid_parameter"}`,
+		},
+		{
+			name:        "attachments without text",
+			chatID:      chatID,
+			requestBody: `{"attachment_ids":["0192f3c4-7000-7000-8000-000000000001"]}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				c.On("ResolveChatIdFor", chatID, mock.Anything).Return(int64(1), nil)
+				m.On("CreateMessage", services.NewMessage{ChatId: 1, SenderId: "u-1", AttachmentIds: []string{"0192f3c4-7000-7000-8000-000000000001"}}).
+					Return(&model.Message{Id: 8, PublicId: "0192f3c4-6000-7000-8000-000000000008", ChatPublicId: chatID, Seq: 4, SenderId: "u-1",
+						Attachments: []*model.Attachment{{PublicId: "0192f3c4-7000-7000-8000-000000000001", FileName: "a.png", Url: "/api/v1/attachments/0192f3c4-7000-7000-8000-000000000001?expires=1&sig=x"}}}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"Attachments":[{"Id":"0192f3c4-7000-7000-8000-000000000001"`,
+		},
+		{
+			name:        "attachment of another user",
+			chatID:      chatID,
+			requestBody: `{"attachment_ids":["0192f3c4-7000-7000-8000-000000000001"]}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				c.On("ResolveChatIdFor", chatID, mock.Anything).Return(int64(1), nil)
+				m.On("CreateMessage", services.NewMessage{ChatId: 1, SenderId: "u-1", AttachmentIds: []string{"0192f3c4-7000-7000-8000-000000000001"}}).
+					Return(nil, repository.ErrAttachmentNotFound)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"attachment_not_found"`,
+		},
+		{
+			name:        "invalid reply_to",
+			chatID:      chatID,
+			requestBody: `{"text":"Hello","reply_to":"42"}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid reply_to","code":"invalid_parameter"}`,
+		},
+		{
+			name:        "reply to a message of another chat",
+			chatID:      chatID,
+			requestBody: `{"text":"Hello","reply_to":"0192f3c4-6000-7000-8000-000000000001"}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+				c.On("ResolveChatIdFor", chatID, mock.Anything).Return(int64(1), nil)
+				m.On("CreateMessage", services.NewMessage{ChatId: 1, SenderId: "u-1", Text: "Hello", ReplyTo: "0192f3c4-6000-7000-8000-000000000001"}).
+					Return(nil, repository.ErrReplyNotFound)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"reply_not_found"`,
+		},
+		{
+			name:        "expires_in not positive",
+			chatID:      chatID,
+			requestBody: `{"text":"Hello","expires_in":0}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid expires_in","code":"invalid_parameter"}`,
+		},
+		{
+			name:        "expires_in too large",
+			chatID:      chatID,
+			requestBody: `{"text":"Hello","expires_in":9223372036854775807}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid expires_in","code":"invalid_parameter"}`,
+		},
+		{
+			name:        "disappearing message",
+			chatID:      chatID,
+			requestBody: `{"text":"Hello","expires_in":3600}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+				c.On("ResolveChatIdFor", chatID, mock.Anything).Return(int64(1), nil)
+				expiresAt := time.Date(2026, 10, 19, 13, 0, 0, 0, time.UTC)
+				m.On("CreateMessage", services.NewMessage{ChatId: 1, SenderId: "u-1", Text: "Hello", ExpiresIn: time.Hour}).
+					Return(&model.Message{PublicId: "0192f3c4-6000-7000-8000-000000000009", ChatPublicId: chatID, Seq: 5, SenderId: "u-1", Text: "Hello", ExpiresAt: &expiresAt}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"ExpiresAt":"2026-10-19T13:00:00Z"`,
+		},
+		{
+			name:        "non-admin posting to a broadcast chat",
+			chatID:      chatID,
+			requestBody: `{"text":"Hello"}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+				c.On("ResolveChatIdFor", chatID, mock.Anything).Return(int64(1), nil)
+				m.On("CreateMessage", services.NewMessage{ChatId: 1, SenderId: "u-1", Text: "Hello"}).
+					Return(nil, repository.ErrChatReadOnly)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"error":"only admins can post to this chat","code":"chat_read_only"}`,
+		},
+		{
+			name:        "field-level validation error",
+			chatID:      chatID,
+			requestBody: `{"text":" "}`,
+			setupMocks: func(c *MockChatsService, m *MockMessagesService) {
+				m.On("ValidateMessageCreate", " ").
+					Return("", &services.ValidationError{Fields: []services.FieldError{
+						{Field: "text", Code: services.CodeRequired, Message: "message is required"},
+					}})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"fields":[{"field":"text","code":"required","message":"message is required"}]`,
+		},
 	}
 
 	for _, test := range tests {
@@ -107,6 +287,7 @@ func TestHandler_HandleMessagesCreate(t *testing.T) {
 			req := httptest.NewRequest(http.MethodPost, url,
 				strings.NewReader(test.requestBody))
 			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-Id", "u-1")
 
 			w := httptest.NewRecorder()
 
@@ -129,3 +310,49 @@ func TestHandler_HandleMessagesCreate(t *testing.T) {
 		})
 	}
 }
+
+func TestHandler_HandleMessagesCreate_AnonymousInBroadcast(t *testing.T) {
+	const chatID = "0192f3c4-5b6a-7d8e-9f01-23456789abcd"
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", chatID, mock.Anything).Return(int64(1), nil)
+	mockMessages := new(MockMessagesService)
+	mockMessages.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+	mockMessages.On("CreateMessage", services.NewMessage{ChatId: 1, Text: "Hello"}).
+		Return(nil, repository.ErrChatReadOnly)
+
+	h := handler.NewHandler(mockChats, mockMessages, slog.Default())
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+apiPrefix+"/{id}/messages", h.HandleMessagesCreate())
+
+	req := httptest.NewRequest(http.MethodPost, apiPrefix+"/"+chatID+"/messages", strings.NewReader(`{"text":"Hello"}`))
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"code":"chat_read_only"`)
+	mockMessages.AssertExpectations(t)
+}
+
+func TestHandler_HandleMessagesCreate_ReservedUserId(t *testing.T) {
+	const chatID = "0192f3c4-5b6a-7d8e-9f01-23456789abcd"
+
+	for _, userId := range []string{"system", "bot:" + chatID, "hook:" + chatID} {
+		t.Run(userId, func(t *testing.T) {
+			mockMessages := new(MockMessagesService)
+
+			h := handler.NewHandler(new(MockChatsService), mockMessages, slog.Default())
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST "+apiPrefix+"/{id}/messages", h.HandleMessagesCreate())
+
+			req := httptest.NewRequest(http.MethodPost, apiPrefix+"/"+chatID+"/messages", strings.NewReader(`{"text":"Hello"}`))
+			req.Header.Set("X-User-Id", userId)
+			w := httptest.NewRecorder()
+			h.Authenticate(mux).ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), `"code":"reserved_user_id"`)
+			mockMessages.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler_HandleMetrics(t *testing.T) {
+	expvar.NewInt("handler_test_counter").Set(3)
+	h := handler.NewHandler(new(MockChatsService), new(MockMessagesService), slog.Default(),
+		handler.WithAdminToken("secret"))
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+	w := httptest.NewRecorder()
+	h.HandleMetrics()(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	h.HandleMetrics()(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var vars map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &vars))
+	require.JSONEq(t, "3", string(vars["handler_test_counter"]))
+	require.NotContains(t, vars, "cmdline")
+	require.NotContains(t, vars, "memstats")
+}
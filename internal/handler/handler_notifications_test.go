@@ -0,0 +1,115 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockNotificationsService struct {
+	mock.Mock
+}
+
+func (m *MockNotificationsService) ListNotifications(ctx context.Context, userId, beforePublicId string, limit int) ([]*model.Notification, error) {
+	args := m.Called(userId, beforePublicId, limit)
+	notifications, _ := args.Get(0).([]*model.Notification)
+	return notifications, args.Error(1)
+}
+
+func (m *MockNotificationsService) MarkNotificationsRead(ctx context.Context, userId string, publicIds []string) (int64, error) {
+	args := m.Called(userId, publicIds)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockNotificationsService) NotifyPendingAll(ctx context.Context) (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func TestHandler_HandleNotificationsList(t *testing.T) {
+	before := model.NewPublicId()
+	notification := &model.Notification{PublicId: "n-1", ChatPublicId: "c-1", Kind: model.NotificationMention,
+		Message: &model.Message{PublicId: "m-1", ChatPublicId: "c-1", Text: "@alice look",
+			Mentions: []model.Mention{{Type: model.MentionUser, UserId: "alice", Offset: 0, Length: 6}}}}
+
+	mockNotifications := new(MockNotificationsService)
+	mockNotifications.On("ListNotifications", "alice", before, 20).Return([]*model.Notification{notification}, nil)
+	h := handler.NewHandler(new(MockChatsService), new(MockMessagesService), slog.Default(), handler.WithNotifications(mockNotifications))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/notifications?limit=20&before="+before, nil)
+	req.Header.Set("X-User-Id", "alice")
+	w := httptest.NewRecorder()
+	h.HandleNotificationsList()(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `{"notifications":[{"Id":"n-1","ChatId":"c-1","Message":{"Id":"m-1"`)
+	require.Contains(t, w.Body.String(), `"Mentions":[{"Type":"user","UserId":"alice","Offset":0,"Length":6}]},"Kind":"mention"`)
+	mockNotifications.AssertExpectations(t)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/me/notifications", nil)
+	w = httptest.NewRecorder()
+	h.HandleNotificationsList()(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestHandler_HandleNotificationsRead(t *testing.T) {
+	id := model.NewPublicId()
+
+	tests := []struct {
+		name           string
+		body           string
+		setupMocks     func(*MockNotificationsService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name: "some",
+			body: `{"ids":["` + id + `"]}`,
+			setupMocks: func(m *MockNotificationsService) {
+				m.On("MarkNotificationsRead", "alice", []string{id}).Return(int64(1), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"marked":1}`,
+		},
+		{
+			name: "all without a body",
+			setupMocks: func(m *MockNotificationsService) {
+				m.On("MarkNotificationsRead", "alice", []string(nil)).Return(int64(7), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"marked":7}`,
+		},
+		{
+			name:           "invalid id",
+			body:           `{"ids":["n-1"]}`,
+			setupMocks:     func(m *MockNotificationsService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"invalid_parameter"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockNotifications := new(MockNotificationsService)
+			test.setupMocks(mockNotifications)
+			h := handler.NewHandler(new(MockChatsService), new(MockMessagesService), slog.Default(), handler.WithNotifications(mockNotifications))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/me/notifications/read", strings.NewReader(test.body))
+			req.Header.Set("X-User-Id", "alice")
+			w := httptest.NewRecorder()
+			h.HandleNotificationsRead()(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockNotifications.AssertExpectations(t)
+		})
+	}
+}
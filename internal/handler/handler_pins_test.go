@@ -0,0 +1,172 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPinsService struct {
+	mock.Mock
+}
+
+func (m *MockPinsService) Pin(ctx context.Context, chatId int64, messagePublicId, userId string) (*model.Message, error) {
+	args := m.Called(chatId, messagePublicId, userId)
+	message, _ := args.Get(0).(*model.Message)
+	return message, args.Error(1)
+}
+
+func (m *MockPinsService) Unpin(ctx context.Context, chatId int64, messagePublicId string) (*model.Message, error) {
+	args := m.Called(chatId, messagePublicId)
+	message, _ := args.Get(0).(*model.Message)
+	return message, args.Error(1)
+}
+
+func (m *MockPinsService) ListPins(ctx context.Context, chatId int64) ([]*model.Message, error) {
+	args := m.Called(chatId)
+	messages, _ := args.Get(0).([]*model.Message)
+	return messages, args.Error(1)
+}
+
+func TestHandler_HandlePinsPin(t *testing.T) {
+	chatId := model.NewPublicId()
+	messageId := model.NewPublicId()
+	pinnedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	alice := "alice"
+
+	tests := []struct {
+		name           string
+		messageId      string
+		setupMocks     func(*MockMembersService, *MockPinsService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:      "not an admin",
+			messageId: messageId,
+			setupMocks: func(mb *MockMembersService, p *MockPinsService) {
+				mb.On("IsAdmin", int64(7), "alice").Return(false, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `"code":"forbidden"`,
+		},
+		{
+			name:      "invalid message id",
+			messageId: "nope",
+			setupMocks: func(mb *MockMembersService, p *MockPinsService) {
+				mb.On("IsAdmin", int64(7), "alice").Return(true, nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"invalid_parameter"`,
+		},
+		{
+			name:      "message not found",
+			messageId: messageId,
+			setupMocks: func(mb *MockMembersService, p *MockPinsService) {
+				mb.On("IsAdmin", int64(7), "alice").Return(true, nil)
+				p.On("Pin", int64(7), messageId, "alice").Return(nil, repository.ErrMessageNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"message_not_found"`,
+		},
+		{
+			name:      "too many pins",
+			messageId: messageId,
+			setupMocks: func(mb *MockMembersService, p *MockPinsService) {
+				mb.On("IsAdmin", int64(7), "alice").Return(true, nil)
+				p.On("Pin", int64(7), messageId, "alice").Return(nil, &services.TooManyPinsError{Limit: 3})
+			},
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `chat already has 3 pinned messages`,
+		},
+		{
+			name:      "pinned",
+			messageId: messageId,
+			setupMocks: func(mb *MockMembersService, p *MockPinsService) {
+				mb.On("IsAdmin", int64(7), "alice").Return(true, nil)
+				p.On("Pin", int64(7), messageId, "alice").
+					Return(&model.Message{PublicId: messageId, ChatPublicId: chatId, Text: "hi", PinnedAt: &pinnedAt, PinnedBy: &alice}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"PinnedAt":"2026-10-01T12:00:00Z","PinnedBy":"alice"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(7), nil)
+			mockMembers := new(MockMembersService)
+			mockPins := new(MockPinsService)
+			test.setupMocks(mockMembers, mockPins)
+
+			h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(),
+				handler.WithMembers(mockMembers), handler.WithPins(mockPins))
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/chats/"+chatId+"/pins/"+test.messageId, nil)
+			req.SetPathValue("id", chatId)
+			req.SetPathValue("messageId", test.messageId)
+			req.Header.Set("X-User-Id", "alice")
+			w := httptest.NewRecorder()
+			h.HandlePinsPin()(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockMembers.AssertExpectations(t)
+			mockPins.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandlePinsUnpin(t *testing.T) {
+	chatId := model.NewPublicId()
+	messageId := model.NewPublicId()
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatId", chatId).Return(int64(7), nil)
+	mockPins := new(MockPinsService)
+	mockPins.On("Unpin", int64(7), messageId).Return(&model.Message{PublicId: messageId}, nil)
+
+	h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(),
+		handler.WithAdminToken("secret"), handler.WithPins(mockPins))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/chats/"+chatId+"/pins/"+messageId, nil)
+	req.SetPathValue("id", chatId)
+	req.SetPathValue("messageId", messageId)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.HandlePinsUnpin()(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	mockPins.AssertExpectations(t)
+}
+
+func TestHandler_HandlePinsList(t *testing.T) {
+	chatId := model.NewPublicId()
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(7), nil)
+	mockPins := new(MockPinsService)
+	mockPins.On("ListPins", int64(7)).Return([]*model.Message{{PublicId: "m-1"}, {PublicId: "m-2"}}, nil)
+
+	h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(), handler.WithPins(mockPins))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chats/"+chatId+"/pins", nil)
+	req.SetPathValue("id", chatId)
+	w := httptest.NewRecorder()
+	h.HandlePinsList()(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Regexp(t, `"pins":\[\{"Id":"m-1".*\},\{"Id":"m-2"`, w.Body.String())
+	mockPins.AssertExpectations(t)
+}
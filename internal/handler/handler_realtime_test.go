@@ -0,0 +1,239 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/realtime"
+	"chats-api/internal/repository"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockReadsService struct {
+	mock.Mock
+}
+
+func (m *MockReadsService) MarkRead(ctx context.Context, chatId int64, userId string, seq int64) (*model.ReadReceipt, int64, error) {
+	args := m.Called(chatId, userId, seq)
+	receipt, _ := args.Get(0).(*model.ReadReceipt)
+	return receipt, args.Get(1).(int64), args.Error(2)
+}
+
+func TestHandler_HandleChatsRead(t *testing.T) {
+	chatId := model.NewPublicId()
+
+	tests := []struct {
+		name           string
+		userId         string
+		body           string
+		setupMocks     func(*MockReadsService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "no user",
+			body:           `{"seq":3}`,
+			setupMocks:     func(m *MockReadsService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"code":"user_required"`,
+		},
+		{
+			name:           "negative seq",
+			userId:         "alice",
+			body:           `{"seq":-1}`,
+			setupMocks:     func(m *MockReadsService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"invalid_parameter"`,
+		},
+		{
+			name:           "zero seq",
+			userId:         "alice",
+			body:           `{"seq":0}`,
+			setupMocks:     func(m *MockReadsService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"invalid_parameter"`,
+		},
+		{
+			name:   "up to a message",
+			userId: "alice",
+			body:   `{"seq":3}`,
+			setupMocks: func(m *MockReadsService) {
+				m.On("MarkRead", int64(7), "alice", int64(3)).
+					Return(&model.ReadReceipt{ChatPublicId: chatId, UserId: "alice", LastReadSeq: 3}, int64(2), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"LastReadSeq":3,"UpdatedAt":"0001-01-01T00:00:00Z","UnreadCount":2`,
+		},
+		{
+			name:   "everything without a body",
+			userId: "alice",
+			setupMocks: func(m *MockReadsService) {
+				m.On("MarkRead", int64(7), "alice", int64(0)).
+					Return(&model.ReadReceipt{ChatPublicId: chatId, UserId: "alice", LastReadSeq: 5}, int64(0), nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"UnreadCount":0`,
+		},
+		{
+			name:   "chat deleted meanwhile",
+			userId: "alice",
+			setupMocks: func(m *MockReadsService) {
+				m.On("MarkRead", int64(7), "alice", int64(0)).Return(nil, int64(0), repository.ErrChatNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"chat_not_found"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(7), nil).Maybe()
+			mockReads := new(MockReadsService)
+			test.setupMocks(mockReads)
+
+			h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(), handler.WithReads(mockReads))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/chats/"+chatId+"/read", strings.NewReader(test.body))
+			req.SetPathValue("id", chatId)
+			req.Header.Set("X-User-Id", test.userId)
+			w := httptest.NewRecorder()
+			h.HandleChatsRead()(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockReads.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleRealtime(t *testing.T) {
+	followed, other := model.NewPublicId(), model.NewPublicId()
+	missing := model.NewPublicId()
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", followed, mock.Anything).Return(int64(1), nil)
+	mockChats.On("ResolveChatIdFor", other, mock.Anything).Return(int64(2), nil)
+	mockChats.On("ResolveChatIdFor", missing, mock.Anything).Return(int64(0), repository.ErrChatNotFound)
+
+	hub := realtime.NewHub(8)
+	h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(),
+		handler.WithRealtime(hub, realtime.NewPresence(hub, time.Minute, time.Minute)))
+	server := httptest.NewServer(h.HandleRealtime())
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, resp, err := websocket.Dial(ctx, server.URL, nil)
+	require.Error(t, err)
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, resp, err = websocket.Dial(ctx, server.URL+"?chat_id="+missing, &websocket.DialOptions{HTTPHeader: http.Header{"X-User-Id": {"alice"}}})
+	require.Error(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	conn, _, err := websocket.Dial(ctx, server.URL+"?chat_id="+followed, &websocket.DialOptions{HTTPHeader: http.Header{"X-User-Id": {"alice"}}})
+	require.NoError(t, err)
+	defer conn.CloseNow()
+
+	send := func(frame string) map[string]any {
+		require.NoError(t, conn.Write(ctx, websocket.MessageText, []byte(frame)))
+		return readFrame(t, ctx, conn)
+	}
+
+	require.Equal(t, map[string]any{"type": "subscribed", "chat_id": other}, send(`{"type":"subscribe","chat_id":"`+other+`"}`))
+	reply := send(`{"type":"subscribe","chat_id":"` + missing + `"}`)
+	require.Equal(t, "error", reply["type"])
+	require.Equal(t, "chat_not_found", reply["code"])
+	require.Equal(t, "invalid_parameter", send(`{"type":"shout"}`)["code"])
+
+	hub.Publish(followed, []byte(`{"type":"message.created","chat_id":"`+followed+`"}`), nil)
+	require.Equal(t, "message.created", readFrame(t, ctx, conn)["type"])
+	hub.Publish(other, []byte(`{"type":"chat.read","chat_id":"`+other+`"}`), nil)
+	require.Equal(t, "chat.read", readFrame(t, ctx, conn)["type"])
+}
+
+func TestHandler_HandleRealtime_TypingAndPresence(t *testing.T) {
+	chatId := model.NewPublicId()
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(1), nil)
+
+	hub := realtime.NewHub(8)
+	presence := realtime.NewPresence(hub, time.Minute, time.Minute)
+	h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(), handler.WithRealtime(hub, presence))
+	server := httptest.NewServer(h.HandleRealtime())
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dial := func(userId, query string) *websocket.Conn {
+		conn, _, err := websocket.Dial(ctx, server.URL+query, &websocket.DialOptions{HTTPHeader: http.Header{"X-User-Id": {userId}}})
+		require.NoError(t, err)
+		return conn
+	}
+	alice := dial("alice", "?chat_id="+chatId)
+	defer alice.CloseNow()
+	bob := dial("bob", "")
+	defer bob.CloseNow()
+
+	require.NoError(t, bob.Write(ctx, websocket.MessageText, []byte(`{"type":"typing","chat_id":"`+chatId+`"}`)))
+	require.Equal(t, "not_subscribed", readFrame(t, ctx, bob)["code"])
+
+	require.NoError(t, bob.Write(ctx, websocket.MessageText, []byte(`{"type":"subscribe","chat_id":"`+chatId+`"}`)))
+	require.Equal(t, "subscribed", readFrame(t, ctx, bob)["type"])
+	require.NoError(t, bob.Write(ctx, websocket.MessageText, []byte(`{"type":"presence","chat_id":"`+chatId+`"}`)))
+	require.NoError(t, bob.Write(ctx, websocket.MessageText, []byte(`{"type":"typing","chat_id":"`+chatId+`"}`)))
+
+	require.Equal(t, map[string]any{"type": "presence", "chat_id": chatId, "user_id": "bob", "status": "online"}, readFrame(t, ctx, alice))
+	require.Equal(t, map[string]any{"type": "typing", "chat_id": chatId, "user_id": "bob", "status": "typing"}, readFrame(t, ctx, alice))
+	require.Equal(t, []string{"bob"}, presence.Online(chatId, time.Now()))
+
+	bob.Close(websocket.StatusNormalClosure, "")
+	require.Equal(t, "stopped", readFrame(t, ctx, alice)["status"])
+	require.Equal(t, "offline", readFrame(t, ctx, alice)["status"])
+}
+
+func TestHandler_HandleChatsOnline(t *testing.T) {
+	chatId := model.NewPublicId()
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(1), nil)
+
+	hub := realtime.NewHub(8)
+	presence := realtime.NewPresence(hub, time.Minute, time.Minute)
+	presence.SetOnline(chatId, "bob", true, time.Now())
+	presence.SetOnline(chatId, "alice", true, time.Now())
+	presence.SetOnline(chatId, "carol", true, time.Now().Add(-time.Hour))
+	h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(), handler.WithRealtime(hub, presence))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/chats/"+chatId+"/online", nil)
+	req.SetPathValue("id", chatId)
+	w := httptest.NewRecorder()
+	h.HandleChatsOnline()(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"chat_id":"`+chatId+`","users":["alice","bob"]}`, w.Body.String())
+}
+
+func readFrame(t *testing.T, ctx context.Context, conn *websocket.Conn) map[string]any {
+	t.Helper()
+
+	_, data, err := conn.Read(ctx)
+	require.NoError(t, err)
+	var frame map[string]any
+	require.NoError(t, json.Unmarshal(data, &frame))
+	return frame
+}
@@ -0,0 +1,193 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockScheduledService struct {
+	mock.Mock
+}
+
+func (m *MockScheduledService) Schedule(ctx context.Context, input services.NewMessage, sendAt time.Time) (*model.ScheduledMessage, error) {
+	args := m.Called(input, sendAt)
+	message, _ := args.Get(0).(*model.ScheduledMessage)
+	return message, args.Error(1)
+}
+
+func (m *MockScheduledService) ListScheduled(ctx context.Context, chatId int64, senderId string) ([]*model.ScheduledMessage, error) {
+	args := m.Called(chatId, senderId)
+	messages, _ := args.Get(0).([]*model.ScheduledMessage)
+	return messages, args.Error(1)
+}
+
+func (m *MockScheduledService) UpdateScheduled(ctx context.Context, chatId int64, senderId, publicId string, update repository.ScheduledUpdate) (*model.ScheduledMessage, error) {
+	args := m.Called(chatId, senderId, publicId, update)
+	message, _ := args.Get(0).(*model.ScheduledMessage)
+	return message, args.Error(1)
+}
+
+func (m *MockScheduledService) CancelScheduled(ctx context.Context, chatId int64, senderId, publicId string) error {
+	return m.Called(chatId, senderId, publicId).Error(0)
+}
+
+func (m *MockScheduledService) DispatchScheduled(ctx context.Context) (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func TestHandler_HandleMessagesCreate_Scheduled(t *testing.T) {
+	chatId := model.NewPublicId()
+	sendAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	past := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	scheduledId := model.NewPublicId()
+
+	tests := []struct {
+		name           string
+		userId         string
+		requestBody    string
+		setupMocks     func(*MockMessagesService, *MockScheduledService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:        "without a user",
+			requestBody: `{"text":"Hello","send_at":"` + sendAt.Format(time.RFC3339) + `"}`,
+			setupMocks: func(m *MockMessagesService, s *MockScheduledService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"code":"user_required"`,
+		},
+		{
+			name:        "send_at in the past",
+			userId:      "u-1",
+			requestBody: `{"text":"Hello","send_at":"` + past.Format(time.RFC3339) + `"}`,
+			setupMocks: func(m *MockMessagesService, s *MockScheduledService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid send_at","code":"invalid_parameter"}`,
+		},
+		{
+			name:        "with attachments",
+			userId:      "u-1",
+			requestBody: `{"text":"Hello","attachment_ids":["0192f3c4-7000-7000-8000-000000000001"],"send_at":"` + sendAt.Format(time.RFC3339) + `"}`,
+			setupMocks: func(m *MockMessagesService, s *MockScheduledService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid attachment_ids","code":"invalid_parameter"}`,
+		},
+		{
+			name:        "scheduled",
+			userId:      "u-1",
+			requestBody: `{"text":"Hello","send_at":"` + sendAt.Format(time.RFC3339) + `"}`,
+			setupMocks: func(m *MockMessagesService, s *MockScheduledService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+				s.On("Schedule", services.NewMessage{ChatId: 1, SenderId: "u-1", Text: "Hello"}, mock.MatchedBy(sendAt.Equal)).
+					Return(&model.ScheduledMessage{PublicId: scheduledId, ChatPublicId: chatId, SenderId: "u-1", Text: "Hello", SendAt: sendAt}, nil)
+			},
+			expectedStatus: http.StatusAccepted,
+			expectedBody:   `"Id":"` + scheduledId + `","ChatId":"` + chatId + `"`,
+		},
+		{
+			name:        "already scheduled",
+			userId:      "u-1",
+			requestBody: `{"text":"Hello","client_msg_id":"c-1","send_at":"` + sendAt.Format(time.RFC3339) + `"}`,
+			setupMocks: func(m *MockMessagesService, s *MockScheduledService) {
+				m.On("ValidateMessageCreate", "Hello").Return("Hello", nil)
+				s.On("Schedule", services.NewMessage{ChatId: 1, SenderId: "u-1", ClientMsgId: "c-1", Text: "Hello"}, mock.MatchedBy(sendAt.Equal)).
+					Return(&model.ScheduledMessage{PublicId: scheduledId, ChatPublicId: chatId, Text: "Hello", SendAt: sendAt}, repository.ErrDuplicateMessage)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"Id":"` + scheduledId + `"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockChats := new(MockChatsService)
+			mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(1), nil)
+			mockMessages := new(MockMessagesService)
+			mockScheduled := new(MockScheduledService)
+			test.setupMocks(mockMessages, mockScheduled)
+
+			h := handler.NewHandler(mockChats, mockMessages, slog.Default(), handler.WithScheduled(mockScheduled))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/chats/"+chatId+"/messages", strings.NewReader(test.requestBody))
+			req.SetPathValue("id", chatId)
+			if test.userId != "" {
+				req.Header.Set("X-User-Id", test.userId)
+			}
+			w := httptest.NewRecorder()
+			h.HandleMessagesCreate()(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockMessages.AssertNotCalled(t, "CreateMessage", mock.Anything)
+			mockScheduled.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleScheduledUpdate(t *testing.T) {
+	chatId := model.NewPublicId()
+	scheduledId := model.NewPublicId()
+	text := "Updated"
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(1), nil)
+	mockMessages := new(MockMessagesService)
+	mockMessages.On("ValidateMessageCreate", "Updated").Return("Updated", nil)
+	mockScheduled := new(MockScheduledService)
+	mockScheduled.On("UpdateScheduled", int64(1), "u-1", scheduledId, repository.ScheduledUpdate{Text: &text}).
+		Return(nil, repository.ErrScheduledNotFound)
+
+	h := handler.NewHandler(mockChats, mockMessages, slog.Default(), handler.WithScheduled(mockScheduled))
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/chats/"+chatId+"/scheduled/"+scheduledId, strings.NewReader(`{"text":"Updated"}`))
+	req.SetPathValue("id", chatId)
+	req.SetPathValue("scheduledId", scheduledId)
+	req.Header.Set("X-User-Id", "u-1")
+	w := httptest.NewRecorder()
+	h.HandleScheduledUpdate()(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+	require.Contains(t, w.Body.String(), `"code":"scheduled_message_not_found"`)
+	mockScheduled.AssertExpectations(t)
+}
+
+func TestHandler_HandleScheduledCancel(t *testing.T) {
+	chatId := model.NewPublicId()
+	scheduledId := model.NewPublicId()
+
+	mockChats := new(MockChatsService)
+	mockChats.On("ResolveChatIdFor", chatId, mock.Anything).Return(int64(1), nil)
+	mockScheduled := new(MockScheduledService)
+	mockScheduled.On("CancelScheduled", int64(1), "u-1", scheduledId).Return(nil)
+
+	h := handler.NewHandler(mockChats, new(MockMessagesService), slog.Default(), handler.WithScheduled(mockScheduled))
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/chats/"+chatId+"/scheduled/"+scheduledId, nil)
+	req.SetPathValue("id", chatId)
+	req.SetPathValue("scheduledId", scheduledId)
+	req.Header.Set("X-User-Id", "u-1")
+	w := httptest.NewRecorder()
+	h.HandleScheduledCancel()(w, req)
+
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	mockScheduled.AssertExpectations(t)
+}
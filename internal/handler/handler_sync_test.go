@@ -0,0 +1,106 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/services"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockSyncService struct {
+	mock.Mock
+}
+
+func (m *MockSyncService) Sync(ctx context.Context, userId, token string, limit int) (*services.SyncResult, error) {
+	args := m.Called(userId, token, limit)
+	result, _ := args.Get(0).(*services.SyncResult)
+	return result, args.Error(1)
+}
+
+func (m *MockSyncService) PruneChanges(ctx context.Context) (int64, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (m *MockSyncService) SequenceChanges(ctx context.Context) (int64, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func TestHandler_HandleSync(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		setupMock      func(*MockSyncService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "invalid limit",
+			query:          "?limit=abc",
+			setupMock:      func(m *MockSyncService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid limit","code":"invalid_parameter"}`,
+		},
+		{
+			name:  "invalid token",
+			query: "?since=!!!",
+			setupMock: func(m *MockSyncService) {
+				m.On("Sync", "u-1", "!!!", 500).Return(nil, services.ErrInvalidSyncToken)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"error":"invalid sync token","code":"invalid_sync_token"}`,
+		},
+		{
+			name:  "token too old",
+			query: "?since=AQ",
+			setupMock: func(m *MockSyncService) {
+				m.On("Sync", "u-1", "AQ", 500).
+					Return(&services.SyncResult{Next: "ZA", ResyncRequired: true}, nil)
+			},
+			expectedStatus: http.StatusGone,
+			expectedBody:   `"resync_required":true`,
+		},
+		{
+			name:  "successful sync",
+			query: "?since=AQ&limit=5000",
+			setupMock: func(m *MockSyncService) {
+				m.On("Sync", "u-1", "AQ", 1000).
+					Return(&services.SyncResult{
+						Messages: services.SyncMessages{
+							Deleted: []services.DeletedMessage{{Id: "m-3", ChatId: "c-1"}},
+						},
+						Next: "Ag",
+					}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `"deleted":[{"Id":"m-3","ChatId":"c-1"}]},"next":"Ag","has_more":false}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockSync := new(MockSyncService)
+			test.setupMock(mockSync)
+
+			h := handler.NewHandler(new(MockChatsService), new(MockMessagesService), slog.Default(),
+				handler.WithSync(mockSync))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/sync"+test.query, nil)
+			req.Header.Set("X-User-Id", "u-1")
+			w := httptest.NewRecorder()
+
+			h.HandleSync()(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code)
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockSync.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package handler_test
+
+import (
+	"chats-api/internal/handler"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockWebhooksService struct {
+	mock.Mock
+}
+
+func (m *MockWebhooksService) Create(ctx context.Context, input services.NewWebhook) (*model.Webhook, error) {
+	args := m.Called(input)
+	webhook, _ := args.Get(0).(*model.Webhook)
+	return webhook, args.Error(1)
+}
+
+func (m *MockWebhooksService) List(ctx context.Context) ([]*model.Webhook, error) {
+	args := m.Called()
+	webhooks, _ := args.Get(0).([]*model.Webhook)
+	return webhooks, args.Error(1)
+}
+
+func (m *MockWebhooksService) Delete(ctx context.Context, publicId string) error {
+	return m.Called(publicId).Error(0)
+}
+
+func (m *MockWebhooksService) Enable(ctx context.Context, publicId string) (*model.Webhook, error) {
+	args := m.Called(publicId)
+	webhook, _ := args.Get(0).(*model.Webhook)
+	return webhook, args.Error(1)
+}
+
+func (m *MockWebhooksService) ListDeliveries(ctx context.Context, publicId string, limit int) ([]*model.WebhookDelivery, error) {
+	args := m.Called(publicId, limit)
+	deliveries, _ := args.Get(0).([]*model.WebhookDelivery)
+	return deliveries, args.Error(1)
+}
+
+func (m *MockWebhooksService) Dispatch(ctx context.Context) (services.WebhookDispatch, error) {
+	args := m.Called()
+	return args.Get(0).(services.WebhookDispatch), args.Error(1)
+}
+
+func (m *MockWebhooksService) PruneDeliveries(ctx context.Context) (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestHandler_HandleWebhooksCreate(t *testing.T) {
+	chatId := model.NewPublicId()
+
+	tests := []struct {
+		name           string
+		token          string
+		body           string
+		setupMock      func(*MockWebhooksService)
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "missing token",
+			body:           `{"url":"https://example.com/hook"}`,
+			setupMock:      func(m *MockWebhooksService) {},
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `"code":"unauthorized"`,
+		},
+		{
+			name:           "invalid chat id",
+			token:          "secret",
+			body:           `{"url":"https://example.com/hook","chat_id":"42"}`,
+			setupMock:      func(m *MockWebhooksService) {},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"code":"invalid_chat_id"`,
+		},
+		{
+			name:  "invalid url",
+			token: "secret",
+			body:  `{"url":"ftp://example.com"}`,
+			setupMock: func(m *MockWebhooksService) {
+				m.On("Create", services.NewWebhook{Url: "ftp://example.com"}).
+					Return(nil, &services.ValidationError{Fields: []services.FieldError{{Field: "url", Code: services.CodeInvalid}}})
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `"fields":[{"field":"url","code":"invalid","message":"webhook URL is invalid"}]`,
+		},
+		{
+			name:  "chat not found",
+			token: "secret",
+			body:  `{"url":"https://example.com/hook","chat_id":"` + chatId + `"}`,
+			setupMock: func(m *MockWebhooksService) {
+				m.On("Create", services.NewWebhook{ChatPublicId: chatId, Url: "https://example.com/hook"}).
+					Return(nil, repository.ErrChatNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+			expectedBody:   `"code":"chat_not_found"`,
+		},
+		{
+			name:  "created with secret",
+			token: "secret",
+			body:  `{"url":"https://example.com/hook","events":["message.created"]}`,
+			setupMock: func(m *MockWebhooksService) {
+				m.On("Create", services.NewWebhook{Url: "https://example.com/hook", Events: []string{"message.created"}}).
+					Return(&model.Webhook{PublicId: "w-1", Url: "https://example.com/hook", Secret: "whsec_1", Events: []string{"message.created"}}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+			expectedBody:   `"Secret":"whsec_1"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockWebhooks := new(MockWebhooksService)
+			test.setupMock(mockWebhooks)
+
+			h := handler.NewHandler(new(MockChatsService), new(MockMessagesService), slog.Default(),
+				handler.WithWebhooks(mockWebhooks), handler.WithAdminToken("secret"))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks", strings.NewReader(test.body))
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			w := httptest.NewRecorder()
+			h.HandleWebhooksCreate()(w, req)
+
+			require.Equal(t, test.expectedStatus, w.Code, w.Body.String())
+			require.Contains(t, w.Body.String(), test.expectedBody)
+			mockWebhooks.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_HandleWebhooksEnable(t *testing.T) {
+	id := model.NewPublicId()
+
+	mockWebhooks := new(MockWebhooksService)
+	mockWebhooks.On("Enable", id).Return(nil, repository.ErrWebhookNotFound)
+
+	h := handler.NewHandler(new(MockChatsService), new(MockMessagesService), slog.Default(),
+		handler.WithWebhooks(mockWebhooks), handler.WithAdminToken("secret"))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhooks/"+id+"/enable", nil)
+	req.SetPathValue("id", id)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.HandleWebhooksEnable()(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Contains(t, w.Body.String(), `"code":"webhook_not_found"`)
+	mockWebhooks.AssertExpectations(t)
+}
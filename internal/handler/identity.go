@@ -0,0 +1,85 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The API sits behind a gateway that authenticates users and passes the
+// caller's id in this header.
+const userIdHeader = "X-User-Id"
+
+const botAuthScheme = "Bot "
+
+type botContextKey struct{}
+
+// callerId is the bot authenticated by Authenticate, or else the user the
+// gateway passed.
+func callerId(r *http.Request) string {
+	if bot := callerBot(r); bot != nil {
+		return bot.SenderId()
+	}
+	return strings.TrimSpace(r.Header.Get(userIdHeader))
+}
+
+func callerBot(r *http.Request) *model.Bot {
+	bot, _ := r.Context().Value(botContextKey{}).(*model.Bot)
+	return bot
+}
+
+// Authenticate lets bots call the API with "Authorization: Bot <key>"; such
+// requests act as the bot. A wrong key is refused outright, and so is a user
+// id that would pass for the service, a bot or a hook.
+func (h *Handler) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := strings.CutPrefix(r.Header.Get("Authorization"), botAuthScheme)
+		if !ok || h.bots == nil {
+			if model.IsReservedSenderId(strings.TrimSpace(r.Header.Get(userIdHeader))) {
+				writeError(w, r, http.StatusBadRequest, i18n.ReservedUserId)
+				h.logger.Error("request with a reserved user id")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		bot, err := h.bots.Authorize(r.Context(), strings.TrimSpace(key))
+		if errors.Is(err, repository.ErrBotNotFound) {
+			writeError(w, r, http.StatusUnauthorized, i18n.InvalidBotKey)
+			h.logger.Error("bot request with an invalid key")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to authorize bot: %v", err))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), botContextKey{}, bot)))
+	})
+}
+
+// requireAdmin lets a request through only with the configured admin bearer
+// token. Without a configured token admin endpoints always refuse.
+func (h *Handler) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.hasAdminToken(r) {
+			writeError(w, r, http.StatusUnauthorized, i18n.Unauthorized)
+			h.logger.Error("admin request without a valid token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (h *Handler) hasAdminToken(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/importer"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const maxImportSize = 512 << 20
+
+func (h *Handler) HandleAdminImport() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling import")
+
+		query := r.URL.Query()
+		format := query.Get("format")
+		if format == "" {
+			format = importer.FormatNDJSON
+		}
+
+		dryRun := false
+		if v := query.Get("dry_run"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "dry_run")
+				h.logger.Error("dry_run is invalid")
+				return
+			}
+			dryRun = b
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxImportSize)
+		report, err := h.imports.Import(r.Context(), format, body, query.Get("title"), dryRun)
+		var tooLarge *http.MaxBytesError
+		var invalid *services.ImportError
+		switch {
+		case errors.Is(err, importer.ErrUnknownFormat):
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "format")
+			h.logger.Error("import format is invalid")
+			return
+		case errors.Is(err, importer.ErrTitleRequired):
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "title")
+			h.logger.Error("import title is missing")
+			return
+		case errors.As(err, &tooLarge):
+			writeError(w, r, http.StatusRequestEntityTooLarge, i18n.ImportTooLarge, tooLarge.Limit)
+			h.logger.Error("import file is too large")
+			return
+		case errors.Is(err, importer.ErrArchiveTooLarge):
+			writeError(w, r, http.StatusRequestEntityTooLarge, i18n.ImportTooLarge, importer.MaxArchiveSize)
+			h.logger.Error("import archive is too large unpacked")
+			return
+		case errors.As(err, &invalid):
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidImport, invalid.Error())
+			h.logger.Error(fmt.Sprintf("failed to parse %s import: %v", format, err))
+			return
+		case err != nil:
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to import %s: %v", format, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(report)
+		h.logger.Info(fmt.Sprintf("imported %d chats and %d messages from %s (dry run: %t, %d errors)",
+			report.Chats, report.Messages, format, dryRun, len(report.Errors)))
+	})
+}
@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func WithIncomingHooks(hooks services.IncomingHooksService) Option {
+	return func(h *Handler) {
+		h.hooks = hooks
+	}
+}
+
+// HandleHooksCreate mints an incoming webhook for the chat. The token is in
+// the response only this once.
+func (h *Handler) HandleHooksCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling create incoming hook")
+
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		type CreateHookReq struct {
+			Name string `json:"name"`
+		}
+
+		var req CreateHookReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+
+		hook, err := h.hooks.Create(r.Context(), chatId, req.Name, callerId(r))
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(w, r, err)
+			h.logger.Error("incoming hook request is invalid")
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to create incoming hook for chat %s: %v", chatPublicId, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(hook)
+		h.logger.Info(fmt.Sprintf("successfully created incoming hook %s for chat %s", hook.PublicId, chatPublicId))
+	}
+}
+
+func (h *Handler) HandleHooksList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list incoming hooks")
+
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+
+		hooks, err := h.hooks.List(r.Context(), chatId)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to list incoming hooks of chat %s: %v", chatPublicId, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hooks)
+	}
+}
+
+func (h *Handler) HandleHooksRevoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling revoke incoming hook")
+
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+		hookId := r.PathValue("hookId")
+		if !model.IsPublicId(hookId) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "hookId")
+			h.logger.Error("incoming hook id is invalid")
+			return
+		}
+
+		err := h.hooks.Revoke(r.Context(), chatId, hookId)
+		if errors.Is(err, repository.ErrHookNotFound) {
+			writeError(w, r, http.StatusNotFound, i18n.HookNotFound)
+			h.logger.Error(fmt.Sprintf("incoming hook %s of chat %s not found", hookId, chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to revoke incoming hook %s: %v", hookId, err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		h.logger.Info(fmt.Sprintf("revoked incoming hook %s of chat %s", hookId, chatPublicId))
+	}
+}
+
+// HandleHookPost posts a message to the chat of the hook in the URL. The
+// message goes through the same validation as messages of users.
+func (h *Handler) HandleHookPost() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling incoming hook post")
+
+		hook, ok := h.authorizeHook(w, r)
+		if !ok {
+			return
+		}
+
+		type HookPostReq struct {
+			Text        string   `json:"text"`
+			Username    string   `json:"username"`
+			Attachments []string `json:"attachments"`
+		}
+
+		var req HookPostReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+
+		var senderName string
+		if req.Username != "" {
+			name, err := h.hooks.ValidateSenderName(req.Username)
+			if err != nil {
+				writeValidationError(w, r, err)
+				h.logger.Error("incoming hook username is invalid")
+				return
+			}
+			senderName = name
+		}
+
+		text, ok := h.messageText(w, r, req.Text, req.Attachments)
+		if !ok {
+			return
+		}
+
+		message, err := h.messages.CreateMessage(r.Context(), services.NewMessage{
+			ChatId:        hook.ChatId,
+			SenderId:      hook.SenderId(),
+			SenderName:    senderName,
+			Text:          text,
+			AttachmentIds: req.Attachments,
+		})
+		h.writeCreatedMessage(w, r, hook.ChatPublicId, "", message, err)
+	}
+}
+
+// HandleHookUpload uploads files that a following post of the same hook can
+// attach.
+func (h *Handler) HandleHookUpload() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling incoming hook upload")
+
+		hook, ok := h.authorizeHook(w, r)
+		if !ok {
+			return
+		}
+
+		parts, err := r.MultipartReader()
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidUpload, err.Error())
+			h.logger.Error("upload is not multipart/form-data")
+			return
+		}
+
+		h.uploadAttachments(w, r, parts, hook.ChatId, hook.ChatPublicId, hook.SenderId())
+	}
+}
+
+// authorizeHook finds the hook of the {token} path segment and takes one
+// request from its rate limit.
+func (h *Handler) authorizeHook(w http.ResponseWriter, r *http.Request) (*model.IncomingHook, bool) {
+	hook, err := h.hooks.Authorize(r.Context(), r.PathValue("token"))
+	var limited *services.RateLimitError
+	switch {
+	case errors.Is(err, repository.ErrHookNotFound):
+		writeError(w, r, http.StatusNotFound, i18n.HookNotFound)
+		h.logger.Error("incoming hook token not found")
+		return nil, false
+	case errors.As(err, &limited):
+		seconds := int(limited.RetryAfter.Seconds())
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		writeError(w, r, http.StatusTooManyRequests, i18n.RateLimited, seconds)
+		h.logger.Error(fmt.Sprintf("incoming hook is rate limited for %s", limited.RetryAfter))
+		return nil, false
+	case err != nil:
+		writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+		h.logger.Error(fmt.Sprintf("failed to authorize incoming hook: %v", err))
+		return nil, false
+	}
+	return hook, true
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+func WithMembers(members services.MembersService) Option {
+	return func(h *Handler) {
+		h.members = members
+	}
+}
+
+// resolveChat resolves a chat as the caller sees it. Direct chats are found
+// only for their members and the admin token; for anyone else they do not
+// exist.
+func (h *Handler) resolveChat(r *http.Request, chatPublicId string) (int64, error) {
+	if h.hasAdminToken(r) {
+		return h.chats.ResolveChatId(r.Context(), chatPublicId)
+	}
+	return h.chats.ResolveChatIdFor(r.Context(), chatPublicId, callerId(r))
+}
+
+// chatId resolves the chat in the {id} path segment.
+func (h *Handler) chatId(w http.ResponseWriter, r *http.Request) (int64, string, bool) {
+	chatPublicId := r.PathValue("id")
+	if !model.IsPublicId(chatPublicId) {
+		writeError(w, r, http.StatusBadRequest, i18n.InvalidChatId)
+		h.logger.Error("chat id is invalid")
+		return 0, "", false
+	}
+
+	chatId, err := h.resolveChat(r, chatPublicId)
+	if errors.Is(err, repository.ErrChatNotFound) {
+		writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+		h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+		return 0, "", false
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+		h.logger.Error(fmt.Sprintf("failed to resolve chat with id %s: %v", chatPublicId, err))
+		return 0, "", false
+	}
+	return chatId, chatPublicId, true
+}
+
+// chatAdmin resolves the chat in the {id} path segment and lets the request
+// through only for admins of that chat. The admin token is an admin of every
+// chat.
+func (h *Handler) chatAdmin(w http.ResponseWriter, r *http.Request) (int64, string, bool) {
+	chatId, chatPublicId, ok := h.chatId(w, r)
+	if !ok {
+		return 0, "", false
+	}
+	if h.hasAdminToken(r) {
+		return chatId, chatPublicId, true
+	}
+
+	isAdmin, err := h.members.IsAdmin(r.Context(), chatId, callerId(r))
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+		h.logger.Error(fmt.Sprintf("failed to check role in chat %s: %v", chatPublicId, err))
+		return 0, "", false
+	}
+	if !isAdmin {
+		writeError(w, r, http.StatusForbidden, i18n.Forbidden)
+		h.logger.Error(fmt.Sprintf("user %q is not an admin of chat %s", callerId(r), chatPublicId))
+		return 0, "", false
+	}
+	return chatId, chatPublicId, true
+}
+
+func (h *Handler) HandleMembersList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list chat members")
+
+		chatId, chatPublicId, ok := h.chatId(w, r)
+		if !ok {
+			return
+		}
+
+		members, err := h.members.List(r.Context(), chatId)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to list members of chat %s: %v", chatPublicId, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(members)
+	}
+}
+
+// HandleMembersSet adds a user to the chat or changes their role.
+func (h *Handler) HandleMembersSet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling set chat member")
+
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+		userId := r.PathValue("userId")
+
+		type SetMemberReq struct {
+			Role string `json:"role"`
+		}
+
+		var req SetMemberReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+
+		member, err := h.members.SetRole(r.Context(), chatId, userId, req.Role)
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(w, r, err)
+			h.logger.Error("member request is invalid")
+			return
+		}
+		if errors.Is(err, repository.ErrDirectChat) {
+			writeError(w, r, http.StatusConflict, i18n.DirectChat)
+			h.logger.Error(fmt.Sprintf("cannot add %q to direct chat %s", userId, chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to set role of %q in chat %s: %v", userId, chatPublicId, err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(member)
+		h.logger.Info(fmt.Sprintf("user %q is now %s of chat %s", userId, member.Role, chatPublicId))
+	}
+}
+
+func (h *Handler) HandleMembersRemove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling remove chat member")
+
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+		userId := r.PathValue("userId")
+
+		err := h.members.Remove(r.Context(), chatId, userId)
+		if errors.Is(err, repository.ErrDirectChat) {
+			writeError(w, r, http.StatusConflict, i18n.DirectChat)
+			h.logger.Error(fmt.Sprintf("cannot remove %q from direct chat %s", userId, chatPublicId))
+			return
+		}
+		if errors.Is(err, repository.ErrMemberNotFound) {
+			writeError(w, r, http.StatusNotFound, i18n.MemberNotFound)
+			h.logger.Error(fmt.Sprintf("user %q is not a member of chat %s", userId, chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to remove %q from chat %s: %v", userId, chatPublicId, err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		h.logger.Info(fmt.Sprintf("removed %q from chat %s", userId, chatPublicId))
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+)
+
+// HandleMetrics serves the expvar counters to admins. The cmdline and
+// memstats variables published by the expvar package itself are left out:
+// the command line carries the secrets passed as flags.
+func (h *Handler) HandleMetrics() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, "{")
+		first := true
+		expvar.Do(func(kv expvar.KeyValue) {
+			if kv.Key == "cmdline" || kv.Key == "memstats" {
+				return
+			}
+			if !first {
+				fmt.Fprint(w, ",")
+			}
+			first = false
+			fmt.Fprintf(w, "%q:%s", kv.Key, kv.Value)
+		})
+		fmt.Fprint(w, "}")
+	})
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const maxNotificationsPerRead = 100
+
+func WithNotifications(notifications services.NotificationsService) Option {
+	return func(h *Handler) {
+		h.notifications = notifications
+	}
+}
+
+// HandleNotificationsList pages through the caller's unread mentions and
+// replies across all chats, newest first.
+func (h *Handler) HandleNotificationsList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list notifications")
+
+		userId, ok := h.callerUser(w, r)
+		if !ok {
+			return
+		}
+
+		limit, ok := parseLimit(r, 50, 100)
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "limit")
+			h.logger.Error("limit is invalid")
+			return
+		}
+
+		before := r.URL.Query().Get("before")
+		if before != "" && !model.IsPublicId(before) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "before")
+			h.logger.Error("before is invalid")
+			return
+		}
+
+		notifications, err := h.notifications.ListNotifications(r.Context(), userId, before, limit)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to list notifications of user %q: %v", userId, err))
+			return
+		}
+
+		type Response struct {
+			Notifications []*model.Notification `json:"notifications"`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(&Response{Notifications: notifications})
+		h.logger.Info(fmt.Sprintf("successfully listed %d notifications of user %q", len(notifications), userId))
+	}
+}
+
+// HandleNotificationsRead marks the notifications with the given ids read, or
+// all of the caller's notifications without a body.
+func (h *Handler) HandleNotificationsRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling mark notifications read")
+
+		userId, ok := h.callerUser(w, r)
+		if !ok {
+			return
+		}
+
+		type ReadNotificationsReq struct {
+			Ids []string `json:"ids"`
+		}
+
+		var req ReadNotificationsReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+		if len(req.Ids) > maxNotificationsPerRead {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "ids")
+			h.logger.Error("too many notification ids")
+			return
+		}
+		for _, id := range req.Ids {
+			if !model.IsPublicId(id) {
+				writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "ids")
+				h.logger.Error("notification id is invalid")
+				return
+			}
+		}
+
+		marked, err := h.notifications.MarkNotificationsRead(r.Context(), userId, req.Ids)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to mark notifications of user %q read: %v", userId, err))
+			return
+		}
+
+		type ReadNotificationsResp struct {
+			Marked int64 `json:"marked"`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ReadNotificationsResp{Marked: marked})
+		h.logger.Info(fmt.Sprintf("marked %d notifications of user %q read", marked, userId))
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+func WithPins(pins services.PinsService) Option {
+	return func(h *Handler) {
+		h.pins = pins
+	}
+}
+
+// HandlePinsList lists the pinned messages of the chat in the order they were
+// pinned.
+func (h *Handler) HandlePinsList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list pinned messages")
+
+		chatId, chatPublicId, ok := h.chatId(w, r)
+		if !ok {
+			return
+		}
+
+		messages, err := h.pins.ListPins(r.Context(), chatId)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to list pinned messages of chat %s: %v", chatPublicId, err))
+			return
+		}
+
+		type PinsResp struct {
+			Pins []*model.Message `json:"pins"`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(PinsResp{Pins: messages})
+	}
+}
+
+// HandlePinsPin pins a message. Pinning a pinned message changes nothing.
+func (h *Handler) HandlePinsPin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling pin message")
+
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+		messageId, ok := h.pinnedMessageId(w, r)
+		if !ok {
+			return
+		}
+
+		message, err := h.pins.Pin(r.Context(), chatId, messageId, callerId(r))
+		var tooMany *services.TooManyPinsError
+		if errors.As(err, &tooMany) {
+			writeError(w, r, http.StatusConflict, i18n.TooManyPins, tooMany.Limit)
+			h.logger.Error(fmt.Sprintf("chat %s has too many pinned messages", chatPublicId))
+			return
+		}
+		if !h.pinError(w, r, err, chatPublicId, messageId) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(message)
+		h.logger.Info(fmt.Sprintf("pinned message %s in chat %s", messageId, chatPublicId))
+	}
+}
+
+// HandlePinsUnpin unpins a message. Unpinning a message that is not pinned
+// changes nothing.
+func (h *Handler) HandlePinsUnpin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling unpin message")
+
+		chatId, chatPublicId, ok := h.chatAdmin(w, r)
+		if !ok {
+			return
+		}
+		messageId, ok := h.pinnedMessageId(w, r)
+		if !ok {
+			return
+		}
+
+		_, err := h.pins.Unpin(r.Context(), chatId, messageId)
+		if !h.pinError(w, r, err, chatPublicId, messageId) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		h.logger.Info(fmt.Sprintf("unpinned message %s in chat %s", messageId, chatPublicId))
+	}
+}
+
+func (h *Handler) pinnedMessageId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	messageId := r.PathValue("messageId")
+	if !model.IsPublicId(messageId) {
+		writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "messageId")
+		h.logger.Error(fmt.Sprintf("message id %q is invalid", messageId))
+		return "", false
+	}
+	return messageId, true
+}
+
+// pinError answers a failed pin or unpin and reports whether err was nil.
+func (h *Handler) pinError(w http.ResponseWriter, r *http.Request, err error, chatPublicId, messageId string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrChatNotFound):
+		writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+		h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+	case errors.Is(err, repository.ErrMessageNotFound):
+		writeError(w, r, http.StatusNotFound, i18n.MessageNotFound)
+		h.logger.Error(fmt.Sprintf("message %s not found in chat %s", messageId, chatPublicId))
+	default:
+		writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+		h.logger.Error(fmt.Sprintf("failed to change pin of message %s in chat %s: %v", messageId, chatPublicId, err))
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+func WithReads(reads services.ReadsService) Option {
+	return func(h *Handler) {
+		h.reads = reads
+	}
+}
+
+// callerUser is callerId for endpoints that keep per-user state and so cannot
+// serve anonymous requests.
+func (h *Handler) callerUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userId := callerId(r)
+	if userId == "" {
+		writeError(w, r, http.StatusUnauthorized, i18n.UserRequired)
+		h.logger.Error("request without a user")
+		return "", false
+	}
+	return userId, true
+}
+
+// HandleChatsRead marks the chat read up to the message with the given seq,
+// or up to the last message without one. Seqs start at 1, so an explicit 0
+// is refused rather than taken for "everything".
+func (h *Handler) HandleChatsRead() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling mark chat read")
+
+		userId, ok := h.callerUser(w, r)
+		if !ok {
+			return
+		}
+		chatId, chatPublicId, ok := h.chatId(w, r)
+		if !ok {
+			return
+		}
+
+		type ReadReq struct {
+			Seq *int64 `json:"seq"`
+		}
+
+		var req ReadReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+		var seq int64
+		if req.Seq != nil {
+			if *req.Seq < 1 {
+				writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "seq")
+				h.logger.Error("read seq is not positive")
+				return
+			}
+			seq = *req.Seq
+		}
+
+		receipt, unread, err := h.reads.MarkRead(r.Context(), chatId, userId, seq)
+		if errors.Is(err, repository.ErrChatNotFound) {
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to mark chat %s read: %v", chatPublicId, err))
+			return
+		}
+
+		type ReadResp struct {
+			*model.ReadReceipt
+			UnreadCount int64
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ReadResp{ReadReceipt: receipt, UnreadCount: unread})
+		h.logger.Info(fmt.Sprintf("user %q read chat %s up to %d", userId, chatPublicId, receipt.LastReadSeq))
+	}
+}
@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/realtime"
+	"chats-api/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+const (
+	realtimeReadLimit    = 4 << 10
+	realtimeWriteTimeout = 10 * time.Second
+	realtimePingInterval = 30 * time.Second
+)
+
+func WithRealtime(hub *realtime.Hub, presence *realtime.Presence) Option {
+	return func(h *Handler) {
+		h.hub = hub
+		h.presence = presence
+	}
+}
+
+// realtimeFrame is what clients send over the real-time connection and what
+// the server answers them with. Events go out as they are published, with
+// their own type, e.g. "message.created"; typing and presence go out as
+// realtime.Signal.
+type realtimeFrame struct {
+	Type   string `json:"type"`
+	ChatId string `json:"chat_id,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	Code   string `json:"code,omitempty"`
+}
+
+const (
+	frameSubscribe    = "subscribe"
+	frameUnsubscribe  = "unsubscribe"
+	frameSubscribed   = "subscribed"
+	frameUnsubscribed = "unsubscribed"
+	frameError        = "error"
+)
+
+// HandleRealtime upgrades to a WebSocket that carries the events of the chats
+// the client follows: those in chat_id query parameters and those it
+// subscribes to later.
+func (h *Handler) HandleRealtime() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling realtime connection")
+
+		userId, ok := h.callerUser(w, r)
+		if !ok {
+			return
+		}
+
+		chats := r.URL.Query()["chat_id"]
+		for _, chatPublicId := range chats {
+			if status, code := h.realtimeChat(r.Context(), userId, chatPublicId); code != "" {
+				writeError(w, r, status, code)
+				h.logger.Error(fmt.Sprintf("cannot follow chat %q: %s", chatPublicId, code))
+				return
+			}
+		}
+
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			h.logger.Error(fmt.Sprintf("failed to accept realtime connection: %v", err))
+			return
+		}
+		defer conn.CloseNow()
+		conn.SetReadLimit(realtimeReadLimit)
+
+		client := h.hub.Register(userId)
+		defer h.hub.Unregister(client)
+		defer func() { h.presence.Disconnect(client, time.Now()) }()
+		for _, chatPublicId := range chats {
+			h.hub.Subscribe(client, chatPublicId)
+		}
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		written := make(chan error, 1)
+		go func() {
+			written <- h.writeRealtime(ctx, conn, client)
+			cancel()
+		}()
+
+		lang := i18n.FromRequest(r)
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				break
+			}
+			reply := h.realtimeReply(ctx, client, data, lang)
+			if reply == nil {
+				continue
+			}
+			if err := writeFrame(ctx, conn, *reply); err != nil {
+				break
+			}
+		}
+		cancel()
+
+		if err := <-written; err != nil {
+			h.logger.Info(fmt.Sprintf("realtime connection of user %q closed: %v", userId, err))
+		}
+	}
+}
+
+// writeRealtime sends the client's events and keeps the connection alive
+// until ctx is done. A client that fell too far behind is disconnected.
+func (h *Handler) writeRealtime(ctx context.Context, conn *websocket.Conn, client *realtime.Client) error {
+	ticker := time.NewTicker(realtimePingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-client.Done():
+			conn.Close(websocket.StatusPolicyViolation, "client is too slow")
+			return errors.New("client is too slow")
+		case frame := <-client.Send():
+			writeCtx, cancel := context.WithTimeout(ctx, realtimeWriteTimeout)
+			err := conn.Write(writeCtx, websocket.MessageText, frame)
+			cancel()
+			if err != nil {
+				return err
+			}
+		case <-ticker.C:
+			pingCtx, cancel := context.WithTimeout(ctx, realtimeWriteTimeout)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// realtimeReply handles one client frame. Typing and presence updates are
+// not answered unless they fail.
+func (h *Handler) realtimeReply(ctx context.Context, client *realtime.Client, data []byte, lang string) *realtimeFrame {
+	var frame realtimeFrame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return errorFrame(lang, "", i18n.InvalidJSON, err.Error())
+	}
+
+	switch frame.Type {
+	case frameSubscribe:
+		if _, code := h.realtimeChat(ctx, client.UserId, frame.ChatId); code != "" {
+			return errorFrame(lang, frame.ChatId, code)
+		}
+		h.hub.Subscribe(client, frame.ChatId)
+		return &realtimeFrame{Type: frameSubscribed, ChatId: frame.ChatId}
+	case frameUnsubscribe:
+		h.hub.Unsubscribe(client, frame.ChatId)
+		h.presence.Leave(client, frame.ChatId, time.Now())
+		return &realtimeFrame{Type: frameUnsubscribed, ChatId: frame.ChatId}
+	case realtime.SignalTyping, realtime.SignalPresence:
+		if !h.hub.Subscribed(client, frame.ChatId) {
+			return errorFrame(lang, frame.ChatId, i18n.NotSubscribed)
+		}
+		switch {
+		case frame.Type == realtime.SignalTyping && (frame.Status == "" || frame.Status == realtime.StatusTyping):
+			h.presence.Typing(frame.ChatId, client.UserId, true, time.Now())
+		case frame.Type == realtime.SignalTyping && frame.Status == realtime.StatusStopped:
+			h.presence.Typing(frame.ChatId, client.UserId, false, time.Now())
+		case frame.Type == realtime.SignalPresence && (frame.Status == "" || frame.Status == realtime.StatusOnline):
+			h.presence.SetOnline(frame.ChatId, client.UserId, true, time.Now())
+		case frame.Type == realtime.SignalPresence && frame.Status == realtime.StatusOffline:
+			h.presence.SetOnline(frame.ChatId, client.UserId, false, time.Now())
+		default:
+			return errorFrame(lang, frame.ChatId, i18n.InvalidParameter, "status")
+		}
+		return nil
+	default:
+		return errorFrame(lang, frame.ChatId, i18n.InvalidParameter, "type")
+	}
+}
+
+// realtimeChat checks that userId can follow a chat and otherwise returns the
+// status and error code to answer with.
+func (h *Handler) realtimeChat(ctx context.Context, userId, chatPublicId string) (int, string) {
+	if !model.IsPublicId(chatPublicId) {
+		return http.StatusBadRequest, i18n.InvalidChatId
+	}
+
+	_, err := h.chats.ResolveChatIdFor(ctx, chatPublicId, userId)
+	if errors.Is(err, repository.ErrChatNotFound) {
+		return http.StatusNotFound, i18n.ChatNotFound
+	}
+	if err != nil {
+		h.logger.Error(fmt.Sprintf("failed to resolve chat with id %s: %v", chatPublicId, err))
+		return http.StatusInternalServerError, i18n.InternalError
+	}
+	return http.StatusOK, ""
+}
+
+func errorFrame(lang, chatId, code string, args ...any) *realtimeFrame {
+	return &realtimeFrame{Type: frameError, ChatId: chatId, Code: code, Error: i18n.Translate(lang, code, args...)}
+}
+
+func writeFrame(ctx context.Context, conn *websocket.Conn, frame realtimeFrame) error {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, realtimeWriteTimeout)
+	defer cancel()
+	return conn.Write(ctx, websocket.MessageText, data)
+}
+
+// HandleChatsOnline lists the users online in the chat. Presence is kept by
+// each instance for its own connections, so with several instances the list
+// covers only those of the instance serving the request.
+func (h *Handler) HandleChatsOnline() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list online users")
+
+		_, chatPublicId, ok := h.chatId(w, r)
+		if !ok {
+			return
+		}
+
+		type OnlineResp struct {
+			ChatId string   `json:"chat_id"`
+			Users  []string `json:"users"`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(OnlineResp{ChatId: chatPublicId, Users: h.presence.Online(chatPublicId, time.Now())})
+	}
+}
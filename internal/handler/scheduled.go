@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+func WithScheduled(scheduled services.ScheduledService) Option {
+	return func(h *Handler) {
+		h.scheduled = scheduled
+	}
+}
+
+// scheduleMessage answers a message creation that came with send_at. The
+// message waits in its own list until it is due, so only a known user can
+// schedule it and find it again.
+func (h *Handler) scheduleMessage(w http.ResponseWriter, r *http.Request, chatPublicId string, input services.NewMessage, sendAt time.Time) {
+	if _, ok := h.callerUser(w, r); !ok {
+		return
+	}
+	if len(input.AttachmentIds) > 0 {
+		writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "attachment_ids")
+		h.logger.Error("attachments cannot be scheduled")
+		return
+	}
+	if !h.validSendAt(w, r, sendAt) {
+		return
+	}
+
+	message, err := h.scheduled.Schedule(r.Context(), input, sendAt)
+	if errors.Is(err, repository.ErrDuplicateMessage) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(message)
+		h.logger.Info(fmt.Sprintf("message with client_msg_id %s is already scheduled in chat %s", input.ClientMsgId, chatPublicId))
+		return
+	}
+	if errors.Is(err, repository.ErrChatNotFound) {
+		writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+		h.logger.Error(fmt.Sprintf("chat with id %s not found", chatPublicId))
+		return
+	}
+	if errors.Is(err, repository.ErrChatReadOnly) {
+		writeError(w, r, http.StatusForbidden, i18n.ChatReadOnly)
+		h.logger.Error(fmt.Sprintf("%s cannot post to chat %s", input.SenderId, chatPublicId))
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+		h.logger.Error(fmt.Sprintf("failed to schedule message in chat %s: %v", chatPublicId, err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(message)
+	h.logger.Info(fmt.Sprintf("scheduled message %s in chat %s for %s", message.PublicId, chatPublicId, message.SendAt.Format(time.RFC3339)))
+}
+
+func (h *Handler) validSendAt(w http.ResponseWriter, r *http.Request, sendAt time.Time) bool {
+	if !sendAt.After(time.Now()) {
+		writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "send_at")
+		h.logger.Error("send_at is not in the future")
+		return false
+	}
+	return true
+}
+
+// HandleScheduledList lists the caller's messages waiting to be posted to the
+// chat, the soonest first.
+func (h *Handler) HandleScheduledList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list scheduled messages")
+
+		userId, ok := h.callerUser(w, r)
+		if !ok {
+			return
+		}
+		chatId, chatPublicId, ok := h.chatId(w, r)
+		if !ok {
+			return
+		}
+
+		messages, err := h.scheduled.ListScheduled(r.Context(), chatId, userId)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to list scheduled messages of %q in chat %s: %v", userId, chatPublicId, err))
+			return
+		}
+
+		type ScheduledResp struct {
+			Scheduled []*model.ScheduledMessage `json:"scheduled"`
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ScheduledResp{Scheduled: messages})
+	}
+}
+
+// HandleScheduledUpdate changes the text or the send time of one of the
+// caller's scheduled messages.
+func (h *Handler) HandleScheduledUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling update scheduled message")
+
+		userId, ok := h.callerUser(w, r)
+		if !ok {
+			return
+		}
+		chatId, chatPublicId, ok := h.chatId(w, r)
+		if !ok {
+			return
+		}
+		scheduledId, ok := h.scheduledId(w, r)
+		if !ok {
+			return
+		}
+
+		type UpdateScheduledReq struct {
+			Text   *string    `json:"text"`
+			SendAt *time.Time `json:"send_at"`
+		}
+
+		var req UpdateScheduledReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+
+		update := repository.ScheduledUpdate{SendAt: req.SendAt}
+		if req.Text != nil {
+			text, err := h.messages.ValidateMessageCreate(*req.Text)
+			if err != nil {
+				writeValidationError(w, r, err)
+				h.logger.Error("scheduled message update is invalid")
+				return
+			}
+			update.Text = &text
+		}
+		if req.SendAt != nil && !h.validSendAt(w, r, *req.SendAt) {
+			return
+		}
+
+		message, err := h.scheduled.UpdateScheduled(r.Context(), chatId, userId, scheduledId, update)
+		if !h.scheduledError(w, r, err, chatPublicId, scheduledId) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(message)
+		h.logger.Info(fmt.Sprintf("updated scheduled message %s in chat %s", scheduledId, chatPublicId))
+	}
+}
+
+// HandleScheduledCancel drops one of the caller's scheduled messages.
+func (h *Handler) HandleScheduledCancel() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling cancel scheduled message")
+
+		userId, ok := h.callerUser(w, r)
+		if !ok {
+			return
+		}
+		chatId, chatPublicId, ok := h.chatId(w, r)
+		if !ok {
+			return
+		}
+		scheduledId, ok := h.scheduledId(w, r)
+		if !ok {
+			return
+		}
+
+		err := h.scheduled.CancelScheduled(r.Context(), chatId, userId, scheduledId)
+		if !h.scheduledError(w, r, err, chatPublicId, scheduledId) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		h.logger.Info(fmt.Sprintf("cancelled scheduled message %s in chat %s", scheduledId, chatPublicId))
+	}
+}
+
+func (h *Handler) scheduledId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	scheduledId := r.PathValue("scheduledId")
+	if !model.IsPublicId(scheduledId) {
+		writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "scheduledId")
+		h.logger.Error(fmt.Sprintf("scheduled message id %q is invalid", scheduledId))
+		return "", false
+	}
+	return scheduledId, true
+}
+
+// scheduledError answers a failed change of a scheduled message and reports
+// whether err was nil.
+func (h *Handler) scheduledError(w http.ResponseWriter, r *http.Request, err error, chatPublicId, scheduledId string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrScheduledNotFound):
+		writeError(w, r, http.StatusNotFound, i18n.ScheduledNotFound)
+		h.logger.Error(fmt.Sprintf("scheduled message %s not found in chat %s", scheduledId, chatPublicId))
+	default:
+		writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+		h.logger.Error(fmt.Sprintf("failed to change scheduled message %s in chat %s: %v", scheduledId, chatPublicId, err))
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"chats-api/internal/i18n"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func WithWebhooks(webhooks services.WebhooksService) Option {
+	return func(h *Handler) {
+		h.webhooks = webhooks
+	}
+}
+
+func (h *Handler) HandleWebhooksCreate() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling create webhook")
+
+		type CreateWebhookReq struct {
+			Url    string   `json:"url"`
+			ChatId string   `json:"chat_id"`
+			Events []string `json:"events"`
+		}
+
+		var req CreateWebhookReq
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidJSON, err.Error())
+			h.logger.Error("got invalid json body")
+			return
+		}
+		if req.ChatId != "" && !model.IsPublicId(req.ChatId) {
+			writeError(w, r, http.StatusBadRequest, i18n.InvalidChatId)
+			h.logger.Error("chat id is invalid")
+			return
+		}
+
+		webhook, err := h.webhooks.Create(r.Context(), services.NewWebhook{ChatPublicId: req.ChatId, Url: req.Url, Events: req.Events})
+		var validationErr *services.ValidationError
+		switch {
+		case errors.As(err, &validationErr):
+			writeValidationError(w, r, err)
+			h.logger.Error("webhook request is invalid")
+			return
+		case errors.Is(err, repository.ErrChatNotFound):
+			writeError(w, r, http.StatusNotFound, i18n.ChatNotFound)
+			h.logger.Error(fmt.Sprintf("chat with id %s not found", req.ChatId))
+			return
+		case err != nil:
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to create webhook: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(webhook)
+		h.logger.Info(fmt.Sprintf("successfully created webhook %s", webhook.PublicId))
+	})
+}
+
+func (h *Handler) HandleWebhooksList() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list webhooks")
+
+		webhooks, err := h.webhooks.List(r.Context())
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+			h.logger.Error(fmt.Sprintf("failed to list webhooks: %v", err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(webhooks)
+	})
+}
+
+func (h *Handler) HandleWebhooksDelete() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling delete webhook")
+
+		publicId, ok := h.webhookId(w, r)
+		if !ok {
+			return
+		}
+
+		err := h.webhooks.Delete(r.Context(), publicId)
+		if !h.checkWebhookErr(w, r, publicId, err) {
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		h.logger.Info(fmt.Sprintf("successfully deleted webhook %s", publicId))
+	})
+}
+
+// HandleWebhooksEnable turns a webhook disabled after repeated failures back
+// on. Its pending deliveries are retried right away.
+func (h *Handler) HandleWebhooksEnable() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling enable webhook")
+
+		publicId, ok := h.webhookId(w, r)
+		if !ok {
+			return
+		}
+
+		webhook, err := h.webhooks.Enable(r.Context(), publicId)
+		if !h.checkWebhookErr(w, r, publicId, err) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(webhook)
+		h.logger.Info(fmt.Sprintf("successfully enabled webhook %s", publicId))
+	})
+}
+
+func (h *Handler) HandleWebhooksDeliveries() http.HandlerFunc {
+	return h.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+		h.logger.Info("handling list webhook deliveries")
+
+		publicId, ok := h.webhookId(w, r)
+		if !ok {
+			return
+		}
+
+		limit := 20
+		if v := r.URL.Query().Get("limit"); v != "" {
+			l, err := strconv.Atoi(v)
+			if err != nil || l < 1 {
+				writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "limit")
+				h.logger.Error("limit is invalid")
+				return
+			}
+			limit = l
+		}
+
+		deliveries, err := h.webhooks.ListDeliveries(r.Context(), publicId, limit)
+		if !h.checkWebhookErr(w, r, publicId, err) {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deliveries)
+	})
+}
+
+func (h *Handler) webhookId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	publicId := r.PathValue("id")
+	if !model.IsPublicId(publicId) {
+		writeError(w, r, http.StatusBadRequest, i18n.InvalidParameter, "id")
+		h.logger.Error("webhook id is invalid")
+		return "", false
+	}
+	return publicId, true
+}
+
+func (h *Handler) checkWebhookErr(w http.ResponseWriter, r *http.Request, publicId string, err error) bool {
+	if errors.Is(err, repository.ErrWebhookNotFound) {
+		writeError(w, r, http.StatusNotFound, i18n.WebhookNotFound)
+		h.logger.Error(fmt.Sprintf("webhook with id %s not found", publicId))
+		return false
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, i18n.InternalError)
+		h.logger.Error(fmt.Sprintf("webhook %s request failed: %v", publicId, err))
+		return false
+	}
+	return true
+}
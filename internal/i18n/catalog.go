@@ -0,0 +1,184 @@
+package i18n
+
+// Error codes are part of the API contract: they stay the same in every
+// language, only the human-readable message is translated.
+const (
+	InvalidJSON           = "invalid_json"
+	InvalidChatId         = "invalid_chat_id"
+	InvalidParameter      = "invalid_parameter"
+	ClientMsgIdTooLong    = "client_msg_id_too_long"
+	ChatNotFound          = "chat_not_found"
+	ChatTitleTaken        = "chat_title_taken"
+	ChatNotInTrash        = "chat_not_in_trash"
+	ValidationFailed      = "validation_failed"
+	InvalidSyncToken      = "invalid_sync_token"
+	ResyncRequired        = "resync_required"
+	InternalError         = "internal_error"
+	Unauthorized          = "unauthorized"
+	InvalidImport         = "invalid_import"
+	ImportTooLarge        = "import_too_large"
+	AttachmentNotFound    = "attachment_not_found"
+	AttachmentTooLarge    = "attachment_too_large"
+	AttachmentType        = "attachment_type_not_allowed"
+	InvalidUpload         = "invalid_upload"
+	InvalidSignature      = "invalid_signature"
+	WebhookNotFound       = "webhook_not_found"
+	Forbidden             = "forbidden"
+	MemberNotFound        = "member_not_found"
+	HookNotFound          = "hook_not_found"
+	RateLimited           = "rate_limited"
+	BotNotFound           = "bot_not_found"
+	BotNameTaken          = "bot_name_taken"
+	CommandTaken          = "command_taken"
+	InvalidBotKey         = "invalid_bot_key"
+	UserRequired          = "user_required"
+	ReservedUserId        = "reserved_user_id"
+	NotSubscribed         = "not_subscribed"
+	ReplyNotFound         = "reply_not_found"
+	MessageNotFound       = "message_not_found"
+	TooManyPins           = "too_many_pins"
+	ScheduledNotFound     = "scheduled_message_not_found"
+	ChatReadOnly          = "chat_read_only"
+	DirectChat            = "direct_chat"
+	DirectChatReopened    = "direct_chat_reopened"
+	ValidationRequired    = "required"
+	ValidationTooLong     = "too_long"
+	ValidationBadUTF8     = "invalid_utf8"
+	ValidationNotPositive = "not_positive"
+	ValidationTooLarge    = "too_large"
+	ValidationInvalid     = "invalid"
+	fieldLabelPrefix      = "field."
+	validationKeyPrefix   = "validation."
+)
+
+var catalog = map[string]map[string]string{
+	English: {
+		InvalidJSON:        "invalid json body: %s",
+		InvalidChatId:      "invalid chat_id",
+		InvalidParameter:   "invalid %s",
+		ClientMsgIdTooLong: "client_msg_id is too long",
+		ChatNotFound:       "chat not found",
+		ChatTitleTaken:     "chat with this title already exists",
+		ChatNotInTrash:     "chat is not in the trash or can no longer be restored",
+		ValidationFailed:   "%s",
+		InvalidSyncToken:   "invalid sync token",
+		ResyncRequired:     "sync token is too old, full resync required",
+		InternalError:      "internal server error",
+		Unauthorized:       "admin token is missing or invalid",
+		InvalidImport:      "cannot read import file: %s",
+		ImportTooLarge:     "import file is larger than %d bytes",
+		AttachmentNotFound: "attachment not found or already attached to a message",
+		AttachmentTooLarge: "file is larger than %d bytes",
+		AttachmentType:     "files of type %s are not allowed",
+		InvalidUpload:      "invalid upload: %s",
+		InvalidSignature:   "download link is invalid or expired",
+		WebhookNotFound:    "webhook not found",
+		Forbidden:          "only chat admins can do this",
+		MemberNotFound:     "user is not a member of the chat",
+		HookNotFound:       "incoming webhook not found or revoked",
+		RateLimited:        "too many requests, retry in %d seconds",
+		BotNotFound:        "bot not found",
+		BotNameTaken:       "bot with this name already exists",
+		CommandTaken:       "command is already handled by another bot",
+		InvalidBotKey:      "bot API key is invalid",
+		UserRequired:       "the X-User-Id header is required",
+		ReservedUserId:     "the X-User-Id header cannot name the service, a bot or an incoming hook",
+		NotSubscribed:      "subscribe to the chat first",
+		ReplyNotFound:      "message to reply to not found in the chat",
+		MessageNotFound:    "message not found in the chat",
+		TooManyPins:        "chat already has %d pinned messages",
+		ScheduledNotFound:  "scheduled message not found or already being sent",
+		ChatReadOnly:       "only admins can post to this chat",
+		DirectChat:         "members of a direct chat cannot change",
+		DirectChatReopened: "the users of this direct chat have opened a new one since it was deleted",
+
+		validationKeyPrefix + ValidationRequired:    "%s is required",
+		validationKeyPrefix + ValidationTooLong:     "%s cannot be longer than %d characters",
+		validationKeyPrefix + ValidationBadUTF8:     "%s is not valid UTF-8",
+		validationKeyPrefix + ValidationNotPositive: "%s must be positive",
+		validationKeyPrefix + ValidationTooLarge:    "%s cannot be greater than %d",
+		validationKeyPrefix + ValidationInvalid:     "%s is invalid",
+
+		fieldLabelPrefix + "title":           "chat title",
+		fieldLabelPrefix + "text":            "message",
+		fieldLabelPrefix + "max_age_seconds": "max_age_seconds",
+		fieldLabelPrefix + "max_messages":    "max_messages",
+		fieldLabelPrefix + "url":             "webhook URL",
+		fieldLabelPrefix + "events":          "events",
+		fieldLabelPrefix + "name":            "name",
+		fieldLabelPrefix + "username":        "username",
+		fieldLabelPrefix + "role":            "role",
+		fieldLabelPrefix + "user_id":         "user_id",
+		fieldLabelPrefix + "endpoint":        "bot endpoint",
+		fieldLabelPrefix + "commands":        "commands",
+	},
+	Russian: {
+		InvalidJSON:        "некорректное тело запроса JSON: %s",
+		InvalidChatId:      "некорректный идентификатор чата",
+		InvalidParameter:   "некорректный параметр %s",
+		ClientMsgIdTooLong: "client_msg_id слишком длинный",
+		ChatNotFound:       "чат не найден",
+		ChatTitleTaken:     "чат с таким названием уже существует",
+		ChatNotInTrash:     "чата нет в корзине или срок его восстановления истёк",
+		ValidationFailed:   "%s",
+		InvalidSyncToken:   "некорректный токен синхронизации",
+		ResyncRequired:     "токен синхронизации устарел, требуется полная синхронизация",
+		InternalError:      "внутренняя ошибка сервера",
+		Unauthorized:       "токен администратора не указан или неверен",
+		InvalidImport:      "не удалось прочитать файл импорта: %s",
+		ImportTooLarge:     "файл импорта больше %d байт",
+		AttachmentNotFound: "вложение не найдено или уже прикреплено к сообщению",
+		AttachmentTooLarge: "файл больше %d байт",
+		AttachmentType:     "файлы типа %s не разрешены",
+		InvalidUpload:      "некорректная загрузка: %s",
+		InvalidSignature:   "ссылка для скачивания недействительна или устарела",
+		WebhookNotFound:    "вебхук не найден",
+		Forbidden:          "это доступно только администраторам чата",
+		MemberNotFound:     "пользователь не состоит в чате",
+		HookNotFound:       "входящий вебхук не найден или отозван",
+		RateLimited:        "слишком много запросов, повторите через %d с",
+		BotNotFound:        "бот не найден",
+		BotNameTaken:       "бот с таким именем уже существует",
+		CommandTaken:       "команду уже обрабатывает другой бот",
+		InvalidBotKey:      "неверный API-ключ бота",
+		UserRequired:       "не указан заголовок X-User-Id",
+		ReservedUserId:     "заголовок X-User-Id не может называть сервис, бота или входящий вебхук",
+		NotSubscribed:      "сначала подпишитесь на чат",
+		ReplyNotFound:      "сообщение, на которое дан ответ, не найдено в чате",
+		MessageNotFound:    "сообщение не найдено в чате",
+		TooManyPins:        "в чате уже закреплено сообщений: %d",
+		ScheduledNotFound:  "запланированное сообщение не найдено или уже отправляется",
+		ChatReadOnly:       "писать в этот чат могут только администраторы",
+		DirectChat:         "состав личного чата нельзя изменить",
+		DirectChatReopened: "после удаления этого личного чата его участники открыли новый",
+
+		validationKeyPrefix + ValidationRequired:    "%s: обязательное поле",
+		validationKeyPrefix + ValidationTooLong:     "%s: длина не может превышать %d символов",
+		validationKeyPrefix + ValidationBadUTF8:     "%s: некорректная кодировка UTF-8",
+		validationKeyPrefix + ValidationNotPositive: "%s: значение должно быть положительным",
+		validationKeyPrefix + ValidationTooLarge:    "%s: значение не может превышать %d",
+		validationKeyPrefix + ValidationInvalid:     "%s: некорректное значение",
+
+		fieldLabelPrefix + "title":           "название чата",
+		fieldLabelPrefix + "text":            "сообщение",
+		fieldLabelPrefix + "max_age_seconds": "max_age_seconds",
+		fieldLabelPrefix + "max_messages":    "max_messages",
+		fieldLabelPrefix + "url":             "URL вебхука",
+		fieldLabelPrefix + "events":          "события",
+		fieldLabelPrefix + "name":            "название",
+		fieldLabelPrefix + "username":        "имя отправителя",
+		fieldLabelPrefix + "role":            "роль",
+		fieldLabelPrefix + "user_id":         "user_id",
+		fieldLabelPrefix + "endpoint":        "адрес бота",
+		fieldLabelPrefix + "commands":        "команды",
+	},
+}
+
+// TranslateField renders a field-level validation message.
+func TranslateField(lang, field, code string, limit int) string {
+	label := Translate(lang, fieldLabelPrefix+field)
+	if code == ValidationTooLong || code == ValidationTooLarge {
+		return Translate(lang, validationKeyPrefix+code, label, limit)
+	}
+	return Translate(lang, validationKeyPrefix+code, label)
+}
@@ -0,0 +1,51 @@
+package i18n
+
+import (
+	"fmt"
+	"net/http"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	English = "en"
+	Russian = "ru"
+)
+
+var matcher = language.NewMatcher([]language.Tag{language.English, language.Russian})
+
+// FromRequest picks the best supported language from the Accept-Language
+// header, falling back to English.
+func FromRequest(r *http.Request) string {
+	tags, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(tags) == 0 {
+		return English
+	}
+
+	_, idx, confidence := matcher.Match(tags...)
+	if confidence == language.No || idx != 1 {
+		return English
+	}
+	return Russian
+}
+
+// Translate renders the message for code in lang. Unknown languages fall back
+// to English and unknown codes are returned as is.
+func Translate(lang, code string, args ...any) string {
+	msgs, ok := catalog[lang]
+	if !ok {
+		msgs = catalog[English]
+	}
+
+	format, ok := msgs[code]
+	if !ok {
+		if format, ok = catalog[English][code]; !ok {
+			return code
+		}
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
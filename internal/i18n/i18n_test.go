@@ -0,0 +1,52 @@
+package i18n
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogsAreComplete(t *testing.T) {
+	for lang, msgs := range catalog {
+		for code := range catalog[English] {
+			require.Contains(t, msgs, code, "language %s misses %s", lang, code)
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{header: "", expected: English},
+		{header: "ru-RU,ru;q=0.9,en-US;q=0.8", expected: Russian},
+		{header: "en-GB,ru;q=0.5", expected: English},
+		{header: "de-DE", expected: English},
+		{header: "not a language", expected: English},
+	}
+
+	for _, test := range tests {
+		t.Run(test.header, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Accept-Language", test.header)
+
+			require.Equal(t, test.expected, FromRequest(r))
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	require.Equal(t, "chat not found", Translate(English, ChatNotFound))
+	require.Equal(t, "чат не найден", Translate(Russian, ChatNotFound))
+	require.Equal(t, "chat not found", Translate("fr", ChatNotFound))
+	require.Equal(t, "invalid limit", Translate(English, InvalidParameter, "limit"))
+	require.Equal(t, "unknown_code", Translate(Russian, "unknown_code"))
+
+	require.Equal(t, "название чата: длина не может превышать 200 символов",
+		TranslateField(Russian, "title", ValidationTooLong, 200))
+	require.Equal(t, "message is required", TranslateField(English, "text", ValidationRequired, 0))
+	require.Equal(t, "max_age_seconds cannot be greater than 3153600000",
+		TranslateField(English, "max_age_seconds", ValidationTooLarge, 3153600000))
+}
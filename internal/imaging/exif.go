@@ -0,0 +1,158 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	tagOrientation = 0x0112
+	tagGPSInfo     = 0x8825
+)
+
+// EXIF field sizes by type: BYTE, ASCII, SHORT, LONG, RATIONAL, SBYTE,
+// UNDEFINED, SSHORT, SLONG, SRATIONAL, FLOAT, DOUBLE.
+var exifTypeSizes = map[uint16]uint32{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// tiff is the TIFF structure inside an EXIF block.
+type tiff struct {
+	b     []byte
+	order binary.ByteOrder
+}
+
+func parseTIFF(b []byte) (*tiff, bool) {
+	switch {
+	case bytes.HasPrefix(b, []byte("II*\x00")):
+		return &tiff{b: b, order: binary.LittleEndian}, true
+	case bytes.HasPrefix(b, []byte("MM\x00*")):
+		return &tiff{b: b, order: binary.BigEndian}, true
+	}
+	return nil, false
+}
+
+func (t *tiff) u16(off uint32) (uint16, bool) {
+	if uint64(off)+2 > uint64(len(t.b)) {
+		return 0, false
+	}
+	return t.order.Uint16(t.b[off:]), true
+}
+
+func (t *tiff) u32(off uint32) (uint32, bool) {
+	if uint64(off)+4 > uint64(len(t.b)) {
+		return 0, false
+	}
+	return t.order.Uint32(t.b[off:]), true
+}
+
+// entry finds a tag in the IFD at off and returns the offset of its entry.
+func (t *tiff) entry(ifd uint32, tag uint16) (uint32, bool) {
+	n, ok := t.u16(ifd)
+	if !ok {
+		return 0, false
+	}
+	for i := uint32(0); i < uint32(n); i++ {
+		e := ifd + 2 + 12*i
+		if got, ok := t.u16(e); !ok {
+			return 0, false
+		} else if got == tag {
+			return e, true
+		}
+	}
+	return 0, false
+}
+
+func (t *tiff) ifd0() (uint32, bool) {
+	return t.u32(4)
+}
+
+func (t *tiff) orientation() int {
+	ifd, ok := t.ifd0()
+	if !ok {
+		return 1
+	}
+	e, ok := t.entry(ifd, tagOrientation)
+	if !ok {
+		return 1
+	}
+	v, _ := t.u16(e + 8)
+	if v < 1 || v > 8 {
+		return 1
+	}
+	return int(v)
+}
+
+// stripGPS wipes every GPS field and leaves an empty GPS IFD behind, so the
+// rest of the metadata and all offsets stay valid.
+func (t *tiff) stripGPS() bool {
+	ifd, ok := t.ifd0()
+	if !ok {
+		return false
+	}
+	e, ok := t.entry(ifd, tagGPSInfo)
+	if !ok {
+		return false
+	}
+	gps, ok := t.u32(e + 8)
+	if !ok {
+		return false
+	}
+	n, ok := t.u16(gps)
+	if !ok {
+		return false
+	}
+
+	for i := uint32(0); i < uint32(n); i++ {
+		entry := gps + 2 + 12*i
+		if uint64(entry)+12 > uint64(len(t.b)) {
+			break
+		}
+		typ, _ := t.u16(entry + 2)
+		count, _ := t.u32(entry + 4)
+		size := uint64(exifTypeSizes[typ]) * uint64(count)
+		if size > 4 {
+			off, _ := t.u32(entry + 8)
+			if uint64(off)+size <= uint64(len(t.b)) {
+				clear(t.b[off : uint64(off)+size])
+			}
+		}
+		clear(t.b[entry : entry+12])
+	}
+	t.order.PutUint16(t.b[gps:], 0)
+	return true
+}
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// jpegExif returns the TIFF blocks of the EXIF APP1 segments of a JPEG. They
+// alias data, so changes to them change the image in place.
+func jpegExif(data []byte) []*tiff {
+	var blocks []*tiff
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return nil
+	}
+
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return blocks
+		}
+		marker := data[i+1]
+		// Start of scan: entropy-coded data follows, no more metadata.
+		if marker == 0xDA || marker == 0xD9 {
+			return blocks
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return blocks
+		}
+		segment := data[i+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, exifHeader) {
+			if t, ok := parseTIFF(segment[len(exifHeader):]); ok {
+				blocks = append(blocks, t)
+			}
+		}
+		i = end
+	}
+
+	return blocks
+}
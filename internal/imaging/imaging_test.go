@@ -0,0 +1,193 @@
+package imaging_test
+
+import (
+	"bytes"
+	"chats-api/internal/imaging"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var gpsLatitude = []byte{55, 0, 0, 0, 1, 0, 0, 0, 45, 0, 0, 0, 1, 0, 0, 0, 0xD2, 0x04, 0, 0, 100, 0, 0, 0}
+
+// exifWithGPS builds a little-endian EXIF block with an orientation and a GPS
+// latitude.
+func exifWithGPS(orientation uint16) []byte {
+	le := binary.LittleEndian
+	b := []byte("II*\x00")
+	b = le.AppendUint32(b, 8)
+
+	b = le.AppendUint16(b, 2)
+	b = append(b, entry(0x0112, 3, 1, uint32(orientation))...)
+	b = append(b, entry(0x8825, 4, 1, 38)...)
+	b = le.AppendUint32(b, 0)
+
+	b = le.AppendUint16(b, 2)
+	b = append(b, entry(0x0001, 2, 2, uint32('N'))...)
+	b = append(b, entry(0x0002, 5, 3, 68)...)
+	b = le.AppendUint32(b, 0)
+
+	b = append(b, gpsLatitude...)
+	return append([]byte("Exif\x00\x00"), b...)
+}
+
+func entry(tag, typ uint16, count, value uint32) []byte {
+	le := binary.LittleEndian
+	b := le.AppendUint16(nil, tag)
+	b = le.AppendUint16(b, typ)
+	b = le.AppendUint32(b, count)
+	return le.AppendUint32(b, value)
+}
+
+func testJPEG(t *testing.T, w, h int, exif []byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	data := buf.Bytes()
+	if exif == nil {
+		return data
+	}
+
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(exif)+2))
+	segment = append(segment, exif...)
+	return append(append([]byte{0xFF, 0xD8}, segment...), data[2:]...)
+}
+
+func TestStripLocation_JPEG(t *testing.T) {
+	data := testJPEG(t, 8, 8, exifWithGPS(1))
+	require.True(t, bytes.Contains(data, gpsLatitude))
+
+	stripped := imaging.StripLocation(data, "image/jpeg")
+
+	require.False(t, bytes.Contains(stripped, gpsLatitude))
+	require.True(t, bytes.Contains(data, gpsLatitude), "the input is not modified")
+	require.Equal(t, len(data), len(stripped))
+	_, err := jpeg.Decode(bytes.NewReader(stripped))
+	require.NoError(t, err)
+}
+
+func TestStripLocation_PNG(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+	data := buf.Bytes()
+
+	// Insert an eXIf chunk right after IHDR (8 byte signature + 25 byte chunk).
+	exif := exifWithGPS(1)[6:]
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(exif)))
+	chunk = append(append(chunk, "eXIf"...), exif...)
+	chunk = append(chunk, 0, 0, 0, 0)
+	withExif := append(append(append([]byte{}, data[:33]...), chunk...), data[33:]...)
+
+	stripped := imaging.StripLocation(withExif, "image/png")
+
+	require.Equal(t, data, stripped)
+}
+
+func TestProcess_Thumbnails(t *testing.T) {
+	info, err := imaging.Process(testJPEG(t, 800, 400, nil), "image/jpeg")
+	require.NoError(t, err)
+
+	require.Equal(t, 800, info.Width)
+	require.Equal(t, 400, info.Height)
+	require.Len(t, info.Variants, 2)
+	require.Equal(t, "small", info.Variants[0].Name)
+	require.Equal(t, [2]int{160, 80}, [2]int{info.Variants[0].Width, info.Variants[0].Height})
+	require.Equal(t, [2]int{640, 320}, [2]int{info.Variants[1].Width, info.Variants[1].Height})
+
+	thumb, err := jpeg.Decode(bytes.NewReader(info.Variants[0].Data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 160, 80), thumb.Bounds())
+}
+
+func TestProcess_HonoursOrientation(t *testing.T) {
+	info, err := imaging.Process(testJPEG(t, 400, 200, exifWithGPS(6)), "image/jpeg")
+	require.NoError(t, err)
+
+	require.Equal(t, 200, info.Width)
+	require.Equal(t, 400, info.Height)
+	require.Len(t, info.Variants, 1, "the image is already within the medium size")
+
+	thumb, err := jpeg.Decode(bytes.NewReader(info.Variants[0].Data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 80, 160), thumb.Bounds())
+}
+
+func TestProcess_SmallImageHasNoThumbnails(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 100, 50))))
+
+	info, err := imaging.Process(buf.Bytes(), "image/png")
+	require.NoError(t, err)
+	require.Equal(t, 100, info.Width)
+	require.Empty(t, info.Variants)
+}
+
+func TestStripLocation_JPEGDropsXMP(t *testing.T) {
+	data := testJPEG(t, 8, 8, nil)
+	xmp := []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta><exif:GPSLatitude>55,45N</exif:GPSLatitude></x:xmpmeta>")
+	segment := binary.BigEndian.AppendUint16([]byte{0xFF, 0xE1}, uint16(len(xmp)+2))
+	withXMP := append(append([]byte{0xFF, 0xD8}, append(segment, xmp...)...), data[2:]...)
+
+	stripped := imaging.StripLocation(withXMP, "image/jpeg")
+
+	require.Equal(t, data, stripped)
+}
+
+func TestStripLocation_WebP(t *testing.T) {
+	chunk := func(fourCC string, payload []byte) []byte {
+		b := binary.LittleEndian.AppendUint32([]byte(fourCC), uint32(len(payload)))
+		b = append(b, payload...)
+		if len(payload)%2 == 1 {
+			b = append(b, 0)
+		}
+		return b
+	}
+	riff := func(chunks ...[]byte) []byte {
+		body := []byte("WEBP")
+		for _, c := range chunks {
+			body = append(body, c...)
+		}
+		return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+	}
+	vp8x := func(flags byte) []byte {
+		return chunk("VP8X", []byte{flags, 0, 0, 0, 7, 0, 0, 7, 0, 0})
+	}
+	bitstream := chunk("VP8L", []byte{0x2f, 7, 0xc0, 0x01, 0})
+
+	data := riff(vp8x(0x2c), bitstream, chunk("EXIF", exifWithGPS(1)[6:]), chunk("XMP ", []byte("<x:xmpmeta/>")))
+
+	stripped := imaging.StripLocation(data, "image/webp")
+
+	require.Equal(t, riff(vp8x(0x20), bitstream), stripped)
+}
+
+func TestStripLocation_GIFDropsXMP(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White}), nil))
+	data := buf.Bytes()
+
+	// An application extension with the XMP packet, before the trailer.
+	xmp := append([]byte{0x21, 0xFF, 0x0B}, "XMP DataXMP"...)
+	xmp = append(xmp, 12)
+	xmp = append(xmp, "<x:xmpmeta/>"...)
+	xmp = append(xmp, 0)
+	withXMP := append(append(bytes.Clone(data[:len(data)-1]), xmp...), 0x3B)
+
+	stripped := imaging.StripLocation(withXMP, "image/gif")
+
+	require.Equal(t, data, stripped)
+	_, err := gif.Decode(bytes.NewReader(stripped))
+	require.NoError(t, err)
+}
@@ -0,0 +1,217 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// StripLocation removes location metadata from an image: GPS fields of EXIF
+// and whole XMP packets, which may repeat them. JPEG keeps the rest of its
+// EXIF, such as the orientation; PNG and WebP lose EXIF altogether. Files that
+// do not parse are returned unchanged.
+func StripLocation(data []byte, contentType string) []byte {
+	switch contentType {
+	case "image/jpeg":
+		data = bytes.Clone(data)
+		for _, t := range jpegExif(data) {
+			t.stripGPS()
+		}
+		return stripJPEGXMP(data)
+	case "image/png":
+		return stripPNG(data)
+	case "image/gif":
+		return stripGIFXMP(data)
+	case "image/webp":
+		return stripWebP(data)
+	}
+	return data
+}
+
+var (
+	xmpHeader         = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	xmpExtendedHeader = []byte("http://ns.adobe.com/xmp/extension/\x00")
+)
+
+// stripJPEGXMP drops the APP1 segments holding XMP. Everything from the start
+// of scan on is copied as is.
+func stripJPEGXMP(data []byte) []byte {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return data
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return data
+		}
+		segment := data[i+4 : end]
+		if marker != 0xE1 || !(bytes.HasPrefix(segment, xmpHeader) || bytes.HasPrefix(segment, xmpExtendedHeader)) {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	return append(out, data[i:]...)
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// stripPNG drops eXIf chunks and the text chunks that carry XMP or an EXIF
+// profile. Chunks carry their own CRC, so the rest of the file stays valid as
+// is.
+func stripPNG(data []byte) []byte {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	for i := len(pngSignature); i+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return data
+		}
+		if !pngMetadata(string(data[i+4:i+8]), data[i+8:end-4]) {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	return out
+}
+
+func pngMetadata(chunkType string, chunk []byte) bool {
+	switch chunkType {
+	case "eXIf":
+		return true
+	case "tEXt", "zTXt", "iTXt":
+		keyword, _, _ := bytes.Cut(chunk, []byte{0})
+		return string(keyword) == "XML:com.adobe.xmp" || bytes.HasPrefix(keyword, []byte("Raw profile type"))
+	}
+	return false
+}
+
+// WebP VP8X flags announcing EXIF and XMP chunks.
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+// stripWebP drops the EXIF and XMP chunks of an extended WebP and clears
+// their flags, fixing up the RIFF size.
+func stripWebP(data []byte) []byte {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:12]...)
+	for i := 12; i < len(data); {
+		if i+8 > len(data) {
+			return data
+		}
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		// Chunks are padded to an even size.
+		end := i + 8 + size + size%2
+		if size < 0 || end > len(data) {
+			return data
+		}
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if size > 0 {
+				out[start+8] &^= webpFlagEXIF | webpFlagXMP
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+// stripGIFXMP drops the XMP application extension of a GIF. GIF has no EXIF.
+func stripGIFXMP(data []byte) []byte {
+	if len(data) < 13 || !(bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))) {
+		return data
+	}
+
+	// Header and logical screen descriptor, then the global color table.
+	i := 13
+	if data[10]&0x80 != 0 {
+		i += 3 << (data[10]&0x07 + 1)
+	}
+	if i > len(data) {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:i]...)
+
+	for i < len(data) {
+		switch data[i] {
+		case 0x3B:
+			return append(out, data[i:]...)
+		case 0x21:
+			if i+2 > len(data) {
+				return data
+			}
+			end, ok := gifSubBlocks(data, i+2)
+			if !ok {
+				return data
+			}
+			if data[i+1] != 0xFF || !bytes.HasPrefix(data[i+2:], []byte("\x0bXMP DataXMP")) {
+				out = append(out, data[i:end]...)
+			}
+			i = end
+		case 0x2C:
+			// Image descriptor, local color table, LZW code size, image data.
+			if i+10 > len(data) {
+				return data
+			}
+			start := i + 10
+			if data[i+9]&0x80 != 0 {
+				start += 3 << (data[i+9]&0x07 + 1)
+			}
+			end, ok := gifSubBlocks(data, start+1)
+			if !ok {
+				return data
+			}
+			out = append(out, data[i:end]...)
+			i = end
+		default:
+			return data
+		}
+	}
+
+	return out
+}
+
+// gifSubBlocks returns the end of the sub-blocks starting at i, past their
+// zero terminator.
+func gifSubBlocks(data []byte, i int) (int, bool) {
+	for i < len(data) {
+		n := int(data[i])
+		i += 1 + n
+		if n == 0 {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,166 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// Images above this many pixels are stored without thumbnails rather than
+// decoded, which keeps small files from expanding into gigabytes of memory.
+// A decoded image takes up to 8 bytes a pixel, so this is at most 200 MB.
+const maxPixels = 25_000_000
+
+// decodeSlots bounds how many images are decoded at once, and with it the
+// memory uploads can take together.
+var decodeSlots = make(chan struct{}, 2)
+
+// Sizes are the thumbnails made for every image, by the longest side in
+// pixels. Images already that small get no thumbnail of that size.
+var Sizes = []struct {
+	Name string
+	Max  int
+}{
+	{Name: "small", Max: 160},
+	{Name: "medium", Max: 640},
+}
+
+type Variant struct {
+	Name        string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+type Info struct {
+	Width    int
+	Height   int
+	Variants []Variant
+}
+
+// Supported reports whether Process understands the content type.
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Process reads the dimensions of an image as it is meant to be displayed,
+// honouring the EXIF orientation, and renders its thumbnails.
+func Process(data []byte, contentType string) (*Info, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	orientation := 1
+	if contentType == "image/jpeg" {
+		if blocks := jpegExif(data); len(blocks) > 0 {
+			orientation = blocks[0].orientation()
+		}
+	}
+	transposed := orientation >= 5
+
+	info := &Info{Width: cfg.Width, Height: cfg.Height}
+	if transposed {
+		info.Width, info.Height = cfg.Height, cfg.Width
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return info, nil
+	}
+
+	var src image.Image
+	for _, size := range Sizes {
+		if info.Width <= size.Max && info.Height <= size.Max {
+			continue
+		}
+		if src == nil {
+			decodeSlots <- struct{}{}
+			defer func() { <-decodeSlots }()
+			if src, _, err = image.Decode(bytes.NewReader(data)); err != nil {
+				return nil, err
+			}
+		}
+
+		w, h := fit(info.Width, info.Height, size.Max)
+		sw, sh := w, h
+		if transposed {
+			sw, sh = h, w
+		}
+		scaled := image.NewRGBA(image.Rect(0, 0, sw, sh))
+		draw.CatmullRom.Scale(scaled, scaled.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+		variant := Variant{Name: size.Name, Width: w, Height: h}
+		var buf bytes.Buffer
+		// JPEG has no transparency, so only JPEG sources get JPEG thumbnails.
+		if contentType == "image/jpeg" {
+			variant.ContentType = "image/jpeg"
+			err = jpeg.Encode(&buf, orient(scaled, orientation), &jpeg.Options{Quality: 80})
+		} else {
+			variant.ContentType = "image/png"
+			err = png.Encode(&buf, orient(scaled, orientation))
+		}
+		if err != nil {
+			return nil, err
+		}
+		variant.Data = buf.Bytes()
+		info.Variants = append(info.Variants, variant)
+	}
+
+	return info, nil
+}
+
+func fit(w, h, limit int) (int, int) {
+	if w >= h {
+		return limit, max(1, h*limit/w)
+	}
+	return max(1, w*limit/h), limit
+}
+
+// orient turns an image stored with the given EXIF orientation upright.
+func orient(img *image.RGBA, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			dst.SetRGBA(x, y, img.RGBAAt(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+
+	return dst
+}
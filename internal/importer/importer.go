@@ -0,0 +1,96 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	FormatNDJSON   = "ndjson"
+	FormatSlack    = "slack"
+	FormatTelegram = "telegram"
+)
+
+var (
+	ErrUnknownFormat = errors.New("unknown import format")
+	ErrTitleRequired = errors.New("chat title is required for this export")
+	// ErrArchiveTooLarge means an archive unpacks to more than MaxArchiveSize
+	// bytes.
+	ErrArchiveTooLarge = errors.New("archive is too large when unpacked")
+)
+
+// Chat is a conversation read from an export. Its messages follow it in the
+// order they appear in the source.
+type Chat struct {
+	Title     string
+	CreatedAt time.Time
+}
+
+type Message struct {
+	// Record locates the message in the source for error reports: a line
+	// number for NDJSON, an index or source id otherwise.
+	Record      string
+	SenderId    string
+	ClientMsgId string
+	Text        string
+	CreatedAt   time.Time
+}
+
+// RecordError describes a record that was skipped. The rest of the import
+// proceeds without it.
+type RecordError struct {
+	Record string `json:"record"`
+	Chat   string `json:"chat,omitempty"`
+	Error  string `json:"error"`
+}
+
+// Sink receives an export as it is read, so that a big one never has to fit
+// in memory: every chat, then its messages, until the next chat or the end.
+// An error from Chat or Message stops the parse and is returned by Parse.
+type Sink interface {
+	Chat(chat Chat) error
+	Message(message Message) error
+	// Skip reports a record that was left out.
+	Skip(err RecordError)
+}
+
+// Parse reads an export in the given format into sink. Title names the chat
+// for formats that do not carry one, such as a single Slack channel file.
+func Parse(format string, r io.Reader, title string, sink Sink) error {
+	s := &stream{sink: sink}
+	switch format {
+	case FormatNDJSON:
+		return parseNDJSON(r, s)
+	case FormatSlack:
+		return parseSlack(r, title, s)
+	case FormatTelegram:
+		return parseTelegram(r, s)
+	}
+	return ErrUnknownFormat
+}
+
+// stream passes records on to a Sink and remembers the chat they belong to
+// for error reports.
+type stream struct {
+	sink  Sink
+	title string
+	open  bool
+}
+
+func (s *stream) chat(chat Chat) error {
+	s.title, s.open = chat.Title, true
+	return s.sink.Chat(chat)
+}
+
+func (s *stream) message(m Message) error {
+	return s.sink.Message(m)
+}
+
+func (s *stream) skip(record, msg string) {
+	rec := RecordError{Record: record, Error: msg}
+	if s.open {
+		rec.Chat = s.title
+	}
+	s.sink.Skip(rec)
+}
@@ -0,0 +1,212 @@
+package importer_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"chats-api/internal/export"
+	"chats-api/internal/importer"
+	"chats-api/internal/model"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// parsedChat is a chat as a sink collects it, with all of its messages.
+type parsedChat struct {
+	importer.Chat
+	Messages []importer.Message
+}
+
+type collector struct {
+	chats []parsedChat
+	errs  []importer.RecordError
+}
+
+func (c *collector) Chat(chat importer.Chat) error {
+	c.chats = append(c.chats, parsedChat{Chat: chat})
+	return nil
+}
+
+func (c *collector) Message(m importer.Message) error {
+	chat := &c.chats[len(c.chats)-1]
+	chat.Messages = append(chat.Messages, m)
+	return nil
+}
+
+func (c *collector) Skip(err importer.RecordError) {
+	c.errs = append(c.errs, err)
+}
+
+func parse(format string, r io.Reader, title string) ([]parsedChat, []importer.RecordError, error) {
+	var c collector
+	err := importer.Parse(format, r, title, &c)
+	return c.chats, c.errs, err
+}
+
+func TestParse_NDJSONRoundTrip(t *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 15, 4, 5, 123456000, time.UTC)
+	clientMsgId := "c-1"
+
+	var buf bytes.Buffer
+	enc, err := export.NewEncoder(export.FormatNDJSON, &buf)
+	require.NoError(t, err)
+	require.NoError(t, enc.Begin(&model.Chat{PublicId: model.NewPublicId(), Title: "Family", CreatedAt: createdAt}))
+	require.NoError(t, enc.Message(&model.Message{Seq: 1, SenderId: "u-1", ClientMsgId: &clientMsgId, Text: "Hello", CreatedAt: createdAt}))
+	require.NoError(t, enc.End())
+	buf.WriteString("{broken\n")
+
+	chats, errs, err := parse(importer.FormatNDJSON, &buf, "")
+	require.NoError(t, err)
+
+	require.Len(t, chats, 1)
+	require.Equal(t, "Family", chats[0].Title)
+	require.Equal(t, []importer.Message{
+		{Record: "line 2", SenderId: "u-1", ClientMsgId: "c-1", Text: "Hello", CreatedAt: createdAt},
+	}, chats[0].Messages)
+
+	require.Len(t, errs, 1)
+	require.Equal(t, "line 3", errs[0].Record)
+	require.Equal(t, "Family", errs[0].Chat)
+}
+
+func TestParse_NDJSONSkipsLongLines(t *testing.T) {
+	input := `{"type":"chat","chat":{"Title":"Family"}}` + "\n" +
+		`{"type":"message","message":{"SenderId":"u-1","Text":"` + strings.Repeat("a", 1<<20) + `"}}` + "\n" +
+		`{"type":"message","message":{"SenderId":"u-1","Text":"short"}}`
+
+	chats, errs, err := parse(importer.FormatNDJSON, strings.NewReader(input), "")
+	require.NoError(t, err)
+	require.Len(t, chats, 1)
+	require.Len(t, chats[0].Messages, 1)
+	require.Equal(t, "short", chats[0].Messages[0].Text)
+	require.Equal(t, "line 3", chats[0].Messages[0].Record)
+	require.Len(t, errs, 1)
+	require.Equal(t, "line 2", errs[0].Record)
+	require.Contains(t, errs[0].Error, "longer than")
+}
+
+const slackChannel = `[
+	{"type": "message", "user": "U1", "text": "Hello", "ts": "1512085950.000216", "client_msg_id": "abc"},
+	{"type": "message", "subtype": "channel_join", "user": "U2", "text": "<@U2> has joined", "ts": "1512085951.000000"},
+	{"type": "message", "bot_id": "B1", "subtype": "bot_message", "text": "Deployed", "ts": "1512085952.5"},
+	{"type": "message", "user": "U1", "text": "bad", "ts": "yesterday"}
+]`
+
+func TestParse_SlackChannelFile(t *testing.T) {
+	_, _, err := parse(importer.FormatSlack, strings.NewReader(slackChannel), "")
+	require.ErrorIs(t, err, importer.ErrTitleRequired)
+
+	chats, errs, err := parse(importer.FormatSlack, strings.NewReader(slackChannel), "general")
+	require.NoError(t, err)
+
+	require.Len(t, chats, 1)
+	require.Equal(t, "general", chats[0].Title)
+	require.Len(t, chats[0].Messages, 2)
+	require.Equal(t, "U1", chats[0].Messages[0].SenderId)
+	require.Equal(t, "abc", chats[0].Messages[0].ClientMsgId)
+	require.Equal(t, time.Date(2017, 11, 30, 23, 52, 30, 216000, time.UTC), chats[0].Messages[0].CreatedAt)
+	require.Equal(t, "B1", chats[0].Messages[1].SenderId)
+	require.Equal(t, "slack:1512085952.5", chats[0].Messages[1].ClientMsgId)
+
+	require.Len(t, errs, 1)
+	require.Equal(t, "message 3", errs[0].Record)
+}
+
+func TestParse_SlackWorkspaceZip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"channels.json":           `[]`,
+		"general/2017-12-02.json": `[{"type": "message", "user": "U1", "text": "second", "ts": "1512172800.000000"}]`,
+		"general/2017-12-01.json": `[{"type": "message", "user": "U1", "text": "first", "ts": "1512086400.000000"}]`,
+		"random/2017-12-01.json":  `[{"type": "message", "user": "U2", "text": "hi", "ts": "1512086400.000000"}]`,
+	} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	chats, errs, err := parse(importer.FormatSlack, &buf, "")
+	require.NoError(t, err)
+	require.Empty(t, errs)
+
+	require.Len(t, chats, 2)
+	require.Equal(t, "general", chats[0].Title)
+	require.Equal(t, "first", chats[0].Messages[0].Text)
+	require.Equal(t, "second", chats[0].Messages[1].Text)
+	require.Equal(t, "random", chats[1].Title)
+}
+
+func TestParse_SlackZipSkipsOversizedFile(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("general/2017-12-01.json")
+	require.NoError(t, err)
+	// Compresses to next to nothing, unpacks past the limit.
+	_, err = w.Write(bytes.Repeat([]byte(" "), 32<<20+1))
+	require.NoError(t, err)
+	w, err = zw.Create("general/2017-12-02.json")
+	require.NoError(t, err)
+	_, err = w.Write([]byte(`[{"type": "message", "user": "U1", "text": "kept", "ts": "1512172800.000000"}]`))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	chats, errs, err := parse(importer.FormatSlack, &buf, "")
+	require.NoError(t, err)
+
+	require.Len(t, errs, 1)
+	require.Equal(t, "general/2017-12-01.json", errs[0].Record)
+	require.Contains(t, errs[0].Error, "larger than")
+	require.Len(t, chats, 1)
+	require.Equal(t, "kept", chats[0].Messages[0].Text)
+}
+
+func TestParse_Telegram(t *testing.T) {
+	const export = `{
+		"name": "Family",
+		"type": "private_group",
+		"messages": [
+			{"id": 1, "type": "service", "date": "2026-01-02T15:04:05", "actor": "Ann", "action": "create_group"},
+			{"id": 2, "type": "message", "date": "2026-01-02T18:04:05", "date_unixtime": "1767366245", "from": "Ann", "from_id": "user1", "text": "Hello"},
+			{"id": 3, "type": "message", "date": "2026-01-02T15:05:00", "from": "Bob", "from_id": "user2",
+				"text": ["See ", {"type": "link", "text": "example.com"}, "!"]},
+			{"id": 4, "type": "message", "date": "not a date", "from_id": "user2", "text": "x"}
+		]
+	}`
+
+	chats, errs, err := parse(importer.FormatTelegram, strings.NewReader(export), "")
+	require.NoError(t, err)
+
+	require.Len(t, chats, 1)
+	require.Equal(t, "Family", chats[0].Title)
+	require.Equal(t, []importer.Message{
+		{Record: "message 2", SenderId: "user1", ClientMsgId: "telegram:2", Text: "Hello", CreatedAt: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)},
+		{Record: "message 3", SenderId: "user2", ClientMsgId: "telegram:3", Text: "See example.com!", CreatedAt: time.Date(2026, 1, 2, 15, 5, 0, 0, time.UTC)},
+	}, chats[0].Messages)
+
+	require.Len(t, errs, 1)
+	require.Equal(t, "message 4", errs[0].Record)
+}
+
+func TestParse_TelegramAccountExport(t *testing.T) {
+	const export = `{"chats": {"list": [
+		{"name": "Ann", "messages": []},
+		{"name": "Work", "messages": [{"id": 1, "type": "message", "date_unixtime": "1767366245", "from_id": "user1", "text": "hi"}]}
+	]}}`
+
+	chats, _, err := parse(importer.FormatTelegram, strings.NewReader(export), "")
+	require.NoError(t, err)
+	require.Len(t, chats, 2)
+	require.Equal(t, "Work", chats[1].Title)
+	require.Len(t, chats[1].Messages, 1)
+}
+
+func TestParse_UnknownFormat(t *testing.T) {
+	_, _, err := parse("xml", strings.NewReader(""), "")
+	require.ErrorIs(t, err, importer.ErrUnknownFormat)
+}
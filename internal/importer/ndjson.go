@@ -0,0 +1,73 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"chats-api/internal/export"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxNDJSONLine bounds a record; a longer line is skipped with an error.
+const maxNDJSONLine = 1 << 20
+
+func parseNDJSON(r io.Reader, s *stream) error {
+	reader := bufio.NewReaderSize(r, maxNDJSONLine)
+	for line := 1; ; line++ {
+		record := "line " + strconv.Itoa(line)
+		b, err := reader.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			s.skip(record, fmt.Sprintf("line is longer than %d bytes", maxNDJSONLine))
+			for errors.Is(err, bufio.ErrBufferFull) {
+				_, err = reader.ReadSlice('\n')
+			}
+			b = nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(bytes.TrimSpace(b)) > 0 {
+			recErr, sinkErr := addNDJSONRecord(s, b, record)
+			if sinkErr != nil {
+				return sinkErr
+			}
+			if recErr != "" {
+				s.skip(record, recErr)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+func addNDJSONRecord(s *stream, b []byte, record string) (string, error) {
+	var rec export.Record
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return "invalid json: " + err.Error(), nil
+	}
+
+	switch {
+	case rec.Type == export.RecordChat && rec.Chat != nil:
+		return "", s.chat(Chat{Title: rec.Chat.Title, CreatedAt: rec.Chat.CreatedAt})
+	case rec.Type == export.RecordMessage && rec.Message != nil:
+		if !s.open {
+			return "message record before any chat record", nil
+		}
+		m := Message{
+			Record:    record,
+			SenderId:  rec.Message.SenderId,
+			Text:      rec.Message.Text,
+			CreatedAt: rec.Message.CreatedAt,
+		}
+		if rec.Message.ClientMsgId != nil {
+			m.ClientMsgId = *rec.Message.ClientMsgId
+		}
+		return "", s.message(m)
+	default:
+		return "unknown record type " + strconv.Quote(rec.Type), nil
+	}
+}
@@ -0,0 +1,230 @@
+package importer
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackMessage is a message from a Slack workspace export, where every
+// channel is a directory of daily JSON files holding arrays of messages.
+type slackMessage struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	User        string `json:"user"`
+	BotId       string `json:"bot_id"`
+	Username    string `json:"username"`
+	Text        string `json:"text"`
+	Ts          string `json:"ts"`
+	ClientMsgId string `json:"client_msg_id"`
+}
+
+// Subtypes carrying user content; the rest are joins, topic changes and
+// other channel events.
+var slackContentSubtypes = map[string]bool{
+	"":                 true,
+	"bot_message":      true,
+	"me_message":       true,
+	"thread_broadcast": true,
+}
+
+var zipMagic = []byte("PK\x03\x04")
+
+// A workspace archive may unpack to far more than it weighs, so its files are
+// read only up to these limits: a larger file is skipped, a larger archive is
+// refused.
+const (
+	maxSlackFileSize = 32 << 20
+	MaxArchiveSize   = 1 << 30
+)
+
+// parseSlack accepts either a whole workspace export as a zip archive or a
+// single channel file, which then needs a title.
+func parseSlack(r io.Reader, title string, s *stream) error {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zipMagic))
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if bytes.Equal(magic, zipMagic) {
+		return parseSlackZip(br, s)
+	}
+
+	if title == "" {
+		return ErrTitleRequired
+	}
+	if err := s.chat(Chat{Title: title}); err != nil {
+		return err
+	}
+	invalid, err := addSlackFile(s, "", br)
+	if err != nil {
+		return err
+	}
+	if invalid != "" {
+		return errors.New(invalid)
+	}
+	return nil
+}
+
+// parseSlackZip spools the archive to a temporary file, as zip needs random
+// access, and reads the channels from there.
+func parseSlackZip(r io.Reader, s *stream) error {
+	tmp, err := os.CreateTemp("", "slack-export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return err
+	}
+	archive, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return err
+	}
+
+	// Daily files are named YYYY-MM-DD.json, so sorting by name keeps each
+	// channel in chronological order.
+	files := make([]*zip.File, 0, len(archive.File))
+	for _, f := range archive.File {
+		dir, name := path.Split(f.Name)
+		if dir == "" || strings.Count(dir, "/") != 1 || path.Ext(name) != ".json" {
+			continue
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	var channel string
+	var total int64
+	for _, f := range files {
+		if dir := strings.TrimSuffix(path.Dir(f.Name), "/"); dir != channel {
+			channel = dir
+			if err := s.chat(Chat{Title: channel}); err != nil {
+				return err
+			}
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, min(maxSlackFileSize, MaxArchiveSize-total)+1))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		total += int64(len(content))
+		if total > MaxArchiveSize {
+			return ErrArchiveTooLarge
+		}
+		if len(content) > maxSlackFileSize {
+			s.skip(f.Name, fmt.Sprintf("file is larger than %d bytes", maxSlackFileSize))
+			continue
+		}
+
+		invalid, err := addSlackFile(s, f.Name, bytes.NewReader(content))
+		if err != nil {
+			return err
+		}
+		if invalid != "" {
+			s.skip(f.Name, invalid)
+		}
+	}
+
+	return nil
+}
+
+// addSlackFile reads the array of messages in a channel file one by one. It
+// reports a file that is not one as invalid; the messages before the broken
+// spot are kept.
+func addSlackFile(s *stream, name string, r io.Reader) (string, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return "invalid slack export: not an array of messages", nil
+	}
+
+	for i := 0; dec.More(); i++ {
+		var m slackMessage
+		if err := dec.Decode(&m); err != nil {
+			return "invalid slack export: " + err.Error(), nil
+		}
+
+		record := "message " + strconv.Itoa(i)
+		if name != "" {
+			record = name + " " + record
+		}
+		if m.Type != "message" || !slackContentSubtypes[m.Subtype] {
+			continue
+		}
+
+		createdAt, err := parseSlackTs(m.Ts)
+		if err != nil {
+			s.skip(record, "invalid ts "+strconv.Quote(m.Ts))
+			continue
+		}
+
+		sender := m.User
+		if sender == "" {
+			sender = m.BotId
+		}
+		if sender == "" {
+			sender = m.Username
+		}
+
+		clientMsgId := m.ClientMsgId
+		if clientMsgId == "" {
+			clientMsgId = "slack:" + m.Ts
+		}
+
+		err = s.message(Message{
+			Record:      record,
+			SenderId:    sender,
+			ClientMsgId: clientMsgId,
+			Text:        m.Text,
+			CreatedAt:   createdAt,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return "invalid slack export: " + err.Error(), nil
+	}
+	return "", nil
+}
+
+// parseSlackTs parses a Slack message timestamp such as "1512085950.000216".
+func parseSlackTs(ts string) (time.Time, error) {
+	secStr, fracStr, _ := strings.Cut(ts, ".")
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var usec int64
+	if fracStr != "" {
+		if len(fracStr) > 6 {
+			fracStr = fracStr[:6]
+		}
+		usec, err = strconv.ParseInt(fracStr+strings.Repeat("0", 6-len(fracStr)), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return time.Unix(sec, usec*1000).UTC(), nil
+}
@@ -0,0 +1,226 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Telegram Desktop "Export chat history" in JSON is one chat object with
+// its name and messages; a full account export wraps many of them in
+// chats.list. Both are read token by token, so only one message at a time is
+// held in memory.
+type telegramMessage struct {
+	Id           int64           `json:"id"`
+	Type         string          `json:"type"`
+	Date         string          `json:"date"`
+	DateUnixtime string          `json:"date_unixtime"`
+	From         string          `json:"from"`
+	FromId       string          `json:"from_id"`
+	Text         json.RawMessage `json:"text"`
+}
+
+func parseTelegram(r io.Reader, s *stream) error {
+	dec := json.NewDecoder(r)
+	if err := telegramChat(dec, s, true); err != nil {
+		return fmt.Errorf("invalid telegram export: %w", err)
+	}
+	return nil
+}
+
+// telegramChat reads a chat object, passing its messages on as they come. The
+// name comes before the messages in Telegram's exports. At the top level the
+// object may be an account export instead, which holds no messages itself.
+func telegramChat(dec *json.Decoder, s *stream, top bool) error {
+	if err := telegramDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	var title string
+	var begun, account bool
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case key == "name":
+			if err := dec.Decode(&title); err != nil {
+				return err
+			}
+		case key == "messages":
+			if err := s.chat(Chat{Title: title}); err != nil {
+				return err
+			}
+			begun = true
+			if err := telegramMessages(dec, s); err != nil {
+				return err
+			}
+		case key == "chats" && top:
+			account = true
+			if err := telegramList(dec, s); err != nil {
+				return err
+			}
+		default:
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+		}
+	}
+	if !begun && !account {
+		if err := s.chat(Chat{Title: title}); err != nil {
+			return err
+		}
+	}
+
+	return telegramDelim(dec, '}')
+}
+
+// telegramList reads the chats.list of an account export.
+func telegramList(dec *json.Decoder, s *stream) error {
+	if err := telegramDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if key != "list" {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := telegramDelim(dec, '['); err != nil {
+			return err
+		}
+		for dec.More() {
+			if err := telegramChat(dec, s, false); err != nil {
+				return err
+			}
+		}
+		if err := telegramDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return telegramDelim(dec, '}')
+}
+
+func telegramMessages(dec *json.Decoder, s *stream) error {
+	if err := telegramDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		var m telegramMessage
+		if err := dec.Decode(&m); err != nil {
+			return err
+		}
+
+		record := "message " + strconv.FormatInt(m.Id, 10)
+		if m.Type != "message" {
+			continue
+		}
+
+		createdAt, err := parseTelegramDate(m)
+		if err != nil {
+			s.skip(record, err.Error())
+			continue
+		}
+		text, err := telegramText(m.Text)
+		if err != nil {
+			s.skip(record, err.Error())
+			continue
+		}
+
+		sender := m.FromId
+		if sender == "" {
+			sender = m.From
+		}
+
+		err = s.message(Message{
+			Record:      record,
+			SenderId:    sender,
+			ClientMsgId: "telegram:" + strconv.FormatInt(m.Id, 10),
+			Text:        text,
+			CreatedAt:   createdAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return telegramDelim(dec, ']')
+}
+
+func telegramDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != want {
+		return fmt.Errorf("expected %v, got %v", want, tok)
+	}
+	return nil
+}
+
+// parseTelegramDate prefers date_unixtime; older exports only have a local
+// date without a zone, which is read as UTC.
+func parseTelegramDate(m telegramMessage) (time.Time, error) {
+	if m.DateUnixtime != "" {
+		sec, err := strconv.ParseInt(m.DateUnixtime, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date_unixtime %q", m.DateUnixtime)
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+
+	t, err := time.Parse("2006-01-02T15:04:05", m.Date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", m.Date)
+	}
+	return t, nil
+}
+
+// telegramText flattens a message text, which is either a string or a list
+// of strings and formatted entities.
+func telegramText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", errors.New("invalid text")
+	}
+
+	var b strings.Builder
+	for _, part := range parts {
+		var str string
+		if err := json.Unmarshal(part, &str); err == nil {
+			b.WriteString(str)
+			continue
+		}
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &entity); err != nil {
+			return "", errors.New("invalid text")
+		}
+		b.WriteString(entity.Text)
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Attachment is a file uploaded to a chat. It stays unattached until a
+// message referencing it is posted. Identical files share one blob.
+type Attachment struct {
+	Id          int64  `gorm:"primary key" json:"-"`
+	PublicId    string `json:"Id"`
+	ChatId      int64  `json:"-"`
+	MessageId   *int64 `json:"-"`
+	UploaderId  string
+	BlobKey     string `json:"-"`
+	FileName    string
+	ContentType string
+	Size        int64
+	Width       *int `json:",omitempty"`
+	Height      *int `json:",omitempty"`
+	CreatedAt   time.Time
+	Url         string               `gorm:"-" json:",omitempty"`
+	Variants    []*AttachmentVariant `gorm:"foreignKey:AttachmentId" json:",omitempty"`
+}
+
+// AttachmentVariant is a file derived from an attachment, such as an image
+// thumbnail.
+type AttachmentVariant struct {
+	Id           int64 `gorm:"primary key" json:"-"`
+	AttachmentId int64 `json:"-"`
+	Name         string
+	BlobKey      string `json:"-"`
+	ContentType  string
+	Width        int
+	Height       int
+	Size         int64
+	Url          string `gorm:"-" json:",omitempty"`
+}
+
+type Blob struct {
+	Key         string `gorm:"primaryKey"`
+	Size        int64
+	ContentType string
+	LastUsedAt  time.Time
+}
+
+func (a *Attachment) BeforeCreate(tx *gorm.DB) error {
+	if a.PublicId == "" {
+		a.PublicId = NewPublicId()
+	}
+	a.CreatedAt = a.CreatedAt.UTC()
+	return nil
+}
+
+func (a *Attachment) AfterFind(tx *gorm.DB) error {
+	a.CreatedAt = a.CreatedAt.UTC()
+	return nil
+}
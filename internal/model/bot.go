@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Bot is an account that programs use to post with an API key. Commands
+// addressed to the bot are forwarded to Endpoint. Only a hash of the key is
+// stored; the key itself is shown once.
+type Bot struct {
+	Id        int64  `gorm:"primary key" json:"-"`
+	PublicId  string `json:"Id"`
+	Name      string
+	Endpoint  *string      `json:",omitempty"`
+	Secret    string       `json:",omitempty"`
+	KeyHash   string       `json:"-"`
+	ApiKey    string       `gorm:"-" json:",omitempty"`
+	Commands  []BotCommand `gorm:"foreignKey:BotId"`
+	CreatedAt time.Time
+}
+
+type BotCommand struct {
+	Command     string `gorm:"primaryKey"`
+	BotId       int64  `json:"-"`
+	Description string `json:",omitempty"`
+}
+
+// SenderId is the author of the bot's messages and uploads.
+func (b *Bot) SenderId() string {
+	return "bot:" + b.PublicId
+}
+
+func (b *Bot) BeforeCreate(tx *gorm.DB) error {
+	if b.PublicId == "" {
+		b.PublicId = NewPublicId()
+	}
+	b.CreatedAt = b.CreatedAt.UTC()
+	return nil
+}
+
+func (b *Bot) AfterFind(tx *gorm.DB) error {
+	b.CreatedAt = b.CreatedAt.UTC()
+	return nil
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ChangeEntityChat    = "chat"
+	ChangeEntityMessage = "message"
+
+	ChangeCreated = "created"
+	ChangeUpdated = "updated"
+	ChangeDeleted = "deleted"
+)
+
+type Change struct {
+	Id int64 `gorm:"primary key"`
+	// Position orders changes for sync. It is given once the change has
+	// committed, so it is zero until then.
+	Position        int64 `gorm:"->"`
+	Entity          string
+	Action          string
+	ChatId          int64
+	ChatPublicId    string
+	MessageId       *int64
+	MessagePublicId *string
+	CreatedAt       time.Time
+}
+
+func (c *Change) AfterFind(tx *gorm.DB) error {
+	c.CreatedAt = c.CreatedAt.UTC()
+	return nil
+}
@@ -1,9 +1,71 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ChatGroup     = "group"
+	ChatDirect    = "direct"
+	ChatBroadcast = "broadcast"
+)
 
 type Chat struct {
-	Id        int `gorm:"primary key"`
-	Title     string
+	Id       int64  `gorm:"primary key" json:"-"`
+	PublicId string `json:"Id"`
+	Title    string
+	// Type is ChatGroup, ChatDirect between two users, whose chats have no
+	// title, or ChatBroadcast, where only admins post.
+	Type      string
+	DirectKey *string `json:"-"`
+	Topic     string  `json:",omitempty"`
+	LastSeq   int64
+	Retention RetentionPolicy `gorm:"embedded;embeddedPrefix:retention_"`
+	// ExpiresIn is how many seconds new messages of the chat live; nil keeps
+	// them.
+	ExpiresIn *int64 `json:",omitempty"`
 	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt `json:",omitzero"`
+	// UnreadCount is set in listings for the user asking.
+	UnreadCount *int64 `gorm:"->;column:unread_count" json:",omitempty"`
+}
+
+// RetentionPolicy limits how long messages of a chat are kept. Nil limits
+// mean messages are kept forever.
+type RetentionPolicy struct {
+	MaxAgeSeconds *int64
+	MaxMessages   *int64
+}
+
+func (p RetentionPolicy) IsSet() bool {
+	return p.MaxAgeSeconds != nil || p.MaxMessages != nil
+}
+
+func (c *Chat) BeforeCreate(tx *gorm.DB) error {
+	if c.PublicId == "" {
+		c.PublicId = NewPublicId()
+	}
+	if c.Type == "" {
+		c.Type = ChatGroup
+	}
+	c.CreatedAt = c.CreatedAt.UTC()
+	return nil
+}
+
+func (c *Chat) AfterFind(tx *gorm.DB) error {
+	c.CreatedAt = c.CreatedAt.UTC()
+	c.DeletedAt.Time = c.DeletedAt.Time.UTC()
+	return nil
+}
+
+// DirectKey names the pair of users of a direct chat, whichever of them opens
+// it.
+func DirectKey(userId, otherId string) string {
+	if otherId < userId {
+		userId, otherId = otherId, userId
+	}
+	// User ids cannot hold control characters, so the pair is unambiguous.
+	return userId + "\n" + otherId
 }
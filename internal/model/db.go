@@ -4,6 +4,8 @@ import (
 	"chats-api/internal/config"
 	"errors"
 	"fmt"
+	"time"
+
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -13,17 +15,28 @@ type PostgresDB struct {
 }
 
 func NewDB(conf *config.PostgresConf) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=%s",
 		conf.Host,
 		conf.User,
 		conf.Password,
 		conf.DbName,
 		conf.Port,
+		conf.TimeZone,
 	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		NowFunc:        Now,
+	})
 	if err != nil {
 		return nil, errors.New("error connecting to db: " + err.Error())
 	}
 
 	return db, nil
 }
+
+// Now returns the current time in UTC, truncated to the microsecond precision
+// Postgres stores, so a freshly created row serializes the same way as when it
+// is read back.
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// Event describes a change for consumers outside the API: webhooks, the
+// message broker and real-time subscribers. Id is the same on every delivery,
+// the change id for changes, so consumers use it to drop duplicates.
+type Event struct {
+	Id        string       `json:"id"`
+	Type      string       `json:"type"`
+	CreatedAt time.Time    `json:"created_at"`
+	ChatId    string       `json:"chat_id"`
+	MessageId string       `json:"message_id,omitempty"`
+	Chat      *Chat        `json:"chat,omitempty"`
+	Message   *Message     `json:"message,omitempty"`
+	Read      *ReadReceipt `json:"read,omitempty"`
+}
+
+// NewEvent describes the change with a snapshot of the chat or message it
+// touched; entity is nil for deletions.
+func NewEvent(c *Change, entity any) *Event {
+	event := &Event{
+		Id:        strconv.FormatInt(c.Id, 10),
+		Type:      c.EventType(),
+		CreatedAt: c.CreatedAt.UTC(),
+		ChatId:    c.ChatPublicId,
+	}
+	if c.MessagePublicId != nil {
+		event.MessageId = *c.MessagePublicId
+	}
+
+	switch e := entity.(type) {
+	case *Chat:
+		event.Chat = e
+	case *Message:
+		event.Message = e
+	}
+	return event
+}
+
+// EventType names the event of a change, e.g. "message.created".
+func (c *Change) EventType() string {
+	return c.Entity + "." + c.Action
+}
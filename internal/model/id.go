@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewPublicId returns a UUIDv7. Internal bigint keys never leave the service;
+// clients only ever see these time-ordered, non-enumerable ids.
+func NewPublicId() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+func IsPublicId(s string) bool {
+	_, err := uuid.Parse(s)
+	return err == nil && len(s) == 36
+}
+
+// NewPublicIdAt returns a UUIDv7 whose timestamp is t, so records imported
+// with their original creation time sort among the ones created natively.
+func NewPublicIdAt(t time.Time) string {
+	id := uuid.Must(uuid.NewV7())
+	ms := uint64(t.UnixMilli())
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	return id.String()
+}
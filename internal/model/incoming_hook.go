@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IncomingHook lets an external system post to one chat with a secret
+// token. Only a hash of the token is stored; the token itself is shown once.
+type IncomingHook struct {
+	Id           int64  `gorm:"primary key" json:"-"`
+	PublicId     string `json:"Id"`
+	ChatId       int64  `json:"-"`
+	ChatPublicId string `gorm:"->;column:chat_public_id" json:"-"`
+	Name         string
+	TokenHash    string `json:"-"`
+	Token        string `gorm:"-" json:",omitempty"`
+	CreatedBy    string
+	CreatedAt    time.Time
+	LastUsedAt   *time.Time
+	RevokedAt    *time.Time `json:",omitempty"`
+}
+
+// SenderId is the author of the hook's messages and uploads.
+func (h *IncomingHook) SenderId() string {
+	return "hook:" + h.PublicId
+}
+
+func (h *IncomingHook) BeforeCreate(tx *gorm.DB) error {
+	if h.PublicId == "" {
+		h.PublicId = NewPublicId()
+	}
+	h.CreatedAt = h.CreatedAt.UTC()
+	return nil
+}
+
+func (h *IncomingHook) AfterFind(tx *gorm.DB) error {
+	h.CreatedAt = h.CreatedAt.UTC()
+	return nil
+}
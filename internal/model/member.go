@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// ChatMember gives a user a role in a chat. Whoever creates a chat becomes
+// its first admin.
+type ChatMember struct {
+	ChatId    int64  `gorm:"primaryKey" json:"-"`
+	UserId    string `gorm:"primaryKey"`
+	Role      string
+	CreatedAt time.Time
+}
+
+func (m *ChatMember) AfterFind(tx *gorm.DB) error {
+	m.CreatedAt = m.CreatedAt.UTC()
+	return nil
+}
@@ -1,10 +1,61 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SystemSenderId authors the messages the service posts itself.
+const SystemSenderId = "system"
+
+// IsReservedSenderId reports whether id names the service, a bot or an
+// incoming hook rather than a user.
+func IsReservedSenderId(id string) bool {
+	return id == SystemSenderId || strings.HasPrefix(id, "bot:") || strings.HasPrefix(id, "hook:")
+}
 
 type Message struct {
-	Id        int `gorm:"primary key"`
-	ChatId    int
-	Text      string
-	CreatedAt time.Time
+	Id           int64  `gorm:"primary key" json:"-"`
+	PublicId     string `json:"Id"`
+	ChatId       int64  `json:"-"`
+	ChatPublicId string `gorm:"->;column:chat_public_id" json:"ChatId"`
+	Seq          int64
+	SenderId     string
+	SenderName   *string `json:",omitempty"`
+	ClientMsgId  *string
+	Text         string
+	CreatedAt    time.Time
+	Attachments  []*Attachment `gorm:"foreignKey:MessageId" json:",omitempty"`
+	// ReplyTo is the public id of the message this one answers.
+	ReplyTo  *string    `json:",omitempty"`
+	Mentions []Mention  `gorm:"serializer:json" json:",omitempty"`
+	PinnedAt *time.Time `json:",omitempty"`
+	PinnedBy *string    `json:",omitempty"`
+	// ExpiresAt is when the message disappears.
+	ExpiresAt *time.Time `json:",omitempty"`
+	// ReadCount is how many users other than the sender have read the
+	// message; ReadBy lists the first of them by user id.
+	ReadCount int64    `gorm:"-" json:",omitempty"`
+	ReadBy    []string `gorm:"-" json:",omitempty"`
+}
+
+func (m *Message) BeforeCreate(tx *gorm.DB) error {
+	if m.PublicId == "" {
+		m.PublicId = NewPublicId()
+	}
+	m.CreatedAt = m.CreatedAt.UTC()
+	return nil
+}
+
+func (m *Message) AfterFind(tx *gorm.DB) error {
+	m.CreatedAt = m.CreatedAt.UTC()
+	if m.PinnedAt != nil {
+		*m.PinnedAt = m.PinnedAt.UTC()
+	}
+	if m.ExpiresAt != nil {
+		*m.ExpiresAt = m.ExpiresAt.UTC()
+	}
+	return nil
 }
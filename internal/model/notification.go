@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MentionUser = "user"
+	MentionAll  = "all"
+	MentionHere = "here"
+)
+
+// Mention is an @mention in the text of a message: of one user, of everyone
+// in the chat (@all) or of everyone online in it (@here). Offset and Length
+// count characters.
+type Mention struct {
+	Type   string
+	UserId string `json:",omitempty"`
+	Offset int
+	Length int
+}
+
+const (
+	NotificationMention = "mention"
+	NotificationReply   = "reply"
+)
+
+// Notification tells a user about a message that mentions them or replies to
+// their message.
+type Notification struct {
+	Id           int64    `gorm:"primary key" json:"-"`
+	PublicId     string   `json:"Id"`
+	UserId       string   `json:"-"`
+	ChatId       int64    `json:"-"`
+	ChatPublicId string   `gorm:"->;column:chat_public_id" json:"ChatId"`
+	MessageId    int64    `json:"-"`
+	Message      *Message `gorm:"-"`
+	Kind         string
+	CreatedAt    time.Time
+	ReadAt       *time.Time
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.PublicId == "" {
+		n.PublicId = NewPublicId()
+	}
+	return nil
+}
+
+func (n *Notification) AfterFind(tx *gorm.DB) error {
+	n.CreatedAt = n.CreatedAt.UTC()
+	if n.ReadAt != nil {
+		*n.ReadAt = n.ReadAt.UTC()
+	}
+	return nil
+}
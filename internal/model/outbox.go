@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxEvent is an event waiting to be published to the message broker. It
+// is written in the transaction of the change it describes, so an event is
+// never lost nor published for a change that was rolled back.
+type OutboxEvent struct {
+	Id int64 `gorm:"primary key"`
+	// Position orders events for readers; see Change.Position.
+	Position    int64 `gorm:"->"`
+	Type        string
+	Key         string
+	Payload     string
+	CreatedAt   time.Time
+	PublishedAt *time.Time
+}
+
+func (e *OutboxEvent) AfterFind(tx *gorm.DB) error {
+	e.CreatedAt = e.CreatedAt.UTC()
+	return nil
+}
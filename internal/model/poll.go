@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Poll struct {
+	Id        int64 `gorm:"primary key"`
+	ChatId    int64
+	Question  string
+	Options   []string `gorm:"serializer:json"`
+	CreatedBy string
+	CreatedAt time.Time
+}
+
+func (p *Poll) AfterFind(tx *gorm.DB) error {
+	p.CreatedAt = p.CreatedAt.UTC()
+	return nil
+}
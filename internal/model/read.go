@@ -0,0 +1,41 @@
+package model
+
+import (
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReadReceipt is how far a user has read a chat: every message with a seq up
+// to LastReadSeq.
+type ReadReceipt struct {
+	ChatId       int64  `gorm:"primaryKey" json:"-"`
+	ChatPublicId string `gorm:"->;column:chat_public_id" json:"ChatId"`
+	UserId       string `gorm:"primaryKey"`
+	LastReadSeq  int64
+	UpdatedAt    time.Time
+}
+
+func (ReadReceipt) TableName() string {
+	return "chat_reads"
+}
+
+func (r *ReadReceipt) AfterFind(tx *gorm.DB) error {
+	r.UpdatedAt = r.UpdatedAt.UTC()
+	return nil
+}
+
+const EventChatRead = "chat.read"
+
+// NewReadEvent describes a user reading a chat up to a message. The id is
+// the same for the same receipt, so consumers can drop duplicates.
+func NewReadEvent(r *ReadReceipt) *Event {
+	return &Event{
+		Id:        "read:" + r.ChatPublicId + ":" + r.UserId + ":" + strconv.FormatInt(r.LastReadSeq, 10),
+		Type:      EventChatRead,
+		CreatedAt: r.UpdatedAt.UTC(),
+		ChatId:    r.ChatPublicId,
+		Read:      r,
+	}
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScheduledMessage is a message its sender wants posted at SendAt. Once
+// posted it becomes a Message whose ClientMsgId is the ClientMsgId given when
+// scheduling or, without one, the public id of the scheduled message.
+type ScheduledMessage struct {
+	Id           int64  `gorm:"primary key" json:"-"`
+	PublicId     string `json:"Id"`
+	ChatId       int64  `json:"-"`
+	ChatPublicId string `gorm:"->;column:chat_public_id" json:"ChatId"`
+	SenderId     string
+	SenderName   *string `json:",omitempty"`
+	ClientMsgId  *string
+	Text         string
+	ReplyTo      *string `json:",omitempty"`
+	// ExpiresIn is how many seconds the message lives once posted.
+	ExpiresIn    *int64 `json:",omitempty"`
+	SendAt       time.Time
+	ClaimedUntil *time.Time `json:"-"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (m *ScheduledMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.PublicId == "" {
+		m.PublicId = NewPublicId()
+	}
+	return nil
+}
+
+func (m *ScheduledMessage) AfterFind(tx *gorm.DB) error {
+	m.SendAt = m.SendAt.UTC()
+	m.CreatedAt = m.CreatedAt.UTC()
+	m.UpdatedAt = m.UpdatedAt.UTC()
+	return nil
+}
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint subscribed to the events of one chat, or of all
+// chats when ChatId is nil. Empty Events means every event type.
+type Webhook struct {
+	Id                  int64   `gorm:"primary key" json:"-"`
+	PublicId            string  `json:"Id"`
+	ChatId              *int64  `json:"-"`
+	ChatPublicId        *string `gorm:"->;column:chat_public_id" json:"ChatId"`
+	Url                 string
+	Secret              string   `json:",omitempty"`
+	Events              []string `gorm:"serializer:json"`
+	ConsecutiveFailures int
+	CreatedAt           time.Time
+	DisabledAt          *time.Time
+}
+
+// WebhookDelivery is one event queued for one webhook. Payload is the exact
+// body sent on every attempt.
+type WebhookDelivery struct {
+	Id            int64  `gorm:"primary key" json:"-"`
+	PublicId      string `json:"Id"`
+	WebhookId     int64  `json:"-"`
+	ChangeId      int64  `json:"-"`
+	EventType     string
+	Payload       string `json:"-"`
+	Status        string
+	Attempts      int
+	NextAttemptAt *time.Time
+	LastError     string
+	CreatedAt     time.Time
+	DeliveredAt   *time.Time
+	Webhook       *Webhook          `json:"-"`
+	AttemptLog    []*WebhookAttempt `gorm:"foreignKey:DeliveryId" json:"AttemptLog"`
+}
+
+type WebhookAttempt struct {
+	Id         int64 `gorm:"primary key" json:"-"`
+	DeliveryId int64 `json:"-"`
+	StatusCode *int
+	Error      string
+	DurationMs int64
+	CreatedAt  time.Time
+}
+
+func (w *Webhook) BeforeCreate(tx *gorm.DB) error {
+	if w.PublicId == "" {
+		w.PublicId = NewPublicId()
+	}
+	w.CreatedAt = w.CreatedAt.UTC()
+	return nil
+}
+
+func (w *Webhook) AfterFind(tx *gorm.DB) error {
+	w.CreatedAt = w.CreatedAt.UTC()
+	if w.DisabledAt != nil {
+		t := w.DisabledAt.UTC()
+		w.DisabledAt = &t
+	}
+	return nil
+}
+
+// Matches reports whether the webhook wants the change.
+func (w *Webhook) Matches(change *Change) bool {
+	if w.DisabledAt != nil || (w.ChatId != nil && *w.ChatId != change.ChatId) {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == change.EventType() {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.PublicId == "" {
+		d.PublicId = NewPublicId()
+	}
+	d.CreatedAt = d.CreatedAt.UTC()
+	return nil
+}
+
+func (d *WebhookDelivery) AfterFind(tx *gorm.DB) error {
+	d.CreatedAt = d.CreatedAt.UTC()
+	return nil
+}
+
+func (a *WebhookAttempt) AfterFind(tx *gorm.DB) error {
+	a.CreatedAt = a.CreatedAt.UTC()
+	return nil
+}
@@ -0,0 +1,173 @@
+// Package realtime fans events out to the clients connected to this process.
+package realtime
+
+import (
+	"expvar"
+	"sync"
+)
+
+var (
+	clientsConnected = expvar.NewInt("realtime_clients")
+	eventsSent       = expvar.NewInt("realtime_events_sent_total")
+	clientsDropped   = expvar.NewInt("realtime_clients_dropped_total")
+)
+
+// Hub keeps the connected clients and the chats each one follows.
+type Hub struct {
+	mu         sync.RWMutex
+	bufferSize int
+	chats      map[string]map[*Client]struct{}
+}
+
+// Client is one connection. Frames for it queue in Send; a client that falls
+// bufferSize frames behind is dropped and Done is closed.
+type Client struct {
+	UserId string
+
+	send  chan []byte
+	done  chan struct{}
+	once  sync.Once
+	chats map[string]struct{}
+}
+
+func NewHub(bufferSize int) *Hub {
+	return &Hub{bufferSize: bufferSize, chats: map[string]map[*Client]struct{}{}}
+}
+
+func (h *Hub) Register(userId string) *Client {
+	clientsConnected.Add(1)
+	return &Client{
+		UserId: userId,
+		send:   make(chan []byte, h.bufferSize),
+		done:   make(chan struct{}),
+		chats:  map[string]struct{}{},
+	}
+}
+
+// Unregister forgets the client and closes it.
+func (h *Hub) Unregister(c *Client) {
+	h.mu.Lock()
+	for chatId := range c.chats {
+		h.unsubscribe(c, chatId)
+	}
+	h.mu.Unlock()
+
+	c.close()
+}
+
+func (h *Hub) Subscribe(c *Client, chatId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscribers, ok := h.chats[chatId]
+	if !ok {
+		subscribers = map[*Client]struct{}{}
+		h.chats[chatId] = subscribers
+	}
+	subscribers[c] = struct{}{}
+	c.chats[chatId] = struct{}{}
+}
+
+func (h *Hub) Unsubscribe(c *Client, chatId string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.unsubscribe(c, chatId)
+}
+
+func (h *Hub) unsubscribe(c *Client, chatId string) {
+	delete(c.chats, chatId)
+	if subscribers, ok := h.chats[chatId]; ok {
+		delete(subscribers, c)
+		if len(subscribers) == 0 {
+			delete(h.chats, chatId)
+		}
+	}
+}
+
+// Subscribed reports whether the client follows the chat.
+func (h *Hub) Subscribed(c *Client, chatId string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	_, ok := c.chats[chatId]
+	return ok
+}
+
+// Publish queues the frame for every client following the chat except skip,
+// which may be nil. It never blocks: slow clients are dropped instead.
+func (h *Hub) Publish(chatId string, frame []byte, skip *Client) {
+	h.publish(chatId, frame, func(c *Client) bool { return c == skip })
+}
+
+// PublishOthers queues the frame for the clients following the chat that do
+// not belong to userId.
+func (h *Hub) PublishOthers(chatId, userId string, frame []byte) {
+	h.publish(chatId, frame, func(c *Client) bool { return c.UserId == userId })
+}
+
+func (h *Hub) publish(chatId string, frame []byte, skip func(*Client) bool) {
+	var slow []*Client
+
+	h.mu.RLock()
+	for c := range h.chats[chatId] {
+		if skip(c) {
+			continue
+		}
+		select {
+		case c.send <- frame:
+			eventsSent.Add(1)
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, c := range slow {
+		clientsDropped.Add(1)
+		h.Unregister(c)
+	}
+}
+
+// Chats lists the chats the client follows.
+func (h *Hub) Chats(c *Client) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	chats := make([]string, 0, len(c.chats))
+	for chatId := range c.chats {
+		chats = append(chats, chatId)
+	}
+	return chats
+}
+
+// UserSubscribed reports whether any client of the user other than skip
+// follows the chat.
+func (h *Hub) UserSubscribed(chatId, userId string, skip *Client) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.chats[chatId] {
+		if c != skip && c.UserId == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// Send is where the connection picks up the frames to write.
+func (c *Client) Send() <-chan []byte {
+	return c.send
+}
+
+// Done is closed once the client is unregistered.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) close() {
+	c.once.Do(func() {
+		clientsConnected.Add(-1)
+		close(c.done)
+	})
+}
@@ -0,0 +1,44 @@
+package realtime_test
+
+import (
+	"chats-api/internal/realtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_PublishReachesSubscribers(t *testing.T) {
+	hub := realtime.NewHub(4)
+	alice, bob := hub.Register("alice"), hub.Register("bob")
+	hub.Subscribe(alice, "c-1")
+	hub.Subscribe(bob, "c-1")
+	hub.Subscribe(bob, "c-2")
+
+	hub.Publish("c-1", []byte("one"), alice)
+	hub.Publish("c-2", []byte("two"), nil)
+
+	require.Empty(t, alice.Send())
+	require.Equal(t, []byte("one"), <-bob.Send())
+	require.Equal(t, []byte("two"), <-bob.Send())
+
+	hub.Unsubscribe(bob, "c-2")
+	require.False(t, hub.Subscribed(bob, "c-2"))
+	hub.Publish("c-2", []byte("three"), nil)
+	require.Empty(t, bob.Send())
+}
+
+func TestHub_DropsSlowClients(t *testing.T) {
+	hub := realtime.NewHub(1)
+	slow := hub.Register("alice")
+	hub.Subscribe(slow, "c-1")
+
+	hub.Publish("c-1", []byte("one"), nil)
+	hub.Publish("c-1", []byte("two"), nil)
+
+	select {
+	case <-slow.Done():
+	default:
+		t.Fatal("slow client was not dropped")
+	}
+	require.False(t, hub.Subscribed(slow, "c-1"))
+}
@@ -0,0 +1,175 @@
+package realtime
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	SignalTyping   = "typing"
+	SignalPresence = "presence"
+
+	StatusTyping  = "typing"
+	StatusStopped = "stopped"
+	StatusOnline  = "online"
+	StatusOffline = "offline"
+)
+
+// Signal is a typing or presence update. Signals live only in memory: they
+// are never stored, published to the broker or replayed by sync.
+type Signal struct {
+	Type   string `json:"type"`
+	ChatId string `json:"chat_id"`
+	UserId string `json:"user_id"`
+	Status string `json:"status"`
+}
+
+type signalKey struct {
+	kind   string
+	userId string
+}
+
+// Presence tracks who is typing and who is online in each chat of this
+// process. A state lasts for its TTL unless the client renews it, so a client
+// that vanished without a goodbye expires on its own. Changes are fanned out
+// to the other users following the chat on this process only: presence is
+// not written to the database or the broker, so instances do not see each
+// other's typing and online users.
+type Presence struct {
+	hub       *Hub
+	typingTTL time.Duration
+	onlineTTL time.Duration
+
+	mu    sync.Mutex
+	chats map[string]map[signalKey]time.Time
+}
+
+func NewPresence(hub *Hub, typingTTL, onlineTTL time.Duration) *Presence {
+	return &Presence{
+		hub:       hub,
+		typingTTL: typingTTL,
+		onlineTTL: onlineTTL,
+		chats:     map[string]map[signalKey]time.Time{},
+	}
+}
+
+// Typing starts or stops the user's typing indicator in the chat. Clients
+// repeat it while the user keeps typing.
+func (p *Presence) Typing(chatId, userId string, typing bool, now time.Time) {
+	p.set(chatId, signalKey{SignalTyping, userId}, typing, p.typingTTL, now)
+}
+
+// SetOnline marks the user online or offline in the chat. Clients repeat it
+// as a heartbeat while the user is around.
+func (p *Presence) SetOnline(chatId, userId string, online bool, now time.Time) {
+	p.set(chatId, signalKey{SignalPresence, userId}, online, p.onlineTTL, now)
+}
+
+// Leave clears the client's user from the chat unless another client of the
+// same user still follows it.
+func (p *Presence) Leave(c *Client, chatId string, now time.Time) {
+	if p.hub.UserSubscribed(chatId, c.UserId, c) {
+		return
+	}
+	p.Typing(chatId, c.UserId, false, now)
+	p.SetOnline(chatId, c.UserId, false, now)
+}
+
+// Disconnect is Leave for every chat the client follows.
+func (p *Presence) Disconnect(c *Client, now time.Time) {
+	for _, chatId := range p.hub.Chats(c) {
+		p.Leave(c, chatId, now)
+	}
+}
+
+// Online lists the users online in the chat, sorted.
+func (p *Presence) Online(chatId string, now time.Time) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	users := []string{}
+	for key, expires := range p.chats[chatId] {
+		if key.kind == SignalPresence && now.Before(expires) {
+			users = append(users, key.userId)
+		}
+	}
+	slices.Sort(users)
+	return users
+}
+
+// Expire drops the states whose TTL ran out, tells the chats about it and
+// returns how many it dropped.
+func (p *Presence) Expire(now time.Time) int {
+	type expired struct {
+		chatId string
+		key    signalKey
+	}
+	var dropped []expired
+
+	p.mu.Lock()
+	for chatId, states := range p.chats {
+		for key, expires := range states {
+			if !now.Before(expires) {
+				dropped = append(dropped, expired{chatId, key})
+				delete(states, key)
+			}
+		}
+		if len(states) == 0 {
+			delete(p.chats, chatId)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, e := range dropped {
+		p.publish(e.chatId, e.key, false)
+	}
+	return len(dropped)
+}
+
+// set changes a state and announces it when it turns on or off; renewing an
+// active state only moves its expiry.
+func (p *Presence) set(chatId string, key signalKey, on bool, ttl time.Duration, now time.Time) {
+	p.mu.Lock()
+	states, ok := p.chats[chatId]
+	if !ok {
+		states = map[signalKey]time.Time{}
+		p.chats[chatId] = states
+	}
+	expires, exists := states[key]
+	// An expired state the sweeper has not dropped yet was announced but
+	// never withdrawn, so turning it off is still news.
+	changed := exists
+	if on {
+		changed = !exists || !now.Before(expires)
+		states[key] = now.Add(ttl)
+	} else {
+		delete(states, key)
+	}
+	if len(states) == 0 {
+		delete(p.chats, chatId)
+	}
+	p.mu.Unlock()
+
+	if changed {
+		p.publish(chatId, key, on)
+	}
+}
+
+func (p *Presence) publish(chatId string, key signalKey, on bool) {
+	signal := Signal{Type: key.kind, ChatId: chatId, UserId: key.userId}
+	switch {
+	case key.kind == SignalTyping && on:
+		signal.Status = StatusTyping
+	case key.kind == SignalTyping:
+		signal.Status = StatusStopped
+	case on:
+		signal.Status = StatusOnline
+	default:
+		signal.Status = StatusOffline
+	}
+
+	frame, _ := json.Marshal(signal)
+	p.hub.PublishOthers(chatId, key.userId, frame)
+}
@@ -0,0 +1,77 @@
+package realtime_test
+
+import (
+	"chats-api/internal/realtime"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, c *realtime.Client) realtime.Signal {
+	t.Helper()
+
+	var signal realtime.Signal
+	require.NoError(t, json.Unmarshal(<-c.Send(), &signal))
+	return signal
+}
+
+func TestPresence_AnnouncesChangesToOtherUsers(t *testing.T) {
+	hub := realtime.NewHub(8)
+	presence := realtime.NewPresence(hub, 5*time.Second, time.Minute)
+	alice, bob := hub.Register("alice"), hub.Register("bob")
+	hub.Subscribe(alice, "c-1")
+	hub.Subscribe(bob, "c-1")
+	now := time.Now()
+
+	presence.Typing("c-1", "bob", true, now)
+	presence.Typing("c-1", "bob", true, now.Add(time.Second))
+
+	require.Equal(t, realtime.Signal{Type: "typing", ChatId: "c-1", UserId: "bob", Status: "typing"}, receive(t, alice))
+	require.Empty(t, alice.Send(), "renewing is not announced")
+	require.Empty(t, bob.Send(), "users do not hear themselves")
+
+	presence.Typing("c-1", "bob", false, now.Add(2*time.Second))
+	presence.Typing("c-1", "bob", false, now.Add(3*time.Second))
+	require.Equal(t, "stopped", receive(t, alice).Status)
+	require.Empty(t, alice.Send())
+}
+
+func TestPresence_Expires(t *testing.T) {
+	hub := realtime.NewHub(8)
+	presence := realtime.NewPresence(hub, 5*time.Second, time.Minute)
+	alice := hub.Register("alice")
+	hub.Subscribe(alice, "c-1")
+	now := time.Now()
+
+	presence.SetOnline("c-1", "bob", true, now)
+	presence.Typing("c-1", "bob", true, now)
+	receive(t, alice)
+	receive(t, alice)
+
+	require.Equal(t, 1, presence.Expire(now.Add(10*time.Second)))
+	require.Equal(t, "stopped", receive(t, alice).Status)
+	require.Equal(t, []string{"bob"}, presence.Online("c-1", now.Add(10*time.Second)))
+
+	require.Empty(t, presence.Online("c-1", now.Add(2*time.Minute)), "expired before the sweep")
+	require.Equal(t, 1, presence.Expire(now.Add(2*time.Minute)))
+	require.Equal(t, "offline", receive(t, alice).Status)
+}
+
+func TestPresence_LeaveKeepsUsersWithAnotherClient(t *testing.T) {
+	hub := realtime.NewHub(8)
+	presence := realtime.NewPresence(hub, 5*time.Second, time.Minute)
+	phone, laptop := hub.Register("bob"), hub.Register("bob")
+	hub.Subscribe(phone, "c-1")
+	hub.Subscribe(laptop, "c-1")
+	now := time.Now()
+
+	presence.SetOnline("c-1", "bob", true, now)
+	presence.Disconnect(phone, now)
+	hub.Unregister(phone)
+	require.Equal(t, []string{"bob"}, presence.Online("c-1", now))
+
+	presence.Disconnect(laptop, now)
+	require.Empty(t, presence.Online("c-1", now))
+}
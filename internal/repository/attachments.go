@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type attachmentsRepo struct {
+	db *gorm.DB
+}
+
+func NewAttachmentsRepo(db *gorm.DB) AttachmentsRepository {
+	return &attachmentsRepo{db: db}
+}
+
+// TouchBlob registers the blob or marks an existing one as just used, which
+// keeps it from being cleaned up while an upload referencing it is under way.
+func (r *attachmentsRepo) TouchBlob(ctx context.Context, blob *model.Blob) error {
+	return r.db.WithContext(ctx).Exec(`INSERT INTO blobs (key, size, content_type, last_used_at) VALUES (?, ?, ?, now())
+		ON CONFLICT (key) DO UPDATE SET last_used_at = now()`,
+		blob.Key, blob.Size, blob.ContentType).Error
+}
+
+func (r *attachmentsRepo) Create(ctx context.Context, attachment *model.Attachment) error {
+	return r.db.WithContext(ctx).Create(attachment).Error
+}
+
+func (r *attachmentsRepo) Get(ctx context.Context, publicId string) (*model.Attachment, error) {
+	var attachment model.Attachment
+
+	result := r.db.WithContext(ctx).
+		Preload("Variants").
+		Joins("JOIN chats ON chats.id = attachments.chat_id AND chats.deleted_at IS NULL").
+		Where("attachments.public_id = ?", publicId).
+		Limit(1).
+		Find(&attachment)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return &attachment, nil
+}
+
+func (r *attachmentsRepo) DeleteUnattached(ctx context.Context, createdBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("message_id IS NULL AND created_at < ?", createdBefore).
+		Delete(&model.Attachment{})
+
+	return result.RowsAffected, result.Error
+}
+
+// DeleteUnusedBlobs forgets blobs no attachment refers to any more. The rows
+// stay locked while remove deletes the content, so a concurrent upload of the
+// same file waits and then stores it again.
+func (r *attachmentsRepo) DeleteUnusedBlobs(ctx context.Context, lastUsedBefore time.Time, limit int, remove func(keys []string) error) (int64, error) {
+	var deleted int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var keys []string
+		err := tx.Raw(`SELECT b.key FROM blobs b
+			WHERE b.last_used_at < ?
+				AND NOT EXISTS (SELECT 1 FROM attachments a WHERE a.blob_key = b.key)
+				AND NOT EXISTS (SELECT 1 FROM attachment_variants v WHERE v.blob_key = b.key)
+			LIMIT ? FOR UPDATE SKIP LOCKED`, lastUsedBefore, limit).
+			Scan(&keys).Error
+		if err != nil || len(keys) == 0 {
+			return err
+		}
+
+		if err := remove(keys); err != nil {
+			return err
+		}
+
+		result := tx.Exec("DELETE FROM blobs WHERE key IN ?", keys)
+		deleted = result.RowsAffected
+		return result.Error
+	})
+
+	return deleted, err
+}
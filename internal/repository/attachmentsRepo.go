@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"time"
+)
+
+type AttachmentsRepository interface {
+	TouchBlob(ctx context.Context, blob *model.Blob) error
+	Create(ctx context.Context, attachment *model.Attachment) error
+	Get(ctx context.Context, publicId string) (*model.Attachment, error)
+	DeleteUnattached(ctx context.Context, createdBefore time.Time) (int64, error)
+	DeleteUnusedBlobs(ctx context.Context, lastUsedBefore time.Time, limit int, remove func(keys []string) error) (int64, error)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type botsRepo struct {
+	db *gorm.DB
+}
+
+func NewBotsRepo(db *gorm.DB) BotsRepository {
+	return &botsRepo{db: db}
+}
+
+func withCommands(db *gorm.DB) *gorm.DB {
+	return db.Preload("Commands", func(db *gorm.DB) *gorm.DB { return db.Order("bot_commands.command") })
+}
+
+func (r *botsRepo) Create(ctx context.Context, bot *model.Bot) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Commands").Create(bot).Error; errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrBotNameTaken
+		} else if err != nil {
+			return err
+		}
+
+		if len(bot.Commands) == 0 {
+			return nil
+		}
+		for i := range bot.Commands {
+			bot.Commands[i].BotId = bot.Id
+		}
+		if err := tx.Create(&bot.Commands).Error; errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrCommandTaken
+		} else if err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *botsRepo) List(ctx context.Context) ([]*model.Bot, error) {
+	var bots []*model.Bot
+	if err := withCommands(r.db.WithContext(ctx)).Order("id").Find(&bots).Error; err != nil {
+		return nil, err
+	}
+	return bots, nil
+}
+
+func (r *botsRepo) Delete(ctx context.Context, publicId string) error {
+	result := r.db.WithContext(ctx).Where("public_id = ?", publicId).Delete(&model.Bot{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBotNotFound
+	}
+	return nil
+}
+
+func (r *botsRepo) SetKeyHash(ctx context.Context, publicId, keyHash string) (*model.Bot, error) {
+	var bot model.Bot
+
+	result := r.db.WithContext(ctx).Model(&bot).
+		Clauses(clause.Returning{}).
+		Where("public_id = ?", publicId).
+		Update("key_hash", keyHash)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrBotNotFound
+	}
+
+	bot.CreatedAt = bot.CreatedAt.UTC()
+	return &bot, nil
+}
+
+func (r *botsRepo) GetByKeyHash(ctx context.Context, keyHash string) (*model.Bot, error) {
+	return r.first(withCommands(r.db.WithContext(ctx)).Where("key_hash = ?", keyHash))
+}
+
+func (r *botsRepo) GetByCommand(ctx context.Context, command string) (*model.Bot, error) {
+	return r.first(r.db.WithContext(ctx).
+		Where("id = (SELECT bot_id FROM bot_commands WHERE command = ?)", command))
+}
+
+func (r *botsRepo) first(query *gorm.DB) (*model.Bot, error) {
+	var bot model.Bot
+
+	result := query.Limit(1).Find(&bot)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrBotNotFound
+	}
+
+	return &bot, nil
+}
+
+func (r *botsRepo) Commands(ctx context.Context) ([]*model.BotCommand, error) {
+	var commands []*model.BotCommand
+	if err := r.db.WithContext(ctx).Order("command").Find(&commands).Error; err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+)
+
+type BotsRepository interface {
+	Create(ctx context.Context, bot *model.Bot) error
+	List(ctx context.Context) ([]*model.Bot, error)
+	Delete(ctx context.Context, publicId string) error
+	SetKeyHash(ctx context.Context, publicId, keyHash string) (*model.Bot, error)
+	GetByKeyHash(ctx context.Context, keyHash string) (*model.Bot, error)
+	GetByCommand(ctx context.Context, command string) (*model.Bot, error)
+	Commands(ctx context.Context) ([]*model.BotCommand, error)
+}
+
+type PollsRepository interface {
+	Create(ctx context.Context, poll *model.Poll) error
+	Latest(ctx context.Context, chatId int64) (*model.Poll, error)
+	// Vote records or changes the user's vote and returns the votes per option.
+	Vote(ctx context.Context, poll *model.Poll, userId string, option int) ([]int, error)
+}
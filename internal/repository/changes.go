@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// changeLogLockKey lets one sequencer at a time hand out positions.
+const changeLogLockKey = 7_270_001
+
+// sequenceBatchSize bounds the rows of each table one sequencer run positions.
+const sequenceBatchSize = 1000
+
+type changesRepo struct {
+	db *gorm.DB
+}
+
+func NewChangesRepo(db *gorm.DB) ChangesRepository {
+	return &changesRepo{db: db}
+}
+
+// recordChange appends the change to the change log and queues its event in
+// the outbox, both in the caller's transaction. entity is the chat or message
+// sent with the event, nil for deletions. Neither has a position before
+// Sequence gets to them.
+func recordChange(tx *gorm.DB, change *model.Change, entity any) error {
+	if err := tx.Create(change).Error; err != nil {
+		return err
+	}
+
+	return enqueueEvents(tx, []*model.Change{change}, []any{entity}, 1)
+}
+
+// enqueueEvent queues an event that is not in the change log, such as a read
+// receipt.
+func enqueueEvent(tx *gorm.DB, event *model.Event, key string) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&model.OutboxEvent{Type: event.Type, Key: key, Payload: string(payload)}).Error
+}
+
+// enqueueEvents writes outbox events for recorded changes; entities, when
+// given, lines up with changes.
+func enqueueEvents(tx *gorm.DB, changes []*model.Change, entities []any, batchSize int) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	events := make([]*model.OutboxEvent, len(changes))
+	for i, c := range changes {
+		var entity any
+		if entities != nil {
+			entity = entities[i]
+		}
+		payload, err := json.Marshal(model.NewEvent(c, entity))
+		if err != nil {
+			return err
+		}
+		events[i] = &model.OutboxEvent{Type: c.EventType(), Key: c.ChatPublicId, Payload: string(payload)}
+	}
+
+	return tx.CreateInBatches(events, batchSize).Error
+}
+
+// Sequence gives positions to the committed changes and outbox events that
+// have none, in id order. Positions go out one sequencer at a time and only
+// to committed rows, so writers need no lock of their own and a reader that
+// remembers the last position it saw misses nothing. When another sequencer
+// is running it returns right away.
+func (r *changesRepo) Sequence(ctx context.Context) (int64, error) {
+	var sequenced int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", changeLogLockKey).Scan(&locked).Error; err != nil || !locked {
+			return err
+		}
+
+		for _, table := range []string{"changes", "outbox_events"} {
+			var ids []int64
+			err := tx.Raw("SELECT id FROM "+table+" WHERE position IS NULL ORDER BY id LIMIT ?", sequenceBatchSize).
+				Scan(&ids).Error
+			if err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				continue
+			}
+
+			// Reserve the positions at once, then number the rows by id.
+			var last int64
+			sequence := "'" + table + "_position_seq'"
+			err = tx.Raw("SELECT setval("+sequence+", nextval("+sequence+") + ? - 1)", len(ids)).
+				Scan(&last).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Exec(`UPDATE `+table+` SET position = ? + numbered.n
+				FROM (SELECT id, row_number() OVER (ORDER BY id) AS n FROM `+table+` WHERE id IN ?) numbered
+				WHERE `+table+`.id = numbered.id`, last-int64(len(ids)), ids).Error
+			if err != nil {
+				return err
+			}
+			sequenced += int64(len(ids))
+		}
+		return nil
+	})
+
+	return sequenced, err
+}
+
+func (r *changesRepo) ListSince(ctx context.Context, sinceId int64, limit int) ([]*model.Change, error) {
+	return r.listSince(r.db.WithContext(ctx), sinceId, limit)
+}
+
+func (r *changesRepo) ListSinceFor(ctx context.Context, userId string, sinceId int64, limit int) ([]*model.Change, error) {
+	// Deleted chats are matched too, so their deletion stays hidden as well.
+	query := r.db.WithContext(ctx)
+	if userId == "" {
+		query = query.Where("chat_id NOT IN (SELECT id FROM chats WHERE type = ?)", model.ChatDirect)
+	} else {
+		query = query.Where(`chat_id NOT IN (SELECT id FROM chats WHERE type = ? AND NOT EXISTS (
+			SELECT 1 FROM chat_members WHERE chat_members.chat_id = chats.id AND chat_members.user_id = ?))`,
+			model.ChatDirect, userId)
+	}
+	return r.listSince(query, sinceId, limit)
+}
+
+func (r *changesRepo) listSince(query *gorm.DB, sinceId int64, limit int) ([]*model.Change, error) {
+	var changes []*model.Change
+
+	result := query.
+		Where("position > ?", sinceId).
+		Order("position asc").
+		Limit(limit).
+		Find(&changes)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return changes, nil
+}
+
+func (r *changesRepo) LastId(ctx context.Context) (int64, error) {
+	var id sql.NullInt64
+	if err := r.db.WithContext(ctx).Raw("SELECT max(position) FROM changes").Scan(&id).Error; err != nil {
+		return 0, err
+	}
+
+	prunedThrough, err := r.PrunedThrough(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(id.Int64, prunedThrough), nil
+}
+
+func (r *changesRepo) PrunedThrough(ctx context.Context) (int64, error) {
+	var prunedThrough int64
+	err := r.db.WithContext(ctx).
+		Raw("SELECT pruned_through FROM change_log_state").
+		Scan(&prunedThrough).Error
+
+	return prunedThrough, err
+}
+
+func (r *changesRepo) Prune(ctx context.Context, before time.Time) (int64, error) {
+	var pruned int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var maxId sql.NullInt64
+		row := tx.Raw(`WITH pruned AS (DELETE FROM changes WHERE created_at < ? AND position IS NOT NULL RETURNING position)
+			SELECT count(*), max(position) FROM pruned`, before).Row()
+		if err := row.Scan(&pruned, &maxId); err != nil {
+			return err
+		}
+		if !maxId.Valid {
+			return nil
+		}
+
+		return tx.Exec("UPDATE change_log_state SET pruned_through = GREATEST(pruned_through, ?)", maxId.Int64).Error
+	})
+
+	return pruned, err
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"time"
+)
+
+// ChangesRepository reads the change log by position: sinceId and LastId
+// are positions, not change ids.
+type ChangesRepository interface {
+	// Sequence gives positions to committed changes and outbox events and
+	// returns how many got one.
+	Sequence(ctx context.Context) (int64, error)
+	ListSince(ctx context.Context, sinceId int64, limit int) ([]*model.Change, error)
+	// ListSinceFor is ListSince without the changes of direct chats userId
+	// is not in, the way chat listings leave them out.
+	ListSinceFor(ctx context.Context, userId string, sinceId int64, limit int) ([]*model.Change, error)
+	LastId(ctx context.Context) (int64, error)
+	PrunedThrough(ctx context.Context) (int64, error)
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
@@ -3,8 +3,11 @@ package repository
 import (
 	"chats-api/internal/model"
 	"context"
+	"errors"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type chatsRepo struct {
@@ -15,11 +18,86 @@ func NewChatsRepo(db *gorm.DB) ChatsRepository {
 	return &chatsRepo{db: db}
 }
 
-func (r *chatsRepo) Create(ctx context.Context, chat *model.Chat) error {
-	return r.db.WithContext(ctx).Create(chat).Error
+// Create makes creatorId, when given, the first admin of the chat.
+func (r *chatsRepo) Create(ctx context.Context, chat *model.Chat, creatorId string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(chat).Error; errors.Is(err, gorm.ErrDuplicatedKey) {
+			return ErrChatTitleTaken
+		} else if err != nil {
+			return err
+		}
+		if creatorId != "" {
+			if err := tx.Create(&model.ChatMember{ChatId: chat.Id, UserId: creatorId, Role: model.RoleAdmin}).Error; err != nil {
+				return err
+			}
+		}
+
+		return recordChange(tx, &model.Change{
+			Entity:       model.ChangeEntityChat,
+			Action:       model.ChangeCreated,
+			ChatId:       chat.Id,
+			ChatPublicId: chat.PublicId,
+		}, chat)
+	})
 }
 
-func (r *chatsRepo) Get(id int) (*model.Chat, error) {
+func (r *chatsRepo) OpenDirect(ctx context.Context, userId, otherId string) (*model.Chat, bool, error) {
+	key := model.DirectKey(userId, otherId)
+	db := r.db.WithContext(ctx)
+
+	if chat, err := findDirect(db, key); !errors.Is(err, ErrChatNotFound) {
+		return chat, false, err
+	}
+
+	chat := &model.Chat{Type: model.ChatDirect, DirectKey: &key}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(chat).Error; err != nil {
+			return err
+		}
+
+		members := []*model.ChatMember{{ChatId: chat.Id, UserId: userId, Role: model.RoleAdmin}}
+		if otherId != userId {
+			members = append(members, &model.ChatMember{ChatId: chat.Id, UserId: otherId, Role: model.RoleAdmin})
+		}
+		if err := tx.Create(&members).Error; err != nil {
+			return err
+		}
+
+		return recordChange(tx, &model.Change{
+			Entity:       model.ChangeEntityChat,
+			Action:       model.ChangeCreated,
+			ChatId:       chat.Id,
+			ChatPublicId: chat.PublicId,
+		}, chat)
+	})
+	// When both users open the chat at once, the unique index on direct_key
+	// lets only one of them create it.
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		chat, err := findDirect(db, key)
+		return chat, false, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return chat, true, nil
+}
+
+func findDirect(db *gorm.DB, key string) (*model.Chat, error) {
+	var chat model.Chat
+
+	err := db.Where("direct_key = ?", key).Take(&chat).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrChatNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &chat, nil
+}
+
+func (r *chatsRepo) Get(id int64) (*model.Chat, error) {
 	var chat model.Chat
 
 	if err := r.db.First(&chat, id).Error; err != nil {
@@ -29,15 +107,369 @@ func (r *chatsRepo) Get(id int) (*model.Chat, error) {
 	return &chat, nil
 }
 
-func (r *chatsRepo) Delete(id int) error {
-	result := r.db.Delete(&model.Chat{}, id)
+func (r *chatsRepo) GetByIds(ctx context.Context, ids []int64) ([]*model.Chat, error) {
+	var chats []*model.Chat
+	if len(ids) == 0 {
+		return chats, nil
+	}
+
+	if err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&chats).Error; err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+func (r *chatsRepo) ResolveId(ctx context.Context, publicId string) (int64, error) {
+	var id int64
+
+	result := r.db.WithContext(ctx).Model(&model.Chat{}).
+		Where("public_id = ?", publicId).
+		Limit(1).
+		Pluck("id", &id)
 
 	if result.Error != nil {
-		return result.Error
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrChatNotFound
 	}
 
+	return id, nil
+}
+
+func (r *chatsRepo) ResolveIdFor(ctx context.Context, publicId, userId string) (int64, error) {
+	var id int64
+
+	result := visibleTo(r.db.WithContext(ctx).Model(&model.Chat{}), userId).
+		Where("chats.public_id = ?", publicId).
+		Limit(1).
+		Pluck("chats.id", &id)
+
+	if result.Error != nil {
+		return 0, result.Error
+	}
 	if result.RowsAffected == 0 {
-		return ErrChatNotFound
+		return 0, ErrChatNotFound
+	}
+
+	return id, nil
+}
+
+// visibleTo leaves out the direct chats userId is not in, all of them when
+// userId is empty.
+func visibleTo(query *gorm.DB, userId string) *gorm.DB {
+	if userId == "" {
+		return query.Where("chats.type <> ?", model.ChatDirect)
+	}
+	return query.Where("(chats.type <> ? OR EXISTS (SELECT 1 FROM chat_members WHERE chat_members.chat_id = chats.id AND chat_members.user_id = ?))",
+		model.ChatDirect, userId)
+}
+
+// List also counts the unread messages of userId in each chat, unless
+// userId is empty.
+func (r *chatsRepo) List(ctx context.Context, userId, afterPublicId string, limit int) ([]*model.Chat, error) {
+	var chats []*model.Chat
+
+	query := r.db.WithContext(ctx).Order("chats.public_id asc").Limit(limit)
+	if afterPublicId != "" {
+		query = query.Where("chats.public_id > ?", afterPublicId)
+	}
+	query = visibleTo(query, userId)
+	if userId != "" {
+		query = query.Select("chats.*, "+unreadCount("COALESCE(chat_reads.last_read_seq, 0)")+" AS unread_count").
+			Joins("LEFT JOIN chat_reads ON chat_reads.chat_id = chats.id AND chat_reads.user_id = ?", userId)
+	}
+
+	if err := query.Find(&chats).Error; err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+func (r *chatsRepo) Delete(id int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var chat model.Chat
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&chat, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrChatNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&chat).Error; err != nil {
+			return err
+		}
+
+		return recordChange(tx, &model.Change{
+			Entity:       model.ChangeEntityChat,
+			Action:       model.ChangeDeleted,
+			ChatId:       chat.Id,
+			ChatPublicId: chat.PublicId,
+		}, nil)
+	})
+}
+
+func (r *chatsRepo) Restore(ctx context.Context, publicId string, deletedAfter time.Time) (*model.Chat, error) {
+	return r.restore(ctx, publicId, deletedAfter, func(db *gorm.DB) *gorm.DB { return db })
+}
+
+func (r *chatsRepo) RestoreFor(ctx context.Context, publicId, userId string, deletedAfter time.Time) (*model.Chat, error) {
+	return r.restore(ctx, publicId, deletedAfter, func(db *gorm.DB) *gorm.DB { return administeredBy(db, userId) })
+}
+
+// restore takes the chat out of the trash if scope lets it be found there. A
+// direct chat cannot come back once its users have opened a new one.
+func (r *chatsRepo) restore(ctx context.Context, publicId string, deletedAfter time.Time, scope func(*gorm.DB) *gorm.DB) (*model.Chat, error) {
+	var chat model.Chat
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := scope(tx.Unscoped()).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("chats.public_id = ? AND chats.deleted_at > ?", publicId, deletedAfter).
+			Limit(1).
+			Find(&chat)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrChatNotInTrash
+		}
+
+		result = tx.Unscoped().Model(&chat).
+			Clauses(clause.Returning{}).
+			Update("deleted_at", nil)
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) && chat.Type == model.ChatDirect {
+			return ErrDirectChatReopened
+		}
+		if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+			return ErrChatTitleTaken
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return recordChange(tx, &model.Change{
+			Entity:       model.ChangeEntityChat,
+			Action:       model.ChangeCreated,
+			ChatId:       chat.Id,
+			ChatPublicId: chat.PublicId,
+		}, &chat)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return nil
+
+	chat.CreatedAt = chat.CreatedAt.UTC()
+	return &chat, nil
+}
+
+func (r *chatsRepo) ListDeleted(ctx context.Context, deletedAfter time.Time, limit int) ([]*model.Chat, error) {
+	return r.listDeleted(r.db.WithContext(ctx), deletedAfter, limit)
+}
+
+func (r *chatsRepo) ListDeletedFor(ctx context.Context, userId string, deletedAfter time.Time, limit int) ([]*model.Chat, error) {
+	return r.listDeleted(administeredBy(r.db.WithContext(ctx), userId), deletedAfter, limit)
+}
+
+// administeredBy keeps the chats userId is an admin of. Those include userId's
+// own direct chats only, so no other direct chat gets through.
+func administeredBy(query *gorm.DB, userId string) *gorm.DB {
+	return query.Where("EXISTS (SELECT 1 FROM chat_members WHERE chat_members.chat_id = chats.id AND chat_members.user_id = ? AND chat_members.role = ?)",
+		userId, model.RoleAdmin)
+}
+
+func (r *chatsRepo) listDeleted(query *gorm.DB, deletedAfter time.Time, limit int) ([]*model.Chat, error) {
+	var chats []*model.Chat
+
+	result := query.Unscoped().
+		Where("chats.deleted_at > ?", deletedAfter).
+		Order("deleted_at desc").
+		Limit(limit).
+		Find(&chats)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return chats, nil
+}
+
+// PurgeDeleted permanently removes chats deleted before deletedBefore, a batch
+// at a time so the cascade to messages never holds locks for long.
+func (r *chatsRepo) PurgeDeleted(ctx context.Context, deletedBefore time.Time, batchSize int) (int64, error) {
+	var purged int64
+
+	for {
+		result := r.db.WithContext(ctx).Exec(`DELETE FROM chats WHERE id IN (
+			SELECT id FROM chats WHERE deleted_at < ? ORDER BY deleted_at LIMIT ?)`, deletedBefore, batchSize)
+		if result.Error != nil {
+			return purged, result.Error
+		}
+
+		purged += result.RowsAffected
+		if result.RowsAffected < int64(batchSize) {
+			return purged, nil
+		}
+	}
+}
+
+func (r *chatsRepo) SetRetention(ctx context.Context, id int64, policy model.RetentionPolicy) (*model.Chat, error) {
+	return r.update(ctx, id, map[string]any{
+		"retention_max_age_seconds": policy.MaxAgeSeconds,
+		"retention_max_messages":    policy.MaxMessages,
+	})
+}
+
+func (r *chatsRepo) SetExpiresIn(ctx context.Context, id int64, expiresIn *int64) (*model.Chat, error) {
+	return r.update(ctx, id, map[string]any{"expires_in": expiresIn})
+}
+
+func (r *chatsRepo) SetTopic(ctx context.Context, id int64, topic string) (*model.Chat, error) {
+	return r.update(ctx, id, map[string]any{"topic": topic})
+}
+
+// update changes columns of a chat and records the change.
+func (r *chatsRepo) update(ctx context.Context, id int64, columns map[string]any) (*model.Chat, error) {
+	var chat model.Chat
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&chat).
+			Clauses(clause.Returning{}).
+			Where("id = ?", id).
+			Updates(columns)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrChatNotFound
+		}
+
+		return recordChange(tx, &model.Change{
+			Entity:       model.ChangeEntityChat,
+			Action:       model.ChangeUpdated,
+			ChatId:       chat.Id,
+			ChatPublicId: chat.PublicId,
+		}, &chat)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	chat.CreatedAt = chat.CreatedAt.UTC()
+	return &chat, nil
+}
+
+func (r *chatsRepo) ListWithRetention(ctx context.Context, afterId int64, limit int) ([]*model.Chat, error) {
+	var chats []*model.Chat
+
+	result := r.db.WithContext(ctx).
+		Where("id > ?", afterId).
+		Where("retention_max_age_seconds IS NOT NULL OR retention_max_messages IS NOT NULL").
+		Order("id asc").
+		Limit(limit).
+		Find(&chats)
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return chats, nil
+}
+
+// Import creates the chat together with its whole history in one transaction.
+// Messages are numbered in the given order and keep their CreatedAt.
+// chatImport keeps a whole import in one transaction, so a file that turns
+// out to be broken halfway leaves nothing behind. Every chat gets a savepoint,
+// so a taken title drops just that chat.
+type chatImport struct {
+	tx        *gorm.DB
+	batchSize int
+	chat      *model.Chat
+}
+
+func (r *chatsRepo) BeginImport(ctx context.Context, batchSize int) (ChatImport, error) {
+	tx := r.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	return &chatImport{tx: tx, batchSize: batchSize}, nil
+}
+
+func (i *chatImport) Chat(chat *model.Chat) error {
+	i.chat = nil
+	if err := i.tx.SavePoint("import_chat").Error; err != nil {
+		return err
+	}
+	if err := i.tx.Create(chat).Error; errors.Is(err, gorm.ErrDuplicatedKey) {
+		if err := i.tx.RollbackTo("import_chat").Error; err != nil {
+			return err
+		}
+		return ErrChatTitleTaken
+	} else if err != nil {
+		return err
+	}
+
+	i.chat = chat
+	return recordChange(i.tx, &model.Change{
+		Entity:       model.ChangeEntityChat,
+		Action:       model.ChangeCreated,
+		ChatId:       chat.Id,
+		ChatPublicId: chat.PublicId,
+	}, chat)
+}
+
+func (i *chatImport) Messages(messages []*model.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	for _, message := range messages {
+		i.chat.LastSeq++
+		message.ChatId = i.chat.Id
+		message.ChatPublicId = i.chat.PublicId
+		message.Seq = i.chat.LastSeq
+	}
+	if err := i.tx.CreateInBatches(messages, i.batchSize).Error; err != nil {
+		return err
+	}
+	if err := i.tx.Exec("UPDATE chats SET last_seq = ? WHERE id = ?", i.chat.LastSeq, i.chat.Id).Error; err != nil {
+		return err
+	}
+
+	changes := make([]*model.Change, len(messages))
+	entities := make([]any, len(messages))
+	for j, message := range messages {
+		changes[j] = &model.Change{
+			Entity:          model.ChangeEntityMessage,
+			Action:          model.ChangeCreated,
+			ChatId:          i.chat.Id,
+			ChatPublicId:    i.chat.PublicId,
+			MessageId:       &message.Id,
+			MessagePublicId: &message.PublicId,
+		}
+		entities[j] = message
+	}
+	if err := i.tx.CreateInBatches(changes, i.batchSize).Error; err != nil {
+		return err
+	}
+	return enqueueEvents(i.tx, changes, entities, i.batchSize)
+}
+
+func (i *chatImport) Commit() error {
+	return i.tx.Commit().Error
+}
+
+func (i *chatImport) Rollback() error {
+	return i.tx.Rollback().Error
+}
+
+func (r *chatsRepo) TitleTaken(ctx context.Context, title string) (bool, error) {
+	var taken bool
+	err := r.db.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM chats WHERE type = ? AND title = ? AND deleted_at IS NULL)", model.ChatGroup, title).
+		Scan(&taken).Error
+	return taken, err
 }
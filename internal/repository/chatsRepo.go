@@ -3,10 +3,50 @@ package repository
 import (
 	"chats-api/internal/model"
 	"context"
+	"time"
 )
 
 type ChatsRepository interface {
-	Create(ctx context.Context, chat *model.Chat) error
-	Get(id int) (*model.Chat, error)
-	Delete(id int) error
+	Create(ctx context.Context, chat *model.Chat, creatorId string) error
+	// OpenDirect finds the direct chat of the two users or creates it with
+	// both of them as admins, reporting whether it was created.
+	OpenDirect(ctx context.Context, userId, otherId string) (*model.Chat, bool, error)
+	Get(id int64) (*model.Chat, error)
+	GetByIds(ctx context.Context, ids []int64) ([]*model.Chat, error)
+	ResolveId(ctx context.Context, publicId string) (int64, error)
+	// ResolveIdFor is ResolveId that does not find the direct chats userId
+	// is not in.
+	ResolveIdFor(ctx context.Context, publicId, userId string) (int64, error)
+	// List leaves out the direct chats userId is not in.
+	List(ctx context.Context, userId, afterPublicId string, limit int) ([]*model.Chat, error)
+	Delete(id int64) error
+	Restore(ctx context.Context, publicId string, deletedAfter time.Time) (*model.Chat, error)
+	// RestoreFor is Restore that finds only the chats userId is an admin of.
+	RestoreFor(ctx context.Context, publicId, userId string, deletedAfter time.Time) (*model.Chat, error)
+	ListDeleted(ctx context.Context, deletedAfter time.Time, limit int) ([]*model.Chat, error)
+	// ListDeletedFor lists only the chats userId is an admin of.
+	ListDeletedFor(ctx context.Context, userId string, deletedAfter time.Time, limit int) ([]*model.Chat, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time, batchSize int) (int64, error)
+	SetRetention(ctx context.Context, id int64, policy model.RetentionPolicy) (*model.Chat, error)
+	// SetExpiresIn sets how many seconds new messages of the chat live; nil
+	// keeps them.
+	SetExpiresIn(ctx context.Context, id int64, expiresIn *int64) (*model.Chat, error)
+	SetTopic(ctx context.Context, id int64, topic string) (*model.Chat, error)
+	ListWithRetention(ctx context.Context, afterId int64, limit int) ([]*model.Chat, error)
+	// BeginImport opens the transaction an import is stored in.
+	BeginImport(ctx context.Context, batchSize int) (ChatImport, error)
+	// TitleTaken reports whether an active group chat has the title.
+	TitleTaken(ctx context.Context, title string) (bool, error)
+}
+
+// ChatImport stores imported chats one after another, each followed by its
+// messages, and makes them all visible on Commit.
+type ChatImport interface {
+	// Chat starts the next chat. When its title is taken it returns
+	// ErrChatTitleTaken, and the import goes on without it.
+	Chat(chat *model.Chat) error
+	// Messages appends messages to the chat, numbered in the order given.
+	Messages(messages []*model.Message) error
+	Commit() error
+	Rollback() error
 }
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type incomingHooksRepo struct {
+	db *gorm.DB
+}
+
+func NewIncomingHooksRepo(db *gorm.DB) IncomingHooksRepository {
+	return &incomingHooksRepo{db: db}
+}
+
+func (r *incomingHooksRepo) Create(ctx context.Context, hook *model.IncomingHook) error {
+	return r.db.WithContext(ctx).Create(hook).Error
+}
+
+// List includes revoked hooks, so admins can see what used to post.
+func (r *incomingHooksRepo) List(ctx context.Context, chatId int64) ([]*model.IncomingHook, error) {
+	var hooks []*model.IncomingHook
+	if err := r.db.WithContext(ctx).Where("chat_id = ?", chatId).Order("id").Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+func (r *incomingHooksRepo) Revoke(ctx context.Context, chatId int64, publicId string) error {
+	result := r.db.WithContext(ctx).Model(&model.IncomingHook{}).
+		Where("chat_id = ? AND public_id = ? AND revoked_at IS NULL", chatId, publicId).
+		Update("revoked_at", gorm.Expr("now()"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrHookNotFound
+	}
+	return nil
+}
+
+// GetByTokenHash finds an active hook of a chat that is not deleted.
+func (r *incomingHooksRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*model.IncomingHook, error) {
+	var hook model.IncomingHook
+
+	result := r.db.WithContext(ctx).
+		Select("incoming_hooks.*, chats.public_id AS chat_public_id").
+		Joins("JOIN chats ON chats.id = incoming_hooks.chat_id AND chats.deleted_at IS NULL").
+		Where("incoming_hooks.token_hash = ? AND incoming_hooks.revoked_at IS NULL", tokenHash).
+		Limit(1).
+		Find(&hook)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrHookNotFound
+	}
+
+	return &hook, nil
+}
+
+func (r *incomingHooksRepo) Touch(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Model(&model.IncomingHook{}).
+		Where("id = ?", id).
+		Update("last_used_at", gorm.Expr("now()")).Error
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+)
+
+type IncomingHooksRepository interface {
+	Create(ctx context.Context, hook *model.IncomingHook) error
+	List(ctx context.Context, chatId int64) ([]*model.IncomingHook, error)
+	Revoke(ctx context.Context, chatId int64, publicId string) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*model.IncomingHook, error)
+	Touch(ctx context.Context, id int64) error
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type membersRepo struct {
+	db *gorm.DB
+}
+
+func NewMembersRepo(db *gorm.DB) MembersRepository {
+	return &membersRepo{db: db}
+}
+
+// Role returns the user's role in the chat, empty for non-members.
+func (r *membersRepo) Role(ctx context.Context, chatId int64, userId string) (string, error) {
+	var roles []string
+	err := r.db.WithContext(ctx).Model(&model.ChatMember{}).
+		Where("chat_id = ? AND user_id = ?", chatId, userId).
+		Limit(1).
+		Pluck("role", &roles).Error
+	if err != nil || len(roles) == 0 {
+		return "", err
+	}
+	return roles[0], nil
+}
+
+// checkNotDirect refuses member changes in direct chats, which always have
+// just their two users.
+func checkNotDirect(db *gorm.DB, chatId int64) error {
+	var direct bool
+	err := db.Raw("SELECT EXISTS (SELECT 1 FROM chats WHERE id = ? AND type = ?)", chatId, model.ChatDirect).
+		Scan(&direct).Error
+	if err != nil {
+		return err
+	}
+	if direct {
+		return ErrDirectChat
+	}
+	return nil
+}
+
+func (r *membersRepo) Set(ctx context.Context, chatId int64, userId, role string) (*model.ChatMember, error) {
+	member := &model.ChatMember{ChatId: chatId, UserId: userId, Role: role}
+
+	if err := checkNotDirect(r.db.WithContext(ctx), chatId); err != nil {
+		return nil, err
+	}
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "chat_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"role"}),
+		}, clause.Returning{}).
+		Create(member).Error
+	if err != nil {
+		return nil, err
+	}
+
+	member.CreatedAt = member.CreatedAt.UTC()
+	return member, nil
+}
+
+func (r *membersRepo) Remove(ctx context.Context, chatId int64, userId string) error {
+	if err := checkNotDirect(r.db.WithContext(ctx), chatId); err != nil {
+		return err
+	}
+	result := r.db.WithContext(ctx).
+		Where("chat_id = ? AND user_id = ?", chatId, userId).
+		Delete(&model.ChatMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMemberNotFound
+	}
+	return nil
+}
+
+func (r *membersRepo) List(ctx context.Context, chatId int64) ([]*model.ChatMember, error) {
+	var members []*model.ChatMember
+	err := r.db.WithContext(ctx).
+		Where("chat_id = ?", chatId).
+		Order("created_at, user_id").
+		Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
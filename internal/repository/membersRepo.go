@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+)
+
+type MembersRepository interface {
+	Role(ctx context.Context, chatId int64, userId string) (string, error)
+	Set(ctx context.Context, chatId int64, userId, role string) (*model.ChatMember, error)
+	Remove(ctx context.Context, chatId int64, userId string) error
+	List(ctx context.Context, chatId int64) ([]*model.ChatMember, error)
+}
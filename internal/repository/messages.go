@@ -4,6 +4,8 @@ import (
 	"chats-api/internal/model"
 	"context"
 	"errors"
+	"slices"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -12,32 +14,347 @@ type messagesRepo struct {
 	db *gorm.DB
 }
 
-var ErrChatNotFound = errors.New("chat not found")
+var (
+	ErrChatNotFound       = errors.New("chat not found")
+	ErrChatTitleTaken     = errors.New("chat with this title already exists")
+	ErrChatNotInTrash     = errors.New("chat is not in the trash or can no longer be restored")
+	ErrDirectChatReopened = errors.New("the users of this direct chat have opened a new one since")
+	ErrDuplicateMessage   = errors.New("message with this client_msg_id already exists")
+	ErrAttachmentNotFound = errors.New("attachment not found or already attached to a message")
+	ErrWebhookNotFound    = errors.New("webhook not found")
+	ErrMemberNotFound     = errors.New("user is not a member of the chat")
+	ErrHookNotFound       = errors.New("incoming hook not found or revoked")
+	ErrBotNotFound        = errors.New("bot not found")
+	ErrBotNameTaken       = errors.New("bot with this name already exists")
+	ErrCommandTaken       = errors.New("command is already handled by another bot")
+	ErrPollNotFound       = errors.New("chat has no polls")
+	ErrReplyNotFound      = errors.New("message to reply to not found in the chat")
+	ErrMessageNotFound    = errors.New("message not found in the chat")
+	ErrTooManyPins        = errors.New("chat has too many pinned messages")
+	ErrScheduledNotFound  = errors.New("scheduled message not found or already being sent")
+	ErrChatReadOnly       = errors.New("only admins can post to this chat")
+	ErrDirectChat         = errors.New("members of a direct chat cannot change")
+)
 
 func NewMessagesRepo(db *gorm.DB) MessagesRepository {
 	return &messagesRepo{db: db}
 }
 
-func (r *messagesRepo) Create(ctx context.Context, message *model.Message) error {
-	var msg model.Message
-	if err := r.db.Where("chat_id = ?", message.ChatId).First(&msg).Error; err != nil {
-		return ErrChatNotFound
+// withChat selects messages together with the public id of their chat and
+// their attachments.
+func withChat(db *gorm.DB) *gorm.DB {
+	return db.Model(&model.Message{}).
+		Select("messages.*, chats.public_id AS chat_public_id").
+		Joins("JOIN chats ON chats.id = messages.chat_id AND chats.deleted_at IS NULL").
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("attachments.id") }).
+		Preload("Attachments.Variants", func(db *gorm.DB) *gorm.DB { return db.Order("attachment_variants.id") })
+}
+
+// unexpired leaves out the messages past their expires_at, which are gone for
+// readers even before the sweeper deletes them.
+func unexpired(db *gorm.DB) *gorm.DB {
+	return db.Where("(messages.expires_at IS NULL OR messages.expires_at > now())")
+}
+
+func (r *messagesRepo) Create(ctx context.Context, message *model.Message, attachmentIds []string, online Online) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The row lock taken on the chat serializes concurrent inserts, and a
+		// rolled back insert also rolls back the counter, so sequences stay gap-free.
+		var chat model.Chat
+		result := tx.Raw("UPDATE chats SET last_seq = last_seq + 1 WHERE id = ? AND deleted_at IS NULL RETURNING last_seq, public_id, expires_in, type", message.ChatId).
+			Scan(&chat)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrChatNotFound
+		}
+		if err := checkCanPost(tx, message.ChatId, chat.Type, message.SenderId); err != nil {
+			return err
+		}
+
+		// The chat row is locked now, so no concurrent insert can slip in
+		// between this lookup and the insert below.
+		if message.ClientMsgId != nil {
+			var existing model.Message
+			result := withChat(tx).
+				Where("messages.chat_id = ? AND messages.sender_id = ? AND messages.client_msg_id = ?",
+					message.ChatId, message.SenderId, *message.ClientMsgId).
+				Limit(1).
+				Find(&existing)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				*message = existing
+				return ErrDuplicateMessage
+			}
+		}
+
+		message.Seq = chat.LastSeq
+		message.ChatPublicId = chat.PublicId
+		if message.ExpiresAt == nil && chat.ExpiresIn != nil {
+			expiresAt := time.Now().Add(time.Duration(*chat.ExpiresIn) * time.Second).UTC()
+			message.ExpiresAt = &expiresAt
+		}
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		// Whoever writes has read everything before their message.
+		if message.SenderId != "" {
+			if _, err := advanceRead(tx, message.ChatId, message.SenderId, message.Seq); err != nil {
+				return err
+			}
+		}
+		if err := notify(tx, message, online); err != nil {
+			return err
+		}
+		if err := queueAll(tx, message); err != nil {
+			return err
+		}
+
+		if len(attachmentIds) > 0 {
+			// Only the sender's own uploads to this chat that are not part of
+			// another message can be attached.
+			result := tx.Model(&model.Attachment{}).
+				Where("public_id IN ? AND chat_id = ? AND uploader_id = ? AND message_id IS NULL",
+					attachmentIds, message.ChatId, message.SenderId).
+				Update("message_id", message.Id)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected != int64(len(attachmentIds)) {
+				return ErrAttachmentNotFound
+			}
+			err := tx.Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("attachment_variants.id") }).
+				Where("message_id = ?", message.Id).
+				Order("id").
+				Find(&message.Attachments).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return recordChange(tx, &model.Change{
+			Entity:          model.ChangeEntityMessage,
+			Action:          model.ChangeCreated,
+			ChatId:          message.ChatId,
+			ChatPublicId:    message.ChatPublicId,
+			MessageId:       &message.Id,
+			MessagePublicId: &message.PublicId,
+		}, message)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The message is posted by now, so a client going away does not cut the
+	// @all notifications short, and a failure is not the sender's: the
+	// message stays queued and NotifyPendingAll finishes the fan-out.
+	if mentionsAll(message) {
+		_ = deliverAll(r.db.WithContext(context.WithoutCancel(ctx)), message)
+	}
+	return nil
+}
+
+// checkCanPost lets anyone post to group and direct chats. Broadcast chats take
+// messages only from their admins, from bots admins made members and the
+// chat's incoming hooks, which admins set up, and from the service itself;
+// never anonymously. Sender ids of bots and hooks come from their
+// credentials: users cannot claim them.
+func checkCanPost(tx *gorm.DB, chatId int64, chatType, senderId string) error {
+	if chatType != model.ChatBroadcast || senderId == model.SystemSenderId {
+		return nil
+	}
+	if senderId == "" {
+		return ErrChatReadOnly
+	}
+
+	var allowed bool
+	err := tx.Raw(`SELECT EXISTS (SELECT 1 FROM chat_members WHERE chat_id = ? AND user_id = ?
+			AND (role = ? OR user_id IN (SELECT 'bot:' || public_id FROM bots)))
+		OR EXISTS (SELECT 1 FROM incoming_hooks WHERE chat_id = ? AND 'hook:' || public_id = ? AND revoked_at IS NULL)`,
+		chatId, senderId, model.RoleAdmin, chatId, senderId).
+		Scan(&allowed).Error
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrChatReadOnly
+	}
+	return nil
+}
+
+func (r *messagesRepo) GetAll(ctx context.Context, chatId int64, page MessagesPage) ([]*model.Message, error) {
+	var messages []*model.Message
+
+	query := unexpired(withChat(r.db.WithContext(ctx))).Where("messages.chat_id = ?", chatId).Limit(page.Limit)
+
+	if page.AfterSeq > 0 {
+		query = query.Where("messages.seq > ?", page.AfterSeq).Order("messages.seq asc")
+	} else {
+		query = query.Order("messages.seq desc")
+	}
+	if page.BeforeSeq > 0 {
+		query = query.Where("messages.seq < ?", page.BeforeSeq)
+	}
+
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, err
 	}
 
-	return r.db.Create(message).WithContext(ctx).Error
+	return messages, nil
 }
 
-func (r *messagesRepo) GetAll(chatId int, limit int) ([]*model.Message, error) {
+func (r *messagesRepo) GetByIds(ctx context.Context, ids []int64) ([]*model.Message, error) {
 	var messages []*model.Message
+	if len(ids) == 0 {
+		return messages, nil
+	}
 
-	result := r.db.Where("chat_id = ?", chatId).
-		Limit(limit).
-		Order("created_at desc").
+	result := unexpired(withChat(r.db.WithContext(ctx))).
+		Where("messages.id IN ?", ids).
+		Order("messages.id").
 		Find(&messages)
-
 	if result.Error != nil {
 		return nil, result.Error
 	}
 
 	return messages, nil
 }
+
+// Each calls fn for every message of the chat matching the filter in seq
+// order, loading them batchSize at a time.
+func (r *messagesRepo) Each(ctx context.Context, chatId int64, filter MessagesFilter, batchSize int, fn func(*model.Message) error) error {
+	var afterSeq int64
+	for {
+		var messages []*model.Message
+
+		query := unexpired(withChat(r.db.WithContext(ctx))).
+			Where("messages.chat_id = ? AND messages.seq > ?", chatId, afterSeq)
+		if !filter.Since.IsZero() {
+			query = query.Where("messages.created_at >= ?", filter.Since)
+		}
+		if !filter.Until.IsZero() {
+			query = query.Where("messages.created_at < ?", filter.Until)
+		}
+
+		if err := query.Order("messages.seq asc").Limit(batchSize).Find(&messages).Error; err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			if err := fn(message); err != nil {
+				return err
+			}
+		}
+
+		if len(messages) < batchSize {
+			return nil
+		}
+		afterSeq = messages[len(messages)-1].Seq
+	}
+}
+
+func (r *messagesRepo) PurgeOlderThan(ctx context.Context, chatId int64, before time.Time, batchSize int) (int64, error) {
+	return r.purgeInBatches(ctx, batchSize,
+		"SELECT id FROM messages WHERE chat_id = ? AND created_at < ? ORDER BY seq LIMIT ?", chatId, before)
+}
+
+func (r *messagesRepo) PurgeExpired(ctx context.Context, batchSize int) (int64, error) {
+	return r.purgeInBatches(ctx, batchSize,
+		"SELECT id FROM messages WHERE expires_at <= now() ORDER BY expires_at LIMIT ?")
+}
+
+func (r *messagesRepo) PurgeBeyondCount(ctx context.Context, chatId int64, keep int64, batchSize int) (int64, error) {
+	var threshold []int64
+	result := r.db.WithContext(ctx).Model(&model.Message{}).
+		Where("chat_id = ?", chatId).
+		Order("seq desc").
+		Offset(int(keep)).
+		Limit(1).
+		Pluck("seq", &threshold)
+	if result.Error != nil || len(threshold) == 0 {
+		return 0, result.Error
+	}
+
+	return r.purgeInBatches(ctx, batchSize,
+		"SELECT id FROM messages WHERE chat_id = ? AND seq <= ? ORDER BY seq LIMIT ?", chatId, threshold[0])
+}
+
+// purgeInBatches deletes the messages selected by idsQuery, whose last
+// placeholder is the batch size, one short transaction per batch.
+func (r *messagesRepo) purgeInBatches(ctx context.Context, batchSize int, idsQuery string, args ...any) (int64, error) {
+	var purged int64
+
+	for {
+		var deleted int64
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			deleted, err = deleteMessages(tx, idsQuery, append(args, batchSize)...)
+			return err
+		})
+		if err != nil {
+			return purged, err
+		}
+
+		purged += deleted
+		if deleted < int64(batchSize) {
+			return purged, nil
+		}
+	}
+}
+
+// deleteMessages removes the messages selected by idsQuery and records their
+// deletion in the change log and their seqs in message_gaps within the
+// caller's transaction.
+func deleteMessages(tx *gorm.DB, idsQuery string, args ...any) (int64, error) {
+	var changes []*model.Change
+	err := tx.Raw(`WITH deleted AS (
+			DELETE FROM messages WHERE id IN (`+idsQuery+`) RETURNING id, public_id, chat_id, seq),
+		gaps AS (
+			INSERT INTO message_gaps (chat_id, seq) SELECT chat_id, seq FROM deleted)
+		INSERT INTO changes (entity, action, chat_id, chat_public_id, message_id, message_public_id, created_at)
+		SELECT ?, ?, d.chat_id, c.public_id, d.id, d.public_id, now()
+		FROM deleted d JOIN chats c ON c.id = d.chat_id
+		RETURNING *`,
+		append(args, model.ChangeEntityMessage, model.ChangeDeleted)...).
+		Scan(&changes).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	chatIds := make([]int64, 0, len(changes))
+	for _, change := range changes {
+		chatIds = append(chatIds, change.ChatId)
+	}
+	if err := advancePurgedSeq(tx, slices.Compact(slices.Sorted(slices.Values(chatIds)))); err != nil {
+		return 0, err
+	}
+
+	return int64(len(changes)), enqueueEvents(tx, changes, nil, 500)
+}
+
+// advancePurgedSeq moves purged_seq of the chats over the gaps right above it
+// and drops those gaps, so message_gaps keeps only the holes in the middle of
+// a history. Only gaps move it: a message posted meanwhile is never taken for
+// purged, and GREATEST keeps a concurrent purge from moving it back.
+func advancePurgedSeq(tx *gorm.DB, chatIds []int64) error {
+	return tx.Exec(`WITH runs AS (
+			SELECT g.chat_id, max(g.seq) AS seq
+			FROM (SELECT message_gaps.chat_id, message_gaps.seq, chats.purged_seq,
+					message_gaps.seq - row_number() OVER (PARTITION BY message_gaps.chat_id ORDER BY message_gaps.seq) AS base
+				FROM message_gaps JOIN chats ON chats.id = message_gaps.chat_id
+				WHERE message_gaps.chat_id IN ? AND message_gaps.seq > chats.purged_seq) g
+			WHERE g.base = g.purged_seq
+			GROUP BY g.chat_id),
+		moved AS (
+			UPDATE chats SET purged_seq = GREATEST(chats.purged_seq, runs.seq)
+			FROM runs WHERE chats.id = runs.chat_id
+			RETURNING chats.id, chats.purged_seq)
+		DELETE FROM message_gaps USING moved
+		WHERE message_gaps.chat_id = moved.id AND message_gaps.seq <= moved.purged_seq`, chatIds).Error
+}
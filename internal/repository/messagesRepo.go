@@ -3,9 +3,36 @@ package repository
 import (
 	"chats-api/internal/model"
 	"context"
+	"time"
 )
 
 type MessagesRepository interface {
-	Create(ctx context.Context, message *model.Message) error
-	GetAll(chatId int, limit int) ([]*model.Message, error)
+	// Create stores the message and notifies the users it mentions or replies
+	// to; online resolves @here and may be nil. @all is fanned out once the
+	// message is stored, and NotificationsRepository.NotifyPendingAll retries
+	// it if that fails.
+	Create(ctx context.Context, message *model.Message, attachmentIds []string, online Online) error
+	GetAll(ctx context.Context, chatId int64, page MessagesPage) ([]*model.Message, error)
+	GetByIds(ctx context.Context, ids []int64) ([]*model.Message, error)
+	PurgeOlderThan(ctx context.Context, chatId int64, before time.Time, batchSize int) (int64, error)
+	PurgeBeyondCount(ctx context.Context, chatId int64, keep int64, batchSize int) (int64, error)
+	// PurgeExpired deletes the messages of all chats that are past their
+	// expires_at.
+	PurgeExpired(ctx context.Context, batchSize int) (int64, error)
+	Each(ctx context.Context, chatId int64, filter MessagesFilter, batchSize int, fn func(*model.Message) error) error
+}
+
+// MessagesPage selects a window of a chat's history by sequence number.
+// With AfterSeq set messages are returned oldest first, otherwise newest first.
+type MessagesPage struct {
+	Limit     int
+	BeforeSeq int64
+	AfterSeq  int64
+}
+
+// MessagesFilter limits messages by creation time. Zero bounds are ignored,
+// Until is exclusive.
+type MessagesFilter struct {
+	Since time.Time
+	Until time.Time
 }
@@ -0,0 +1,248 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const notificationsBatchSize = 500
+
+// notifyAllLease is how long an @all fan-out is left to the instance that
+// claimed it before NotifyPendingAll takes it over.
+const notifyAllLease = time.Minute
+
+type notificationsRepo struct {
+	db *gorm.DB
+}
+
+func NewNotificationsRepo(db *gorm.DB) NotificationsRepository {
+	return &notificationsRepo{db: db}
+}
+
+// notify writes the notifications of a new message: to the users it mentions
+// who joined or read the chat, to those online for @here and to the author of
+// the message it replies to. Nobody is notified of their own message, and a
+// user mentioned in a reply to them gets one notification. @all is left to
+// notifyAll.
+func notify(tx *gorm.DB, message *model.Message, online Online) error {
+	var notifications []*model.Notification
+	notified := map[string]bool{message.SenderId: true}
+	add := func(userId, kind string) {
+		if userId == "" || notified[userId] {
+			return
+		}
+		notified[userId] = true
+		notifications = append(notifications, &model.Notification{
+			UserId:    userId,
+			ChatId:    message.ChatId,
+			MessageId: message.Id,
+			Kind:      kind,
+		})
+	}
+
+	var mentioned []string
+	for _, m := range message.Mentions {
+		if m.Type == model.MentionUser {
+			mentioned = append(mentioned, m.UserId)
+		}
+	}
+	// Any name can be typed after @, but only users of the chat hear of it.
+	var known []string
+	if len(mentioned) > 0 {
+		err := tx.Raw(`SELECT user_id FROM chat_members WHERE chat_id = ? AND user_id IN ?
+			UNION SELECT user_id FROM chat_reads WHERE chat_id = ? AND user_id IN ?`,
+			message.ChatId, mentioned, message.ChatId, mentioned).
+			Scan(&known).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, m := range message.Mentions {
+		switch m.Type {
+		case model.MentionUser:
+			if slices.Contains(known, m.UserId) {
+				add(m.UserId, model.NotificationMention)
+			}
+		case model.MentionHere:
+			if online != nil {
+				for _, userId := range online(message.ChatPublicId) {
+					add(userId, model.NotificationMention)
+				}
+			}
+		}
+	}
+
+	if message.ReplyTo != nil {
+		var authors []string
+		err := tx.Raw(`SELECT sender_id FROM messages WHERE chat_id = ? AND public_id = ?
+			AND (expires_at IS NULL OR expires_at > now())`, message.ChatId, *message.ReplyTo).
+			Scan(&authors).Error
+		if err != nil {
+			return err
+		}
+		if len(authors) == 0 {
+			return ErrReplyNotFound
+		}
+		add(authors[0], model.NotificationReply)
+	}
+
+	if len(notifications) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(notifications, notificationsBatchSize).Error
+}
+
+func mentionsAll(message *model.Message) bool {
+	return message.SenderId != "" && slices.ContainsFunc(message.Mentions, func(m model.Mention) bool {
+		return m.Type == model.MentionAll
+	})
+}
+
+// queueAll puts a message with @all in pending_all_mentions, claimed by the
+// instance posting it, until notifyAll has got to everyone.
+func queueAll(tx *gorm.DB, message *model.Message) error {
+	if !mentionsAll(message) {
+		return nil
+	}
+	return tx.Exec("INSERT INTO pending_all_mentions (message_id, claimed_until) VALUES (?, ?)",
+		message.Id, time.Now().Add(notifyAllLease)).Error
+}
+
+// deliverAll runs notifyAll for a queued message and takes it off the queue.
+func deliverAll(db *gorm.DB, message *model.Message) error {
+	if err := notifyAll(db, message); err != nil {
+		return err
+	}
+	return db.Exec("DELETE FROM pending_all_mentions WHERE message_id = ?", message.Id).Error
+}
+
+// notifyAll notifies everyone who joined or read the chat of an @all in a
+// message posted by an admin of the chat; the mention of anybody else notifies
+// nobody. It runs once the message is committed, batch by batch, so a big chat
+// does not keep the chat row locked, and skips users already notified, so it
+// can be run again after a failure.
+func notifyAll(db *gorm.DB, message *model.Message) error {
+	if !mentionsAll(message) {
+		return nil
+	}
+	var admin bool
+	err := db.Raw("SELECT EXISTS (SELECT 1 FROM chat_members WHERE chat_id = ? AND user_id = ? AND role = ?)",
+		message.ChatId, message.SenderId, model.RoleAdmin).
+		Scan(&admin).Error
+	if err != nil || !admin {
+		return err
+	}
+
+	after := ""
+	for {
+		var users []string
+		err := db.Raw(`SELECT user_id FROM (SELECT user_id FROM chat_members WHERE chat_id = ?
+			UNION SELECT user_id FROM chat_reads WHERE chat_id = ?) readers
+			WHERE user_id > ? AND user_id <> ? ORDER BY user_id LIMIT ?`,
+			message.ChatId, message.ChatId, after, message.SenderId, notificationsBatchSize).
+			Scan(&users).Error
+		if err != nil || len(users) == 0 {
+			return err
+		}
+
+		notifications := make([]*model.Notification, 0, len(users))
+		for _, userId := range users {
+			notifications = append(notifications, &model.Notification{
+				UserId:    userId,
+				ChatId:    message.ChatId,
+				MessageId: message.Id,
+				Kind:      model.NotificationMention,
+			})
+		}
+		if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&notifications).Error; err != nil {
+			return err
+		}
+		if len(users) < notificationsBatchSize {
+			return nil
+		}
+		after = users[len(users)-1]
+	}
+}
+
+// markNotificationsRead marks the user's notifications of the chat read up to
+// the message with seq.
+func markNotificationsRead(tx *gorm.DB, chatId int64, userId string, seq int64) error {
+	return tx.Exec(`UPDATE notifications SET read_at = now()
+		WHERE user_id = ? AND chat_id = ? AND read_at IS NULL
+		AND message_id IN (SELECT id FROM messages WHERE chat_id = ? AND seq <= ?)`, userId, chatId, chatId, seq).Error
+}
+
+func (r *notificationsRepo) ListUnread(ctx context.Context, userId, beforePublicId string, limit int) ([]*model.Notification, error) {
+	var notifications []*model.Notification
+
+	query := unexpired(r.db.WithContext(ctx)).
+		Select("notifications.*, chats.public_id AS chat_public_id").
+		Joins("JOIN chats ON chats.id = notifications.chat_id AND chats.deleted_at IS NULL").
+		Joins("JOIN messages ON messages.id = notifications.message_id").
+		Where("notifications.user_id = ? AND notifications.read_at IS NULL", userId).
+		Order("notifications.public_id desc").
+		Limit(limit)
+	if beforePublicId != "" {
+		query = query.Where("notifications.public_id < ?", beforePublicId)
+	}
+
+	if err := query.Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *notificationsRepo) MarkRead(ctx context.Context, userId string, publicIds []string) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userId)
+	if len(publicIds) > 0 {
+		query = query.Where("public_id IN ?", publicIds)
+	}
+
+	result := query.Update("read_at", gorm.Expr("now()"))
+	return result.RowsAffected, result.Error
+}
+
+// NotifyPendingAll claims the @all fan-outs whose lease ran out, so another
+// instance picks them up only if this one fails too, and finishes them.
+func (r *notificationsRepo) NotifyPendingAll(ctx context.Context, limit int) (int, error) {
+	var messages []*model.Message
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []int64
+		err := tx.Raw(`SELECT message_id FROM pending_all_mentions WHERE claimed_until < now()
+			ORDER BY message_id LIMIT ? FOR UPDATE SKIP LOCKED`, limit).
+			Scan(&ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		err = tx.Exec("UPDATE pending_all_mentions SET claimed_until = ? WHERE message_id IN ?",
+			time.Now().Add(notifyAllLease), ids).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Order("id").Find(&messages).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var notified int
+	var firstErr error
+	for _, m := range messages {
+		if err := deliverAll(r.db.WithContext(ctx), m); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		notified++
+	}
+	return notified, firstErr
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+)
+
+type NotificationsRepository interface {
+	// ListUnread pages through the user's unread notifications newest first.
+	ListUnread(ctx context.Context, userId, beforePublicId string, limit int) ([]*model.Notification, error)
+	// MarkRead marks the given notifications of the user read, or all of them
+	// when publicIds is empty, and returns how many it marked.
+	MarkRead(ctx context.Context, userId string, publicIds []string) (int64, error)
+	// NotifyPendingAll retries up to limit @all fan-outs cut short by an
+	// error or a crash and returns how many it finished.
+	NotifyPendingAll(ctx context.Context, limit int) (int, error)
+}
+
+// Online tells who is online in a chat, the users @here notifies.
+type Online func(chatPublicId string) []string
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// outboxLockKey lets one relay at a time publish, which keeps events in
+// position order even with several replicas running.
+const outboxLockKey = 7_270_002
+
+type outboxRepo struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepo(db *gorm.DB) OutboxRepository {
+	return &outboxRepo{db: db}
+}
+
+// Publish hands the oldest unpublished events that have a position to
+// publish and marks them published once it succeeds. A failed or interrupted
+// publish leaves them for the next run, so events go out at least once. When
+// another relay holds the lock it returns right away.
+func (r *outboxRepo) Publish(ctx context.Context, limit int, publish func([]*model.OutboxEvent) error) (int, error) {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return 0, err
+	}
+
+	// The lock is held by a session of its own rather than a transaction, so
+	// nothing stays open in the database while the broker is slow.
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", outboxLockKey).Scan(&locked); err != nil || !locked {
+		return 0, err
+	}
+	defer func() {
+		_, err := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", outboxLockKey)
+		if err != nil {
+			// A session that may still hold the lock must not return to the pool.
+			conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	db := r.db.WithContext(ctx)
+	var events []*model.OutboxEvent
+	err = db.Where("published_at IS NULL AND position IS NOT NULL").Order("position").Limit(limit).Find(&events).Error
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	if err := publish(events); err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, len(events))
+	for i, e := range events {
+		ids[i] = e.Id
+	}
+	if err := db.Model(&model.OutboxEvent{}).Where("id IN ?", ids).Update("published_at", gorm.Expr("now()")).Error; err != nil {
+		return 0, err
+	}
+
+	return len(events), nil
+}
+
+func (r *outboxRepo) DeletePublished(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("published_at < ?", publishedBefore).
+		Delete(&model.OutboxEvent{})
+
+	return result.RowsAffected, result.Error
+}
+
+func (r *outboxRepo) DeleteCreatedBefore(ctx context.Context, createdBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("created_at < ?", createdBefore).
+		Delete(&model.OutboxEvent{})
+
+	return result.RowsAffected, result.Error
+}
+
+// ListAfter reads events whether published or not, after the position
+// afterId. Positions are given in commit order, so a reader that remembers
+// the last position it saw misses nothing.
+func (r *outboxRepo) ListAfter(ctx context.Context, afterId int64, limit int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+	if err := r.db.WithContext(ctx).Where("position > ?", afterId).Order("position").Limit(limit).Find(&events).Error; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *outboxRepo) LastId(ctx context.Context) (int64, error) {
+	var id sql.NullInt64
+	if err := r.db.WithContext(ctx).Raw("SELECT max(position) FROM outbox_events").Scan(&id).Error; err != nil {
+		return 0, err
+	}
+	return id.Int64, nil
+}
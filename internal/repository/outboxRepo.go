@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"time"
+)
+
+type OutboxRepository interface {
+	Publish(ctx context.Context, limit int, publish func(events []*model.OutboxEvent) error) (int, error)
+	DeletePublished(ctx context.Context, publishedBefore time.Time) (int64, error)
+	// DeleteCreatedBefore removes events whether published or not.
+	DeleteCreatedBefore(ctx context.Context, createdBefore time.Time) (int64, error)
+	ListAfter(ctx context.Context, afterId int64, limit int) ([]*model.OutboxEvent, error)
+	// LastId returns the last position given to an event.
+	LastId(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pinsRepo struct {
+	db *gorm.DB
+}
+
+func NewPinsRepo(db *gorm.DB) PinsRepository {
+	return &pinsRepo{db: db}
+}
+
+func (r *pinsRepo) Pin(ctx context.Context, chatId int64, messagePublicId, userId string, maxPins int) (*model.Message, error) {
+	return r.setPin(ctx, chatId, messagePublicId, func(tx *gorm.DB, message *model.Message) (bool, error) {
+		if message.PinnedAt != nil {
+			return false, nil
+		}
+
+		// Expired pins wait for the sweep but no longer hold a slot.
+		var pinned int64
+		if err := unexpired(tx.Model(&model.Message{})).Where("chat_id = ? AND pinned_at IS NOT NULL", chatId).Count(&pinned).Error; err != nil {
+			return false, err
+		}
+		if pinned >= int64(maxPins) {
+			return false, ErrTooManyPins
+		}
+
+		// The admin token pins on behalf of nobody.
+		var pinnedBy *string
+		if userId != "" {
+			pinnedBy = &userId
+		}
+		err := tx.Model(&model.Message{}).Where("id = ?", message.Id).
+			Updates(map[string]any{"pinned_at": gorm.Expr("now()"), "pinned_by": pinnedBy}).Error
+		return true, err
+	})
+}
+
+func (r *pinsRepo) Unpin(ctx context.Context, chatId int64, messagePublicId string) (*model.Message, error) {
+	return r.setPin(ctx, chatId, messagePublicId, func(tx *gorm.DB, message *model.Message) (bool, error) {
+		if message.PinnedAt == nil {
+			return false, nil
+		}
+
+		err := tx.Model(&model.Message{}).Where("id = ?", message.Id).
+			Updates(map[string]any{"pinned_at": nil, "pinned_by": nil}).Error
+		return true, err
+	})
+}
+
+// setPin runs change on the message with the chat row locked, so concurrent
+// pins cannot exceed the limit, and records a message.updated change when it
+// reports a change.
+func (r *pinsRepo) setPin(ctx context.Context, chatId int64, messagePublicId string, change func(tx *gorm.DB, message *model.Message) (bool, error)) (*model.Message, error) {
+	var message model.Message
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var chat model.Chat
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", chatId).Limit(1).Find(&chat)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrChatNotFound
+		}
+
+		result = unexpired(tx).Where("messages.chat_id = ? AND messages.public_id = ?", chatId, messagePublicId).Limit(1).Find(&message)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMessageNotFound
+		}
+
+		changed, err := change(tx, &message)
+		if err != nil || !changed {
+			return err
+		}
+
+		if err := withChat(tx).Where("messages.id = ?", message.Id).Take(&message).Error; err != nil {
+			return err
+		}
+		return recordChange(tx, &model.Change{
+			Entity:          model.ChangeEntityMessage,
+			Action:          model.ChangeUpdated,
+			ChatId:          message.ChatId,
+			ChatPublicId:    message.ChatPublicId,
+			MessageId:       &message.Id,
+			MessagePublicId: &message.PublicId,
+		}, &message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if message.ChatPublicId == "" {
+		if err := withChat(r.db.WithContext(ctx)).Where("messages.id = ?", message.Id).Take(&message).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &message, nil
+}
+
+func (r *pinsRepo) List(ctx context.Context, chatId int64) ([]*model.Message, error) {
+	var messages []*model.Message
+
+	err := unexpired(withChat(r.db.WithContext(ctx))).
+		Where("messages.chat_id = ? AND messages.pinned_at IS NOT NULL", chatId).
+		Order("messages.pinned_at, messages.id").
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
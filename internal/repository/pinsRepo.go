@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+)
+
+type PinsRepository interface {
+	// Pin pins the message unless the chat already has maxPins pinned
+	// messages. Pinning a pinned message changes nothing.
+	Pin(ctx context.Context, chatId int64, messagePublicId, userId string, maxPins int) (*model.Message, error)
+	// Unpin unpins the message. Unpinning a message that is not pinned
+	// changes nothing.
+	Unpin(ctx context.Context, chatId int64, messagePublicId string) (*model.Message, error)
+	// List returns the pinned messages of the chat in the order they were
+	// pinned.
+	List(ctx context.Context, chatId int64) ([]*model.Message, error)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pollsRepo struct {
+	db *gorm.DB
+}
+
+func NewPollsRepo(db *gorm.DB) PollsRepository {
+	return &pollsRepo{db: db}
+}
+
+func (r *pollsRepo) Create(ctx context.Context, poll *model.Poll) error {
+	return r.db.WithContext(ctx).Create(poll).Error
+}
+
+func (r *pollsRepo) Latest(ctx context.Context, chatId int64) (*model.Poll, error) {
+	var poll model.Poll
+
+	result := r.db.WithContext(ctx).Where("chat_id = ?", chatId).Order("id desc").Limit(1).Find(&poll)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrPollNotFound
+	}
+
+	return &poll, nil
+}
+
+func (r *pollsRepo) Vote(ctx context.Context, poll *model.Poll, userId string, option int) ([]int, error) {
+	tally := make([]int, len(poll.Options))
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "poll_id"}, {Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"option"}),
+		}).Create(&pollVote{PollId: poll.Id, UserId: userId, Option: option}).Error
+		if err != nil {
+			return err
+		}
+
+		var counts []struct {
+			Option int
+			Votes  int
+		}
+		err = tx.Model(&pollVote{}).
+			Select("option, count(*) AS votes").
+			Where("poll_id = ?", poll.Id).
+			Group("option").
+			Scan(&counts).Error
+		if err != nil {
+			return err
+		}
+		for _, c := range counts {
+			if c.Option >= 0 && c.Option < len(tally) {
+				tally[c.Option] = c.Votes
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tally, nil
+}
+
+type pollVote struct {
+	PollId int64
+	UserId string
+	Option int
+}
+
+func (pollVote) TableName() string {
+	return "poll_votes"
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type readsRepo struct {
+	db *gorm.DB
+}
+
+func NewReadsRepo(db *gorm.DB) ReadsRepository {
+	return &readsRepo{db: db}
+}
+
+// MarkRead also returns the number of messages the user has left unread. A
+// receipt that moved is published as a chat.read event.
+func (r *readsRepo) MarkRead(ctx context.Context, chatId int64, userId string, seq int64) (*model.ReadReceipt, int64, error) {
+	var receipt model.ReadReceipt
+	var unread int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var chat model.Chat
+		result := tx.Select("id, public_id, last_seq").Where("id = ?", chatId).Limit(1).Find(&chat)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrChatNotFound
+		}
+		if seq <= 0 || seq > chat.LastSeq {
+			seq = chat.LastSeq
+		}
+
+		moved, err := advanceRead(tx, chatId, userId, seq)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("chat_id = ? AND user_id = ?", chatId, userId).Limit(1).Find(&receipt).Error; err != nil {
+			return err
+		}
+		receipt.ChatPublicId = chat.PublicId
+		err = tx.Raw("SELECT "+unreadCount("chat_reads.last_read_seq")+` FROM chats
+			JOIN chat_reads ON chat_reads.chat_id = chats.id AND chat_reads.user_id = ? WHERE chats.id = ?`, userId, chatId).
+			Scan(&unread).Error
+		if err != nil {
+			return err
+		}
+
+		if !moved {
+			return nil
+		}
+		return enqueueEvent(tx, model.NewReadEvent(&receipt), chat.PublicId)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &receipt, unread, nil
+}
+
+// unreadCount is an SQL expression for the number of messages of the chats
+// row past lastReadSeq that are still there. Seqs are handed out without
+// gaps, so rather than counting those messages it takes away from the seqs
+// past lastReadSeq the purged ones up to purged_seq, the gaps above it kept
+// in message_gaps and the expired messages the sweeper has not deleted yet.
+func unreadCount(lastReadSeq string) string {
+	from := "GREATEST(" + lastReadSeq + ", chats.purged_seq)"
+	return `(chats.last_seq - ` + from + `
+		- (SELECT count(*) FROM message_gaps WHERE message_gaps.chat_id = chats.id AND message_gaps.seq > ` + from + `)
+		- (SELECT count(*) FROM messages WHERE messages.chat_id = chats.id AND messages.seq > ` + from + `
+			AND messages.expires_at <= now()))`
+}
+
+// advanceRead moves the receipt forward to seq, together with the user's
+// notifications of the chat, and reports whether it moved.
+func advanceRead(tx *gorm.DB, chatId int64, userId string, seq int64) (bool, error) {
+	result := tx.Exec(`INSERT INTO chat_reads (chat_id, user_id, last_read_seq, updated_at) VALUES (?, ?, ?, now())
+		ON CONFLICT (chat_id, user_id) DO UPDATE SET last_read_seq = excluded.last_read_seq, updated_at = excluded.updated_at
+		WHERE chat_reads.last_read_seq < excluded.last_read_seq`, chatId, userId, seq)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return true, markNotificationsRead(tx, chatId, userId, seq)
+}
+
+// readLevel is how many receipts of a chat stop at a seq.
+type readLevel struct {
+	LastReadSeq int64
+	Readers     int64
+}
+
+// Readers counts readers from the number of receipts at each seq rather than
+// loading them, so a chat with many readers costs a grouped index scan and
+// limit rows per message.
+func (r *readsRepo) Readers(ctx context.Context, chatId int64, messages []*model.Message, limit int) (map[int64]*MessageReaders, error) {
+	readers := make(map[int64]*MessageReaders, len(messages))
+	if len(messages) == 0 {
+		return readers, nil
+	}
+	db := r.db.WithContext(ctx)
+
+	minSeq := messages[0].Seq
+	senders := make([]string, 0, len(messages))
+	for _, m := range messages {
+		minSeq = min(minSeq, m.Seq)
+		senders = append(senders, m.SenderId)
+	}
+
+	var levels []readLevel
+	err := db.Model(&model.ReadReceipt{}).
+		Select("last_read_seq, count(*) AS readers").
+		Where("chat_id = ? AND last_read_seq >= ?", chatId, minSeq).
+		Group("last_read_seq").
+		Order("last_read_seq").
+		Scan(&levels).Error
+	if err != nil {
+		return nil, err
+	}
+	// atOrPast[i] is the number of receipts at levels[i] or past it.
+	atOrPast := make([]int64, len(levels)+1)
+	for i := len(levels) - 1; i >= 0; i-- {
+		atOrPast[i] = atOrPast[i+1] + levels[i].Readers
+	}
+
+	var own []*model.ReadReceipt
+	err = db.Where("chat_id = ? AND user_id IN ?", chatId, slices.Compact(slices.Sorted(slices.Values(senders)))).
+		Find(&own).Error
+	if err != nil {
+		return nil, err
+	}
+	senderRead := make(map[string]int64, len(own))
+	for _, receipt := range own {
+		senderRead[receipt.UserId] = receipt.LastReadSeq
+	}
+
+	values := make([]string, 0, len(messages))
+	args := make([]any, 0, 2*len(messages)+2)
+	for _, m := range messages {
+		i, _ := slices.BinarySearchFunc(levels, m.Seq, func(l readLevel, seq int64) int {
+			return cmp.Compare(l.LastReadSeq, seq)
+		})
+		count := atOrPast[i]
+		if seq, ok := senderRead[m.SenderId]; ok && seq >= m.Seq {
+			count--
+		}
+		readers[m.Seq] = &MessageReaders{Count: count}
+
+		values = append(values, "(?::bigint, ?::text)")
+		args = append(args, m.Seq, m.SenderId)
+	}
+
+	var rows []struct {
+		Seq    int64
+		UserId string
+	}
+	err = db.Raw(`SELECT p.seq, r.user_id FROM (VALUES `+strings.Join(values, ", ")+`) AS p(seq, sender_id)
+		CROSS JOIN LATERAL (SELECT user_id FROM chat_reads
+			WHERE chat_id = ? AND last_read_seq >= p.seq AND user_id <> p.sender_id
+			ORDER BY user_id LIMIT ?) r
+		ORDER BY p.seq, r.user_id`, append(args, chatId, limit)...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		readers[row.Seq].UserIds = append(readers[row.Seq].UserIds, row.UserId)
+	}
+
+	return readers, nil
+}
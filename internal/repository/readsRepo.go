@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+)
+
+type ReadsRepository interface {
+	// MarkRead moves the user's receipt forward to seq, or to the last message
+	// when seq is 0 or past it. It never moves a receipt back.
+	MarkRead(ctx context.Context, chatId int64, userId string, seq int64) (*model.ReadReceipt, int64, error)
+	// Readers tells, by seq, how many users other than the sender have read
+	// each of the messages of the chat and who the first limit of them are.
+	Readers(ctx context.Context, chatId int64, messages []*model.Message, limit int) (map[int64]*MessageReaders, error)
+}
+
+// MessageReaders are the users other than the sender who have read a
+// message: all of them counted, UserIds the first by user id.
+type MessageReaders struct {
+	Count   int64
+	UserIds []string
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type scheduledRepo struct {
+	db *gorm.DB
+}
+
+func NewScheduledRepo(db *gorm.DB) ScheduledRepository {
+	return &scheduledRepo{db: db}
+}
+
+func withScheduledChat(db *gorm.DB) *gorm.DB {
+	return db.Model(&model.ScheduledMessage{}).
+		Select("scheduled_messages.*, chats.public_id AS chat_public_id").
+		Joins("JOIN chats ON chats.id = scheduled_messages.chat_id")
+}
+
+// unclaimed matches the messages no dispatcher is posting right now.
+func unclaimed(db *gorm.DB) *gorm.DB {
+	return db.Where("scheduled_messages.claimed_until IS NULL OR scheduled_messages.claimed_until < now()")
+}
+
+func (r *scheduledRepo) Create(ctx context.Context, message *model.ScheduledMessage) error {
+	db := r.db.WithContext(ctx)
+
+	// The dispatcher checks again when posting, in case the sender is no
+	// longer an admin by then.
+	var chat model.Chat
+	if err := db.Select("type").Take(&chat, message.ChatId).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrChatNotFound
+	} else if err != nil {
+		return err
+	}
+	if err := checkCanPost(db, message.ChatId, chat.Type, message.SenderId); err != nil {
+		return err
+	}
+
+	// The unique index on client_msg_id catches concurrent retries, and the
+	// failed insert leaves no transaction to roll back before the lookup.
+	err := db.Create(message).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) && message.ClientMsgId != nil {
+		err := withScheduledChat(db).
+			Where("scheduled_messages.chat_id = ? AND scheduled_messages.sender_id = ? AND scheduled_messages.client_msg_id = ?",
+				message.ChatId, message.SenderId, *message.ClientMsgId).
+			Take(message).Error
+		if err != nil {
+			return err
+		}
+		return ErrDuplicateMessage
+	}
+	if err != nil {
+		return err
+	}
+
+	return withScheduledChat(db).Where("scheduled_messages.id = ?", message.Id).Take(message).Error
+}
+
+func (r *scheduledRepo) List(ctx context.Context, chatId int64, senderId string) ([]*model.ScheduledMessage, error) {
+	var messages []*model.ScheduledMessage
+
+	err := withScheduledChat(r.db.WithContext(ctx)).
+		Where("scheduled_messages.chat_id = ? AND scheduled_messages.sender_id = ?", chatId, senderId).
+		Order("scheduled_messages.send_at, scheduled_messages.id").
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+func (r *scheduledRepo) Update(ctx context.Context, chatId int64, senderId, publicId string, update ScheduledUpdate) (*model.ScheduledMessage, error) {
+	updates := map[string]any{"updated_at": gorm.Expr("now()")}
+	if update.Text != nil {
+		updates["text"] = *update.Text
+	}
+	if update.SendAt != nil {
+		updates["send_at"] = update.SendAt.UTC()
+	}
+
+	var message model.ScheduledMessage
+	result := unclaimed(r.db.WithContext(ctx).Model(&model.ScheduledMessage{})).
+		Where("chat_id = ? AND sender_id = ? AND public_id = ?", chatId, senderId, publicId).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrScheduledNotFound
+	}
+
+	err := withScheduledChat(r.db.WithContext(ctx)).Where("scheduled_messages.public_id = ?", publicId).Take(&message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Posted right after the update.
+		return nil, ErrScheduledNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *scheduledRepo) Delete(ctx context.Context, chatId int64, senderId, publicId string) error {
+	result := unclaimed(r.db.WithContext(ctx)).
+		Where("chat_id = ? AND sender_id = ? AND public_id = ?", chatId, senderId, publicId).
+		Delete(&model.ScheduledMessage{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrScheduledNotFound
+	}
+	return nil
+}
+
+// ClaimDue leases due messages: they are not due again until the lease ends,
+// so another replica picks them up only if this one dies before posting them.
+func (r *scheduledRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.ScheduledMessage, error) {
+	var messages []*model.ScheduledMessage
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []int64
+		err := tx.Raw(`SELECT s.id FROM scheduled_messages s
+			JOIN chats c ON c.id = s.chat_id AND c.deleted_at IS NULL
+			WHERE s.send_at <= ? AND (s.claimed_until IS NULL OR s.claimed_until < ?)
+			ORDER BY s.send_at, s.id
+			LIMIT ? FOR UPDATE OF s SKIP LOCKED`, now, now, limit).
+			Scan(&ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		err = tx.Model(&model.ScheduledMessage{}).
+			Where("id IN ?", ids).
+			Update("claimed_until", now.Add(lease)).Error
+		if err != nil {
+			return err
+		}
+
+		return withScheduledChat(tx).
+			Where("scheduled_messages.id IN ?", ids).
+			Order("scheduled_messages.send_at, scheduled_messages.id").
+			Find(&messages).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (r *scheduledRepo) Sent(ctx context.Context, id int64) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&model.ScheduledMessage{}).Error
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"time"
+)
+
+// ScheduledUpdate holds the changes to a scheduled message; nil fields stay
+// as they are.
+type ScheduledUpdate struct {
+	Text   *string
+	SendAt *time.Time
+}
+
+type ScheduledRepository interface {
+	// Create stores a scheduled message. When the sender already scheduled a
+	// message with the same ClientMsgId in the chat, that one is loaded into
+	// message and ErrDuplicateMessage is returned.
+	Create(ctx context.Context, message *model.ScheduledMessage) error
+	// List returns the sender's pending messages in the chat by send time.
+	List(ctx context.Context, chatId int64, senderId string) ([]*model.ScheduledMessage, error)
+	// Update and Delete change only the sender's messages that no dispatcher
+	// holds; the others are reported as ErrScheduledNotFound.
+	Update(ctx context.Context, chatId int64, senderId, publicId string, update ScheduledUpdate) (*model.ScheduledMessage, error)
+	Delete(ctx context.Context, chatId int64, senderId, publicId string) error
+	// ClaimDue leases messages due at now in chats that are not in the trash.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.ScheduledMessage, error)
+	// Sent removes a posted message, or one that can never be posted.
+	Sent(ctx context.Context, id int64) error
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhooksRepo struct {
+	db *gorm.DB
+}
+
+func NewWebhooksRepo(db *gorm.DB) WebhooksRepository {
+	return &webhooksRepo{db: db}
+}
+
+func withWebhookChat(db *gorm.DB) *gorm.DB {
+	return db.Select("webhooks.*, chats.public_id AS chat_public_id").
+		Joins("LEFT JOIN chats ON chats.id = webhooks.chat_id")
+}
+
+func (r *webhooksRepo) Create(ctx context.Context, webhook *model.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *webhooksRepo) List(ctx context.Context) ([]*model.Webhook, error) {
+	var webhooks []*model.Webhook
+	if err := r.db.WithContext(ctx).Scopes(withWebhookChat).Order("webhooks.id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *webhooksRepo) Get(ctx context.Context, publicId string) (*model.Webhook, error) {
+	var webhook model.Webhook
+
+	result := r.db.WithContext(ctx).Scopes(withWebhookChat).
+		Where("webhooks.public_id = ?", publicId).
+		Limit(1).
+		Find(&webhook)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	return &webhook, nil
+}
+
+func (r *webhooksRepo) Delete(ctx context.Context, publicId string) error {
+	result := r.db.WithContext(ctx).Where("public_id = ?", publicId).Delete(&model.Webhook{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (r *webhooksRepo) Enable(ctx context.Context, publicId string) (*model.Webhook, error) {
+	result := r.db.WithContext(ctx).Model(&model.Webhook{}).
+		Where("public_id = ?", publicId).
+		Updates(map[string]any{"disabled_at": nil, "consecutive_failures": 0})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	return r.Get(ctx, publicId)
+}
+
+// FanOut queues deliveries for the changes after the last fanned out one,
+// which it tracks by change position.
+// The state row stays locked while fn runs, so replicas never queue the same
+// change twice.
+func (r *webhooksRepo) FanOut(ctx context.Context, fn func(lastChangeId int64) ([]*model.WebhookDelivery, int64, error)) (int, error) {
+	var queued int
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var lastChangeId int64
+		err := tx.Raw("SELECT last_change_id FROM webhook_state FOR UPDATE").Scan(&lastChangeId).Error
+		if err != nil {
+			return err
+		}
+
+		deliveries, newLastChangeId, err := fn(lastChangeId)
+		if err != nil || newLastChangeId == lastChangeId {
+			return err
+		}
+
+		if len(deliveries) > 0 {
+			result := tx.Omit("Webhook", "AttemptLog").
+				Clauses(clause.OnConflict{DoNothing: true}).
+				CreateInBatches(deliveries, 500)
+			if result.Error != nil {
+				return result.Error
+			}
+			queued = int(result.RowsAffected)
+		}
+
+		return tx.Exec("UPDATE webhook_state SET last_change_id = ?", newLastChangeId).Error
+	})
+
+	return queued, err
+}
+
+// ClaimDue leases due deliveries of enabled webhooks: they are not due again
+// until the lease ends, so another replica picks them up only if this one
+// dies before recording the attempt.
+func (r *webhooksRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []int64
+		err := tx.Raw(`SELECT d.id FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id AND w.disabled_at IS NULL
+			WHERE d.status = ? AND d.next_attempt_at <= ?
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT ? FOR UPDATE OF d SKIP LOCKED`, model.DeliveryPending, now, limit).
+			Scan(&ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		err = tx.Model(&model.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Preload("Webhook").Where("id IN ?", ids).Order("id").Find(&deliveries).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhooksRepo) RecordAttempt(ctx context.Context, delivery *model.WebhookDelivery, attempt *model.WebhookAttempt, result DeliveryResult) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		attempt.DeliveryId = delivery.Id
+		if err := tx.Create(attempt).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]any{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      attempt.Error,
+			"next_attempt_at": result.NextAttemptAt,
+		}
+		switch {
+		case result.Succeeded:
+			updates["status"] = model.DeliverySucceeded
+			updates["delivered_at"] = attempt.CreatedAt
+		case result.NextAttemptAt == nil:
+			updates["status"] = model.DeliveryFailed
+		}
+		if err := tx.Model(&model.WebhookDelivery{}).Where("id = ?", delivery.Id).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if result.Succeeded {
+			return tx.Exec("UPDATE webhooks SET consecutive_failures = 0 WHERE id = ?", delivery.WebhookId).Error
+		}
+		return tx.Exec(`UPDATE webhooks SET consecutive_failures = consecutive_failures + 1,
+				disabled_at = CASE WHEN consecutive_failures + 1 >= ? THEN coalesce(disabled_at, now()) ELSE disabled_at END
+			WHERE id = ?`, result.DisableAfter, delivery.WebhookId).Error
+	})
+}
+
+func (r *webhooksRepo) ListDeliveries(ctx context.Context, webhookId int64, limit int) ([]*model.WebhookDelivery, error) {
+	var deliveries []*model.WebhookDelivery
+
+	err := r.db.WithContext(ctx).
+		Preload("AttemptLog", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Where("webhook_id = ?", webhookId).
+		Order("id desc").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *webhooksRepo) PruneDeliveries(ctx context.Context, createdBefore time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status <> ? AND created_at < ?", model.DeliveryPending, createdBefore).
+		Delete(&model.WebhookDelivery{})
+
+	return result.RowsAffected, result.Error
+}
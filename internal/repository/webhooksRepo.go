@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"chats-api/internal/model"
+	"context"
+	"time"
+)
+
+type WebhooksRepository interface {
+	Create(ctx context.Context, webhook *model.Webhook) error
+	List(ctx context.Context) ([]*model.Webhook, error)
+	Get(ctx context.Context, publicId string) (*model.Webhook, error)
+	Delete(ctx context.Context, publicId string) error
+	Enable(ctx context.Context, publicId string) (*model.Webhook, error)
+	FanOut(ctx context.Context, fn func(lastChangeId int64) (deliveries []*model.WebhookDelivery, newLastChangeId int64, err error)) (int, error)
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error)
+	RecordAttempt(ctx context.Context, delivery *model.WebhookDelivery, attempt *model.WebhookAttempt, result DeliveryResult) error
+	ListDeliveries(ctx context.Context, webhookId int64, limit int) ([]*model.WebhookDelivery, error)
+	PruneDeliveries(ctx context.Context, createdBefore time.Time) (int64, error)
+}
+
+// DeliveryResult is what an attempt means for the delivery and its webhook.
+// A nil NextAttemptAt on failure gives the delivery up. The webhook is
+// disabled once its consecutive failures reach DisableAfter.
+type DeliveryResult struct {
+	Succeeded     bool
+	NextAttemptAt *time.Time
+	DisableAfter  int
+}
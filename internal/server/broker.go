@@ -0,0 +1,22 @@
+package server
+
+import (
+	"chats-api/internal/broker"
+	"chats-api/internal/config"
+	"context"
+)
+
+// newBroker returns nil when events are not published anywhere.
+func newBroker(conf *config.OutboxConf) (broker.Broker, error) {
+	switch conf.Broker {
+	case "nats":
+		return broker.NewNATS(context.Background(), broker.NATSConfig{
+			Url:     conf.NatsUrl,
+			Stream:  conf.NatsStream,
+			Subject: conf.Subject,
+		})
+	case "kafka":
+		return broker.NewKafka(broker.KafkaConfig{Brokers: conf.KafkaBrokers}), nil
+	}
+	return nil, nil
+}
@@ -1,28 +1,39 @@
 package server
 
 import (
+	"chats-api/internal/broker"
 	"chats-api/internal/config"
 	"chats-api/internal/handler"
 	"chats-api/internal/model"
+	"chats-api/internal/realtime"
 	"chats-api/internal/repository"
 	"chats-api/internal/services"
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/pressly/goose/v3"
 	"gorm.io/gorm"
 )
 
+// shutdownTimeout bounds how long requests in flight may finish on shutdown.
+const shutdownTimeout = 15 * time.Second
+
 type Server struct {
 	router  http.Handler
 	conf    *config.Config
 	logger  *slog.Logger
 	handler *handler.Handler
+	workers []worker
+	events  broker.Broker
 }
 
 func NewServer(conf *config.Config) (*Server, error) {
@@ -36,16 +47,90 @@ func NewServer(conf *config.Config) (*Server, error) {
 		return nil, errors.New("db error: " + err.Error())
 	}
 
-	if err := runMigrations(db); err != nil {
+	if err := runMigrations(db, conf.PostgresConf.Reset); err != nil {
 		return nil, errors.New("error " + err.Error())
 	}
+	store, err := newBlobStore(conf.Attachments)
+	if err != nil {
+		return nil, errors.New("blob store error: " + err.Error())
+	}
+	events, err := newBroker(conf.Outbox)
+	if err != nil {
+		return nil, errors.New("broker error: " + err.Error())
+	}
+	urls, err := newURLSigner(conf)
+	if err != nil {
+		return nil, errors.New("url signer error: " + err.Error())
+	}
+
 	chatsRepo := repository.NewChatsRepo(db)
 	messagesRepo := repository.NewMessagesRepo(db)
+	changesRepo := repository.NewChangesRepo(db)
+	attachmentsRepo := repository.NewAttachmentsRepo(db)
+	webhooksRepo := repository.NewWebhooksRepo(db)
+	outboxRepo := repository.NewOutboxRepo(db)
+	membersRepo := repository.NewMembersRepo(db)
+	hooksRepo := repository.NewIncomingHooksRepo(db)
+	botsRepo := repository.NewBotsRepo(db)
+	pollsRepo := repository.NewPollsRepo(db)
+	readsRepo := repository.NewReadsRepo(db)
+	notificationsRepo := repository.NewNotificationsRepo(db)
+	pinsRepo := repository.NewPinsRepo(db)
+	scheduledRepo := repository.NewScheduledRepo(db)
+
+	hub := realtime.NewHub(conf.Realtime.BufferSize)
+	presence := realtime.NewPresence(hub, conf.Realtime.TypingTTL, conf.Realtime.PresenceTTL)
+
+	chats := services.NewChatsRepository(chatsRepo, conf.Chats.RestoreGracePeriod)
+	messages := services.NewMessagesRepository(messagesRepo, readsRepo, presence, urls)
+	sync := services.NewSyncService(changesRepo, chatsRepo, messagesRepo, urls, conf.Sync.ChangeLogRetention)
+	attachments := services.NewAttachmentsService(attachmentsRepo, store, urls, services.AttachmentLimits{
+		MaxSize:      conf.Attachments.MaxSize,
+		AllowedTypes: conf.Attachments.AllowedTypes,
+		CleanupAfter: conf.Attachments.CleanupAfter,
+	})
+	members := services.NewMembersService(membersRepo)
+	hooks := services.NewIncomingHooksService(hooksRepo, conf.IncomingHooks.RateLimit, conf.IncomingHooks.Burst)
+	bots := services.NewBotsService(botsRepo)
+	commands := services.NewCommandsService(botsRepo, chatsRepo, pollsRepo, messages, conf.Bots.Timeout)
+	reads := services.NewReadsService(readsRepo)
+	notifications := services.NewNotificationsService(notificationsRepo, messagesRepo, urls, conf.Notifications.BatchSize)
+	pins := services.NewPinsService(pinsRepo, conf.Chats.MaxPins, urls)
+	scheduled := services.NewScheduledService(scheduledRepo, messages, commands, conf.Scheduled.Lease, conf.Scheduled.BatchSize)
+	feed := services.NewRealtimeService(outboxRepo, hub, conf.Realtime.BatchSize)
+	retention := services.NewRetentionService(chatsRepo, messagesRepo, conf.Retention.BatchSize)
+
+	imports := services.NewImportService(chatsRepo)
+	webhooks := services.NewWebhooksService(webhooksRepo, changesRepo, chatsRepo, messagesRepo, services.WebhookSettings{
+		Timeout:      conf.Webhooks.Timeout,
+		MaxAttempts:  conf.Webhooks.MaxAttempts,
+		BackoffBase:  conf.Webhooks.BackoffBase,
+		BackoffMax:   conf.Webhooks.BackoffMax,
+		DisableAfter: conf.Webhooks.DisableAfter,
+		KeepFor:      conf.Webhooks.KeepDeliveries,
+	})
 
-	chats := services.NewChatsRepository(chatsRepo)
-	messages := services.NewMessagesRepository(messagesRepo)
+	outbox := services.NewOutboxService(outboxRepo, events, services.OutboxSettings{
+		Subject:   conf.Outbox.Subject,
+		BatchSize: conf.Outbox.BatchSize,
+		KeepFor:   conf.Outbox.KeepFor,
+	})
 
-	h := handler.NewHandler(chats, messages, logger)
+	h := handler.NewHandler(chats, messages, logger,
+		handler.WithSync(sync),
+		handler.WithImport(imports),
+		handler.WithAttachments(attachments),
+		handler.WithWebhooks(webhooks),
+		handler.WithMembers(members),
+		handler.WithIncomingHooks(hooks),
+		handler.WithBots(bots),
+		handler.WithCommands(commands),
+		handler.WithReads(reads),
+		handler.WithRealtime(hub, presence),
+		handler.WithNotifications(notifications),
+		handler.WithPins(pins),
+		handler.WithScheduled(scheduled),
+		handler.WithAdminToken(conf.Admin.Token))
 
 	hdlr := configureMux(h, conf.ApiVersion)
 
@@ -54,16 +139,128 @@ func NewServer(conf *config.Config) (*Server, error) {
 		logger:  logger,
 		conf:    conf,
 		handler: h,
+		events:  events,
+		workers: []worker{
+			{name: "deleted chats purger", interval: conf.Chats.PurgeInterval, run: func(ctx context.Context) error {
+				purged, err := chats.PurgeDeletedChats(ctx)
+				if purged > 0 {
+					logger.Info(fmt.Sprintf("purged %d deleted chats", purged))
+				}
+				return err
+			}},
+			{name: "change log sequencer", interval: conf.Sync.SequenceInterval, run: func(ctx context.Context) error {
+				_, err := sync.SequenceChanges(ctx)
+				return err
+			}},
+			{name: "change log pruner", interval: conf.Sync.PruneInterval, run: func(ctx context.Context) error {
+				pruned, err := sync.PruneChanges(ctx)
+				if err == nil && pruned > 0 {
+					logger.Info(fmt.Sprintf("pruned %d sync changes", pruned))
+				}
+				return err
+			}},
+			{name: "retention purger", interval: conf.Retention.Interval, run: func(ctx context.Context) error {
+				purges, err := retention.PurgeExpiredMessages(ctx)
+				for _, p := range purges {
+					logger.Info(fmt.Sprintf("purged %d messages from chat %s by %s retention", p.Purged, p.ChatPublicId, p.Reason))
+				}
+				return err
+			}},
+			{name: "expired messages sweeper", interval: conf.Retention.SweepInterval, run: func(ctx context.Context) error {
+				swept, err := retention.SweepExpiredMessages(ctx)
+				if swept > 0 {
+					logger.Info(fmt.Sprintf("deleted %d expired messages", swept))
+				}
+				return err
+			}},
+			{name: "attachments cleaner", interval: conf.Attachments.CleanupInterval, run: func(ctx context.Context) error {
+				unattached, blobs, err := attachments.Cleanup(ctx)
+				if unattached > 0 || blobs > 0 {
+					logger.Info(fmt.Sprintf("removed %d unattached uploads and %d unused blobs", unattached, blobs))
+				}
+				return err
+			}},
+			{name: "webhook dispatcher", interval: conf.Webhooks.Interval, run: func(ctx context.Context) error {
+				d, err := webhooks.Dispatch(ctx)
+				if d.Queued > 0 || d.Delivered > 0 || d.Failed > 0 {
+					logger.Info(fmt.Sprintf("webhooks: queued %d, delivered %d, failed %d", d.Queued, d.Delivered, d.Failed))
+				}
+				return err
+			}},
+			{name: "scheduled messages dispatcher", interval: conf.Scheduled.Interval, run: func(ctx context.Context) error {
+				posted, err := scheduled.DispatchScheduled(ctx)
+				if posted > 0 {
+					logger.Info(fmt.Sprintf("posted %d scheduled messages", posted))
+				}
+				return err
+			}},
+			{name: "@all notifier", interval: conf.Notifications.Interval, run: func(ctx context.Context) error {
+				notified, err := notifications.NotifyPendingAll(ctx)
+				if notified > 0 {
+					logger.Info(fmt.Sprintf("finished %d @all notifications", notified))
+				}
+				return err
+			}},
+			{name: "outbox relay", interval: conf.Outbox.Interval, run: func(ctx context.Context) error {
+				_, err := outbox.Relay(ctx)
+				return err
+			}},
+			{name: "realtime feed", interval: conf.Realtime.Interval, run: func(ctx context.Context) error {
+				_, err := feed.Feed(ctx)
+				return err
+			}},
+			{name: "presence expirer", interval: conf.Realtime.Interval, run: func(ctx context.Context) error {
+				presence.Expire(time.Now())
+				return nil
+			}},
+			{name: "outbox cleaner", interval: conf.Outbox.CleanupInterval, run: func(ctx context.Context) error {
+				deleted, err := outbox.Cleanup(ctx)
+				if deleted > 0 {
+					logger.Info(fmt.Sprintf("removed %d published outbox events", deleted))
+				}
+				return err
+			}},
+			{name: "webhook deliveries pruner", interval: conf.Webhooks.PruneInterval, run: func(ctx context.Context) error {
+				pruned, err := webhooks.PruneDeliveries(ctx)
+				if pruned > 0 {
+					logger.Info(fmt.Sprintf("pruned %d webhook deliveries", pruned))
+				}
+				return err
+			}},
+		},
 	}, nil
 }
 
+// Start serves until SIGINT or SIGTERM, then lets requests in flight and the
+// workers finish before closing the broker.
 func (s *Server) Start() error {
-	s.logger.Info("starting server...")
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := http.ListenAndServe("0.0.0.0:8080", s.router); err != nil {
-		return err
+	waitWorkers := s.startWorkers(ctx)
+
+	s.logger.Info("starting server on port " + s.conf.ApiPort)
+
+	srv := &http.Server{Addr: "0.0.0.0:" + s.conf.ApiPort, Handler: s.router}
+	served := make(chan error, 1)
+	go func() { served <- srv.ListenAndServe() }()
+
+	var err error
+	select {
+	case err = <-served:
+	case <-ctx.Done():
+		s.logger.Info("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		err = srv.Shutdown(shutdownCtx)
+		cancel()
 	}
-	return nil
+
+	stop()
+	waitWorkers()
+	if s.events != nil {
+		err = errors.Join(err, s.events.Close())
+	}
+	return err
 }
 
 func setupLogger() (*slog.Logger, error) {
@@ -88,7 +285,9 @@ func setupLogger() (*slog.Logger, error) {
 	return logger, nil
 }
 
-func runMigrations(db *gorm.DB) error {
+// runMigrations brings the schema up to date, first rolling every migration
+// back when reset is set.
+func runMigrations(db *gorm.DB, reset bool) error {
 
 	sql, err := db.DB()
 	if err != nil {
@@ -102,8 +301,10 @@ func runMigrations(db *gorm.DB) error {
 
 	goose.SetLogger(log.New(os.Stdout, "[goose]", 0))
 
-	if err := goose.DownTo(sql, migrationsDir, 0); err != nil {
-		return err
+	if reset {
+		if err := goose.DownTo(sql, migrationsDir, 0); err != nil {
+			return err
+		}
 	}
 	if err := goose.Up(sql, migrationsDir); err != nil {
 		return err
@@ -116,12 +317,55 @@ func runMigrations(db *gorm.DB) error {
 func configureMux(h *handler.Handler, apiVersion string) http.Handler {
 	mux := http.NewServeMux()
 
-	apiPrefix := fmt.Sprintf("/api/%s/chats", apiVersion)
+	apiBase := fmt.Sprintf("/api/%s", apiVersion)
+	apiPrefix := apiBase + "/chats"
 
 	mux.HandleFunc("POST "+apiPrefix, h.HandleChatsCreate())
+	mux.HandleFunc("GET "+apiPrefix, h.HandleChatsList())
+	mux.HandleFunc("GET "+apiPrefix+"/trash", h.HandleChatsTrash())
+	mux.HandleFunc("POST "+apiPrefix+"/{id}/restore", h.HandleChatsRestore())
+	mux.HandleFunc("PUT "+apiPrefix+"/{id}/retention", h.HandleChatsRetention())
+	mux.HandleFunc("PUT "+apiPrefix+"/{id}/expiration", h.HandleChatsExpiration())
+	mux.HandleFunc("GET "+apiPrefix+"/{id}/export", h.HandleChatsExport())
 	mux.HandleFunc("POST "+apiPrefix+"/{id}/messages", h.HandleMessagesCreate())
+	mux.HandleFunc("POST "+apiPrefix+"/{id}/read", h.HandleChatsRead())
+	mux.HandleFunc("GET "+apiPrefix+"/{id}/online", h.HandleChatsOnline())
+	mux.HandleFunc("POST "+apiPrefix+"/{id}/attachments", h.HandleAttachmentsUpload())
+	mux.HandleFunc("GET "+apiPrefix+"/{id}/members", h.HandleMembersList())
+	mux.HandleFunc("PUT "+apiPrefix+"/{id}/members/{userId}", h.HandleMembersSet())
+	mux.HandleFunc("DELETE "+apiPrefix+"/{id}/members/{userId}", h.HandleMembersRemove())
+	mux.HandleFunc("GET "+apiPrefix+"/{id}/pins", h.HandlePinsList())
+	mux.HandleFunc("PUT "+apiPrefix+"/{id}/pins/{messageId}", h.HandlePinsPin())
+	mux.HandleFunc("DELETE "+apiPrefix+"/{id}/pins/{messageId}", h.HandlePinsUnpin())
+	mux.HandleFunc("GET "+apiPrefix+"/{id}/scheduled", h.HandleScheduledList())
+	mux.HandleFunc("PATCH "+apiPrefix+"/{id}/scheduled/{scheduledId}", h.HandleScheduledUpdate())
+	mux.HandleFunc("DELETE "+apiPrefix+"/{id}/scheduled/{scheduledId}", h.HandleScheduledCancel())
+	mux.HandleFunc("POST "+apiPrefix+"/{id}/hooks", h.HandleHooksCreate())
+	mux.HandleFunc("GET "+apiPrefix+"/{id}/hooks", h.HandleHooksList())
+	mux.HandleFunc("DELETE "+apiPrefix+"/{id}/hooks/{hookId}", h.HandleHooksRevoke())
 	mux.HandleFunc("GET "+apiPrefix+"/{id}", h.HandleMessagesGet())
 	mux.HandleFunc("DELETE "+apiPrefix+"/{id}", h.HandleChatsDelete())
 
-	return mux
+	mux.HandleFunc("POST "+apiBase+"/dm/{userId}", h.HandleDirectOpen())
+	mux.HandleFunc("GET "+apiBase+"/sync", h.HandleSync())
+	mux.HandleFunc("GET "+apiBase+"/realtime", h.HandleRealtime())
+	mux.HandleFunc("GET "+apiBase+"/me/notifications", h.HandleNotificationsList())
+	mux.HandleFunc("POST "+apiBase+"/me/notifications/read", h.HandleNotificationsRead())
+	mux.HandleFunc("GET "+apiBase+"/attachments/{id}", h.HandleAttachmentsDownload())
+	mux.HandleFunc("POST "+apiBase+"/hooks/{token}", h.HandleHookPost())
+	mux.HandleFunc("POST "+apiBase+"/hooks/{token}/attachments", h.HandleHookUpload())
+	mux.HandleFunc("POST "+apiBase+"/admin/import", h.HandleAdminImport())
+	mux.HandleFunc("POST "+apiBase+"/admin/webhooks", h.HandleWebhooksCreate())
+	mux.HandleFunc("GET "+apiBase+"/admin/webhooks", h.HandleWebhooksList())
+	mux.HandleFunc("DELETE "+apiBase+"/admin/webhooks/{id}", h.HandleWebhooksDelete())
+	mux.HandleFunc("POST "+apiBase+"/admin/webhooks/{id}/enable", h.HandleWebhooksEnable())
+	mux.HandleFunc("GET "+apiBase+"/admin/webhooks/{id}/deliveries", h.HandleWebhooksDeliveries())
+	mux.HandleFunc("POST "+apiBase+"/admin/bots", h.HandleBotsCreate())
+	mux.HandleFunc("GET "+apiBase+"/admin/bots", h.HandleBotsList())
+	mux.HandleFunc("DELETE "+apiBase+"/admin/bots/{id}", h.HandleBotsDelete())
+	mux.HandleFunc("POST "+apiBase+"/admin/bots/{id}/key", h.HandleBotsRotateKey())
+
+	mux.HandleFunc("GET /debug/vars", h.HandleMetrics())
+
+	return h.Authenticate(mux)
 }
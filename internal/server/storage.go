@@ -0,0 +1,39 @@
+package server
+
+import (
+	"chats-api/internal/blob"
+	"chats-api/internal/config"
+	"chats-api/internal/services"
+	"context"
+	"crypto/rand"
+	"fmt"
+)
+
+func newBlobStore(conf *config.AttachmentsConf) (blob.Store, error) {
+	if conf.Storage == "s3" {
+		return blob.NewS3Store(context.Background(), blob.S3Config{
+			Endpoint:  conf.S3Endpoint,
+			Bucket:    conf.S3Bucket,
+			Region:    conf.S3Region,
+			AccessKey: conf.S3AccessKey,
+			SecretKey: conf.S3SecretKey,
+			UseSSL:    conf.S3UseSSL,
+		})
+	}
+	return blob.NewLocalStore(conf.LocalDir)
+}
+
+// newURLSigner falls back to a random key, which is fine for a single
+// instance but invalidates links on restart and across replicas.
+func newURLSigner(conf *config.Config) (*services.URLSigner, error) {
+	key := []byte(conf.Attachments.SigningKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	prefix := fmt.Sprintf("/api/%s/attachments", conf.ApiVersion)
+	return services.NewURLSigner(key, conf.Attachments.UrlTTL, prefix), nil
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type worker struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+// startWorkers runs the workers until ctx is done; the returned wait blocks
+// until all of them have returned.
+func (s *Server) startWorkers(ctx context.Context) (wait func()) {
+	var wg sync.WaitGroup
+	for _, w := range s.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runWorker(ctx, w)
+		}()
+	}
+	return wg.Wait
+}
+
+func (s *Server) runWorker(ctx context.Context, w worker) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.run(ctx); err != nil {
+			s.logger.Error(fmt.Sprintf("%s failed: %v", w.name, err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
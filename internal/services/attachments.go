@@ -0,0 +1,336 @@
+package services
+
+import (
+	"bytes"
+	"chats-api/internal/blob"
+	"chats-api/internal/imaging"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxFileNameLen   = 255
+	blobCleanupBatch = 100
+)
+
+var ErrInvalidSignature = errors.New("download link is invalid or expired")
+
+type AttachmentTooLargeError struct {
+	Limit int64
+}
+
+func (e *AttachmentTooLargeError) Error() string {
+	return fmt.Sprintf("attachment is larger than %d bytes", e.Limit)
+}
+
+type AttachmentTypeError struct {
+	ContentType string
+}
+
+func (e *AttachmentTypeError) Error() string {
+	return fmt.Sprintf("attachments of type %s are not allowed", e.ContentType)
+}
+
+type AttachmentsService interface {
+	Upload(ctx context.Context, input NewAttachment) (*model.Attachment, error)
+	Open(ctx context.Context, publicId, variant string, expires int64, signature string) (*Download, error)
+	Cleanup(ctx context.Context) (attachments int64, blobs int64, err error)
+}
+
+type NewAttachment struct {
+	ChatId     int64
+	UploaderId string
+	FileName   string
+	Content    io.Reader
+}
+
+type AttachmentLimits struct {
+	MaxSize      int64
+	AllowedTypes []string
+	CleanupAfter time.Duration
+}
+
+type attachmentsService struct {
+	repo   repository.AttachmentsRepository
+	store  blob.Store
+	urls   *URLSigner
+	limits AttachmentLimits
+}
+
+func NewAttachmentsService(repo repository.AttachmentsRepository, store blob.Store, urls *URLSigner, limits AttachmentLimits) AttachmentsService {
+	return &attachmentsService{repo: repo, store: store, urls: urls, limits: limits}
+}
+
+// Upload spools the content to a temporary file to check its size and sniff
+// its type. Images lose their GPS metadata and get thumbnails. Every file is
+// stored under its SHA-256 unless an identical one is stored already.
+func (s *attachmentsService) Upload(ctx context.Context, input NewAttachment) (*model.Attachment, error) {
+	tmp, err := os.CreateTemp("", "chats-upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, io.LimitReader(input.Content, s.limits.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > s.limits.MaxSize {
+		return nil, &AttachmentTooLargeError{Limit: s.limits.MaxSize}
+	}
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !s.allowed(contentType) {
+		return nil, &AttachmentTypeError{ContentType: contentType}
+	}
+
+	attachment := &model.Attachment{
+		ChatId:      input.ChatId,
+		UploaderId:  input.UploaderId,
+		FileName:    cleanFileName(input.FileName),
+		ContentType: contentType,
+		Size:        size,
+	}
+
+	var content io.ReadSeeker = tmp
+	if imaging.Supported(contentType) {
+		data, err := io.ReadAll(io.NewSectionReader(tmp, 0, size))
+		if err != nil {
+			return nil, err
+		}
+		data = imaging.StripLocation(data, contentType)
+		content, attachment.Size = bytes.NewReader(data), int64(len(data))
+
+		// A file that only looks like an image is kept as is, just without
+		// dimensions and thumbnails.
+		if info, err := imaging.Process(data, contentType); err == nil {
+			attachment.Width, attachment.Height = &info.Width, &info.Height
+			for _, v := range info.Variants {
+				key, err := s.storeBlob(ctx, bytes.NewReader(v.Data), int64(len(v.Data)), v.ContentType)
+				if err != nil {
+					return nil, err
+				}
+				attachment.Variants = append(attachment.Variants, &model.AttachmentVariant{
+					Name:        v.Name,
+					BlobKey:     key,
+					ContentType: v.ContentType,
+					Width:       v.Width,
+					Height:      v.Height,
+					Size:        int64(len(v.Data)),
+				})
+			}
+		}
+	}
+
+	if attachment.BlobKey, err = s.storeBlob(ctx, content, attachment.Size, contentType); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, attachment); err != nil {
+		return nil, err
+	}
+	s.urls.signAttachments(attachment)
+
+	return attachment, nil
+}
+
+// storeBlob saves content under its hash and returns the key.
+func (s *attachmentsService) storeBlob(ctx context.Context, content io.ReadSeeker, size int64, contentType string) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	key := hex.EncodeToString(hash.Sum(nil))
+
+	if err := s.repo.TouchBlob(ctx, &model.Blob{Key: key, Size: size, ContentType: contentType}); err != nil {
+		return "", err
+	}
+	exists, err := s.store.Exists(ctx, key)
+	if err != nil || exists {
+		return key, err
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return key, s.store.Put(ctx, key, content, size, contentType)
+}
+
+func (s *attachmentsService) allowed(contentType string) bool {
+	for _, t := range s.limits.AllowedTypes {
+		if t == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Download is an attachment or one of its variants opened for reading.
+type Download struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Content     io.ReadCloser
+}
+
+// Open checks a signed link and opens the attachment, or its variant when
+// variant is not empty.
+func (s *attachmentsService) Open(ctx context.Context, publicId, variant string, expires int64, signature string) (*Download, error) {
+	if !s.urls.verify(publicId, variant, expires, signature) {
+		return nil, ErrInvalidSignature
+	}
+
+	attachment, err := s.repo.Get(ctx, publicId)
+	if err != nil {
+		return nil, err
+	}
+
+	download := &Download{FileName: attachment.FileName, ContentType: attachment.ContentType, Size: attachment.Size}
+	key := attachment.BlobKey
+	if variant != "" {
+		i := slices.IndexFunc(attachment.Variants, func(v *model.AttachmentVariant) bool { return v.Name == variant })
+		if i < 0 {
+			return nil, repository.ErrAttachmentNotFound
+		}
+		v := attachment.Variants[i]
+		key, download.ContentType, download.Size = v.BlobKey, v.ContentType, v.Size
+		download.FileName = variantFileName(attachment.FileName, v)
+	}
+
+	if download.Content, err = s.store.Get(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return download, nil
+}
+
+func variantFileName(name string, v *model.AttachmentVariant) string {
+	ext := ".png"
+	if v.ContentType == "image/jpeg" {
+		ext = ".jpg"
+	}
+	return strings.TrimSuffix(name, path.Ext(name)) + "-" + v.Name + ext
+}
+
+// Cleanup drops uploads that never made it into a message and then the blobs
+// nothing refers to any more, including those of purged messages.
+func (s *attachmentsService) Cleanup(ctx context.Context) (int64, int64, error) {
+	before := time.Now().Add(-s.limits.CleanupAfter)
+
+	attachments, err := s.repo.DeleteUnattached(ctx, before)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var blobs int64
+	for {
+		deleted, err := s.repo.DeleteUnusedBlobs(ctx, before, blobCleanupBatch, func(keys []string) error {
+			for _, key := range keys {
+				if err := s.store.Delete(ctx, key); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		blobs += deleted
+		if err != nil || deleted < blobCleanupBatch {
+			return attachments, blobs, err
+		}
+	}
+}
+
+func cleanFileName(name string) string {
+	name = strings.ToValidUTF8(name, "")
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = normalizeText(name, false)
+	for utf8.RuneCountInString(name) > maxFileNameLen {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+// URLSigner issues short-lived download links for attachments, signed with
+// HMAC-SHA256 so the download endpoint needs no other credentials.
+type URLSigner struct {
+	key    []byte
+	ttl    time.Duration
+	prefix string
+}
+
+func NewURLSigner(key []byte, ttl time.Duration, prefix string) *URLSigner {
+	return &URLSigner{key: key, ttl: ttl, prefix: prefix}
+}
+
+// URL links to the attachment, or to its variant when variant is not empty.
+func (s *URLSigner) URL(publicId, variant string) string {
+	expires := time.Now().Add(s.ttl).Unix()
+	q := url.Values{}
+	if variant != "" {
+		q.Set("variant", variant)
+	}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("sig", s.signature(publicId, variant, expires))
+	return s.prefix + "/" + publicId + "?" + q.Encode()
+}
+
+func (s *URLSigner) signature(publicId, variant string, expires int64) string {
+	mac := hmac.New(sha256.New, s.key)
+	fmt.Fprintf(mac, "%s:%s:%d", publicId, variant, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *URLSigner) verify(publicId, variant string, expires int64, signature string) bool {
+	if s == nil || time.Now().Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(s.signature(publicId, variant, expires)))
+}
+
+func (s *URLSigner) signAttachments(attachments ...*model.Attachment) {
+	if s == nil {
+		return
+	}
+	for _, a := range attachments {
+		a.Url = s.URL(a.PublicId, "")
+		for _, v := range a.Variants {
+			v.Url = s.URL(a.PublicId, v.Name)
+		}
+	}
+}
+
+func (s *URLSigner) signMessages(messages ...*model.Message) {
+	for _, m := range messages {
+		if m != nil {
+			s.signAttachments(m.Attachments...)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package services_test
+
+import (
+	"bytes"
+	"chats-api/internal/blob"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"image"
+	"image/jpeg"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAttachmentsRepo struct {
+	blobs       map[string]*model.Blob
+	attachments map[string]*model.Attachment
+}
+
+func newFakeAttachmentsRepo() *fakeAttachmentsRepo {
+	return &fakeAttachmentsRepo{blobs: map[string]*model.Blob{}, attachments: map[string]*model.Attachment{}}
+}
+
+func (r *fakeAttachmentsRepo) TouchBlob(ctx context.Context, blob *model.Blob) error {
+	r.blobs[blob.Key] = blob
+	return nil
+}
+
+func (r *fakeAttachmentsRepo) Create(ctx context.Context, attachment *model.Attachment) error {
+	attachment.PublicId = model.NewPublicId()
+	r.attachments[attachment.PublicId] = attachment
+	return nil
+}
+
+func (r *fakeAttachmentsRepo) Get(ctx context.Context, publicId string) (*model.Attachment, error) {
+	if a, ok := r.attachments[publicId]; ok {
+		return a, nil
+	}
+	return nil, repository.ErrAttachmentNotFound
+}
+
+func (r *fakeAttachmentsRepo) DeleteUnattached(ctx context.Context, createdBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeAttachmentsRepo) DeleteUnusedBlobs(ctx context.Context, lastUsedBefore time.Time, limit int, remove func(keys []string) error) (int64, error) {
+	return 0, nil
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newAttachmentsService(t *testing.T, maxSize int64) (services.AttachmentsService, *fakeAttachmentsRepo, blob.Store) {
+	store, err := blob.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	repo := newFakeAttachmentsRepo()
+	urls := services.NewURLSigner([]byte("key"), time.Minute, "/api/v1/attachments")
+
+	return services.NewAttachmentsService(repo, store, urls, services.AttachmentLimits{
+		MaxSize:      maxSize,
+		AllowedTypes: []string{"image/*", "text/plain"},
+		CleanupAfter: time.Hour,
+	}), repo, store
+}
+
+func TestAttachmentsService_UploadDeduplicatesContent(t *testing.T) {
+	svc, repo, _ := newAttachmentsService(t, 64)
+	ctx := context.Background()
+
+	first, err := svc.Upload(ctx, services.NewAttachment{ChatId: 1, UploaderId: "u-1", FileName: "../../etc/a.png", Content: bytes.NewReader(pngHeader)})
+	require.NoError(t, err)
+	second, err := svc.Upload(ctx, services.NewAttachment{ChatId: 1, UploaderId: "u-2", FileName: "b.png", Content: bytes.NewReader(pngHeader)})
+	require.NoError(t, err)
+
+	require.Equal(t, "a.png", first.FileName)
+	require.Equal(t, "image/png", first.ContentType)
+	require.Equal(t, int64(len(pngHeader)), first.Size)
+	require.NotEqual(t, first.PublicId, second.PublicId)
+	require.Equal(t, first.BlobKey, second.BlobKey)
+	require.Len(t, repo.blobs, 1)
+}
+
+func TestAttachmentsService_UploadLimits(t *testing.T) {
+	svc, _, _ := newAttachmentsService(t, 64)
+	ctx := context.Background()
+
+	_, err := svc.Upload(ctx, services.NewAttachment{FileName: "big.txt", Content: strings.NewReader(strings.Repeat("a", 65))})
+	var tooLarge *services.AttachmentTooLargeError
+	require.ErrorAs(t, err, &tooLarge)
+	require.Equal(t, int64(64), tooLarge.Limit)
+
+	_, err = svc.Upload(ctx, services.NewAttachment{FileName: "doc.pdf", Content: strings.NewReader("%PDF-1.4\n")})
+	var badType *services.AttachmentTypeError
+	require.ErrorAs(t, err, &badType)
+	require.Equal(t, "application/pdf", badType.ContentType)
+}
+
+func TestAttachmentsService_OpenChecksSignature(t *testing.T) {
+	svc, _, _ := newAttachmentsService(t, 64)
+	ctx := context.Background()
+
+	attachment, err := svc.Upload(ctx, services.NewAttachment{FileName: "note.txt", Content: strings.NewReader("hello")})
+	require.NoError(t, err)
+
+	u, err := url.Parse(attachment.Url)
+	require.NoError(t, err)
+	require.Equal(t, "/api/v1/attachments/"+attachment.PublicId, u.Path)
+	expires, err := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+	require.NoError(t, err)
+	sig := u.Query().Get("sig")
+
+	_, err = svc.Open(ctx, attachment.PublicId, "", expires+1, sig)
+	require.ErrorIs(t, err, services.ErrInvalidSignature)
+	_, err = svc.Open(ctx, model.NewPublicId(), "", expires, sig)
+	require.ErrorIs(t, err, services.ErrInvalidSignature)
+	_, err = svc.Open(ctx, attachment.PublicId, "small", expires, sig)
+	require.ErrorIs(t, err, services.ErrInvalidSignature)
+
+	download, err := svc.Open(ctx, attachment.PublicId, "", expires, sig)
+	require.NoError(t, err)
+	defer download.Content.Close()
+	b, err := io.ReadAll(download.Content)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(b))
+	require.Equal(t, "text/plain", download.ContentType)
+}
+
+func TestURLSigner_ExpiredLinkIsRejected(t *testing.T) {
+	svc, repo, store := newAttachmentsService(t, 64)
+	ctx := context.Background()
+	attachment, err := svc.Upload(ctx, services.NewAttachment{FileName: "note.txt", Content: strings.NewReader("hello")})
+	require.NoError(t, err)
+
+	expired := services.NewURLSigner([]byte("key"), -time.Minute, "/api/v1/attachments")
+	u, err := url.Parse(expired.URL(attachment.PublicId, ""))
+	require.NoError(t, err)
+	expires, _ := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+
+	svc = services.NewAttachmentsService(repo, store, expired, services.AttachmentLimits{})
+	_, err = svc.Open(ctx, attachment.PublicId, "", expires, u.Query().Get("sig"))
+	require.ErrorIs(t, err, services.ErrInvalidSignature)
+}
+
+func TestAttachmentsService_UploadImageMakesThumbnails(t *testing.T) {
+	svc, repo, _ := newAttachmentsService(t, 1<<20)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 800, 400)), nil))
+
+	attachment, err := svc.Upload(ctx, services.NewAttachment{FileName: "photo.jpeg", Content: &buf})
+	require.NoError(t, err)
+
+	require.Equal(t, 800, *attachment.Width)
+	require.Equal(t, 400, *attachment.Height)
+	require.Len(t, attachment.Variants, 2)
+	require.Len(t, repo.blobs, 3)
+
+	small := attachment.Variants[0]
+	require.Equal(t, "small", small.Name)
+	require.Equal(t, 160, small.Width)
+	u, err := url.Parse(small.Url)
+	require.NoError(t, err)
+	require.Equal(t, "small", u.Query().Get("variant"))
+	expires, _ := strconv.ParseInt(u.Query().Get("expires"), 10, 64)
+
+	download, err := svc.Open(ctx, attachment.PublicId, "small", expires, u.Query().Get("sig"))
+	require.NoError(t, err)
+	defer download.Content.Close()
+	require.Equal(t, "photo-small.jpg", download.FileName)
+	thumb, err := jpeg.Decode(download.Content)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 160, 80), thumb.Bounds())
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"unicode/utf8"
+)
+
+const (
+	botKeyPrefix           = "bk_"
+	maxBotCommands         = 20
+	maxCommandDescription  = 200
+	maxBotEndpointLen      = maxWebhookUrlLen
+	botSecretPrefix        = "bsec_"
+	botKeyAndSecretBytes   = 32
+	botNamePatternHint     = "3 to 32 lowercase letters, digits or underscores, starting with a letter"
+	commandNamePatternHint = "1 to 32 lowercase letters, digits or underscores, starting with a letter"
+)
+
+var (
+	botNamePattern     = regexp.MustCompile(`^[a-z][a-z0-9_]{2,31}$`)
+	commandNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+)
+
+type BotsService interface {
+	Create(ctx context.Context, input NewBot) (*model.Bot, error)
+	List(ctx context.Context) ([]*model.Bot, error)
+	Delete(ctx context.Context, publicId string) error
+	RotateKey(ctx context.Context, publicId string) (*model.Bot, error)
+	Authorize(ctx context.Context, key string) (*model.Bot, error)
+}
+
+// NewBot describes a bot account. Commands are forwarded to Endpoint, so a bot
+// with commands needs one.
+type NewBot struct {
+	Name     string
+	Endpoint string
+	Commands []model.BotCommand
+}
+
+type botsService struct {
+	repo repository.BotsRepository
+}
+
+func NewBotsService(repo repository.BotsRepository) BotsService {
+	return &botsService{repo: repo}
+}
+
+func (s *botsService) Create(ctx context.Context, input NewBot) (*model.Bot, error) {
+	var fields []FieldError
+	if !botNamePattern.MatchString(input.Name) {
+		fields = append(fields, FieldError{Field: "name", Code: CodeInvalid, Message: "name must be " + botNamePatternHint})
+	}
+	if input.Endpoint != "" {
+		if u, err := url.Parse(input.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(input.Endpoint) > maxBotEndpointLen {
+			fields = append(fields, FieldError{Field: "endpoint", Code: CodeInvalid, Message: "endpoint must be an absolute http or https URL"})
+		}
+	} else if len(input.Commands) > 0 {
+		fields = append(fields, FieldError{Field: "endpoint", Code: CodeRequired, Message: "endpoint is required for a bot with commands"})
+	}
+	if fieldErr := validateBotCommands(input.Commands); fieldErr != nil {
+		fields = append(fields, *fieldErr)
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	bot := &model.Bot{Name: input.Name, Commands: input.Commands}
+	if input.Endpoint != "" {
+		bot.Endpoint = &input.Endpoint
+	}
+
+	secret := make([]byte, botKeyAndSecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	bot.Secret = botSecretPrefix + hex.EncodeToString(secret)
+
+	key, err := newBotKey()
+	if err != nil {
+		return nil, err
+	}
+	bot.KeyHash = hashToken(key)
+
+	if err := s.repo.Create(ctx, bot); err != nil {
+		return nil, err
+	}
+
+	bot.ApiKey = key
+	return bot, nil
+}
+
+func validateBotCommands(commands []model.BotCommand) *FieldError {
+	if len(commands) > maxBotCommands {
+		return &FieldError{Field: "commands", Code: CodeInvalid, Message: fmt.Sprintf("a bot can have at most %d commands", maxBotCommands)}
+	}
+
+	seen := map[string]bool{}
+	for _, c := range commands {
+		switch {
+		case !commandNamePattern.MatchString(c.Command):
+			return &FieldError{Field: "commands", Code: CodeInvalid, Message: fmt.Sprintf("command %q must be %s", c.Command, commandNamePatternHint)}
+		case isBuiltinCommand(c.Command):
+			return &FieldError{Field: "commands", Code: CodeInvalid, Message: fmt.Sprintf("command /%s is built in", c.Command)}
+		case seen[c.Command]:
+			return &FieldError{Field: "commands", Code: CodeInvalid, Message: fmt.Sprintf("command /%s is listed twice", c.Command)}
+		case !utf8.ValidString(c.Description) || utf8.RuneCountInString(c.Description) > maxCommandDescription:
+			return &FieldError{Field: "commands", Code: CodeInvalid, Message: fmt.Sprintf("description of /%s must be valid UTF-8 of at most %d characters", c.Command, maxCommandDescription)}
+		}
+		seen[c.Command] = true
+	}
+	return nil
+}
+
+// List never shows secrets: they are returned once, on creation.
+func (s *botsService) List(ctx context.Context) ([]*model.Bot, error) {
+	bots, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, bot := range bots {
+		bot.Secret = ""
+	}
+	return bots, nil
+}
+
+func (s *botsService) Delete(ctx context.Context, publicId string) error {
+	return s.repo.Delete(ctx, publicId)
+}
+
+// RotateKey replaces the bot's API key; the old key stops working at once.
+func (s *botsService) RotateKey(ctx context.Context, publicId string) (*model.Bot, error) {
+	key, err := newBotKey()
+	if err != nil {
+		return nil, err
+	}
+
+	bot, err := s.repo.SetKeyHash(ctx, publicId, hashToken(key))
+	if err != nil {
+		return nil, err
+	}
+
+	bot.Secret = ""
+	bot.ApiKey = key
+	return bot, nil
+}
+
+func (s *botsService) Authorize(ctx context.Context, key string) (*model.Bot, error) {
+	return s.repo.GetByKeyHash(ctx, hashToken(key))
+}
+
+func newBotKey() (string, error) {
+	key := make([]byte, botKeyAndSecretBytes)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return botKeyPrefix + base64.RawURLEncoding.EncodeToString(key), nil
+}
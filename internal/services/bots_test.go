@@ -0,0 +1,60 @@
+package services_test
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type createdBots struct {
+	repository.BotsRepository
+	created []*model.Bot
+}
+
+func (r *createdBots) Create(ctx context.Context, bot *model.Bot) error {
+	r.created = append(r.created, bot)
+	return nil
+}
+
+func TestBots_Create(t *testing.T) {
+	repo := &createdBots{}
+	bots := services.NewBotsService(repo)
+
+	bot, err := bots.Create(context.Background(), services.NewBot{
+		Name:     "deploybot",
+		Endpoint: "https://ci.example.com/commands",
+		Commands: []model.BotCommand{{Command: "deploy", Description: "deploy a branch"}},
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(bot.ApiKey, "bk_"))
+	require.True(t, strings.HasPrefix(bot.Secret, "bsec_"))
+	require.NotContains(t, repo.created[0].KeyHash, bot.ApiKey)
+}
+
+func TestBots_CreateValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		input services.NewBot
+		field string
+	}{
+		{name: "bad name", input: services.NewBot{Name: "Deploy Bot"}, field: "name"},
+		{name: "commands without endpoint", input: services.NewBot{Name: "deploybot", Commands: []model.BotCommand{{Command: "deploy"}}}, field: "endpoint"},
+		{name: "bad endpoint", input: services.NewBot{Name: "deploybot", Endpoint: "ftp://example.com"}, field: "endpoint"},
+		{name: "built-in command", input: services.NewBot{Name: "deploybot", Endpoint: "https://example.com", Commands: []model.BotCommand{{Command: "help"}}}, field: "commands"},
+		{name: "bad command", input: services.NewBot{Name: "deploybot", Endpoint: "https://example.com", Commands: []model.BotCommand{{Command: "/deploy"}}}, field: "commands"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := services.NewBotsService(&createdBots{}).Create(context.Background(), test.input)
+			var validationErr *services.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Equal(t, test.field, validationErr.Fields[0].Field)
+		})
+	}
+}
@@ -4,49 +4,80 @@ import (
 	"chats-api/internal/model"
 	"chats-api/internal/repository"
 	"context"
-	"errors"
+	"fmt"
 	"strings"
+	"time"
+	"unicode"
 )
 
 type ChatsService interface {
 	ValidateChatCreate(title string) (string, error)
-	CreateChat(ctx context.Context, title string) (*model.Chat, error)
-	GetChat(id int) (*model.Chat, error)
-	DeleteChat(id int) error
+	// CreateChat creates a group or broadcast chat.
+	CreateChat(ctx context.Context, title, chatType, creatorId string) (*model.Chat, error)
+	// OpenDirectChat finds or creates the direct chat of userId with otherId
+	// and reports whether it was created.
+	OpenDirectChat(ctx context.Context, userId, otherId string) (*model.Chat, bool, error)
+	GetChat(id int64) (*model.Chat, error)
+	ResolveChatId(ctx context.Context, publicId string) (int64, error)
+	// ResolveChatIdFor resolves the chat as userId sees it: the direct chats
+	// of other users are not found.
+	ResolveChatIdFor(ctx context.Context, publicId, userId string) (int64, error)
+	ListChats(ctx context.Context, userId, afterPublicId string, limit int) ([]*model.Chat, error)
+	DeleteChat(id int64) error
+	RestoreChat(ctx context.Context, publicId string) (*model.Chat, error)
+	// RestoreChatFor restores the chat only if userId is an admin of it.
+	RestoreChatFor(ctx context.Context, publicId, userId string) (*model.Chat, error)
+	ListDeletedChats(ctx context.Context, limit int) ([]*model.Chat, error)
+	// ListDeletedChatsFor lists only the chats userId is an admin of.
+	ListDeletedChatsFor(ctx context.Context, userId string, limit int) ([]*model.Chat, error)
+	PurgeDeletedChats(ctx context.Context) (int64, error)
+	SetRetention(ctx context.Context, id int64, policy model.RetentionPolicy) (*model.Chat, error)
+	// SetExpiresIn makes messages posted to the chat from now on disappear
+	// after expiresIn seconds, or stops it when expiresIn is nil.
+	SetExpiresIn(ctx context.Context, id int64, expiresIn *int64) (*model.Chat, error)
 }
 type chatsService struct {
-	repo repository.ChatsRepository
+	repo       repository.ChatsRepository
+	restoreFor time.Duration
+	purgeBatch int
 }
 
-func NewChatsRepository(repo repository.ChatsRepository) ChatsService {
-	return &chatsService{repo: repo}
+func NewChatsRepository(repo repository.ChatsRepository, restoreFor time.Duration) ChatsService {
+	return &chatsService{repo: repo, restoreFor: restoreFor, purgeBatch: 100}
 }
 
 func (s *chatsService) ValidateChatCreate(title string) (string, error) {
-	str := strings.TrimSpace(title)
-
-	if len(str) == 0 {
-		return "", errors.New("chat title is required")
-	}
-
-	if len(str) > 200 {
-		return "", errors.New("chat title cannot be too long")
+	str, fieldErr := validateText("title", "chat title", title, MaxChatTitleLen, false)
+	if fieldErr != nil {
+		return "", &ValidationError{Fields: []FieldError{*fieldErr}}
 	}
 
 	return str, nil
 }
 
-func (s *chatsService) CreateChat(ctx context.Context, title string) (*model.Chat, error) {
-	chat := &model.Chat{Title: title}
+func (s *chatsService) CreateChat(ctx context.Context, title, chatType, creatorId string) (*model.Chat, error) {
+	chat := &model.Chat{Title: title, Type: chatType}
 
-	if err := s.repo.Create(ctx, chat); err != nil {
+	if err := s.repo.Create(ctx, chat, creatorId); err != nil {
 		return nil, err
 	}
 
 	return chat, nil
 }
 
-func (s *chatsService) GetChat(id int) (*model.Chat, error) {
+func (s *chatsService) OpenDirectChat(ctx context.Context, userId, otherId string) (*model.Chat, bool, error) {
+	if len(otherId) > maxUserIdLen {
+		return nil, false, &ValidationError{Fields: []FieldError{{Field: "user_id", Code: CodeTooLong, Message: fmt.Sprintf("user_id cannot be longer than %d characters", maxUserIdLen), Limit: maxUserIdLen}}}
+	}
+	// Control characters would let two pairs of users share a direct key.
+	if strings.ContainsFunc(otherId, unicode.IsControl) {
+		return nil, false, &ValidationError{Fields: []FieldError{{Field: "user_id", Code: CodeInvalid, Message: "user_id is invalid"}}}
+	}
+
+	return s.repo.OpenDirect(ctx, userId, otherId)
+}
+
+func (s *chatsService) GetChat(id int64) (*model.Chat, error) {
 	chat, err := s.repo.Get(id)
 
 	if err != nil {
@@ -56,6 +87,61 @@ func (s *chatsService) GetChat(id int) (*model.Chat, error) {
 	return chat, nil
 }
 
-func (s *chatsService) DeleteChat(id int) error {
+func (s *chatsService) ResolveChatId(ctx context.Context, publicId string) (int64, error) {
+	return s.repo.ResolveId(ctx, publicId)
+}
+
+func (s *chatsService) ResolveChatIdFor(ctx context.Context, publicId, userId string) (int64, error) {
+	return s.repo.ResolveIdFor(ctx, publicId, userId)
+}
+
+func (s *chatsService) ListChats(ctx context.Context, userId, afterPublicId string, limit int) ([]*model.Chat, error) {
+	return s.repo.List(ctx, userId, afterPublicId, limit)
+}
+
+func (s *chatsService) DeleteChat(id int64) error {
 	return s.repo.Delete(id)
 }
+
+func (s *chatsService) RestoreChat(ctx context.Context, publicId string) (*model.Chat, error) {
+	return s.repo.Restore(ctx, publicId, time.Now().Add(-s.restoreFor))
+}
+
+func (s *chatsService) RestoreChatFor(ctx context.Context, publicId, userId string) (*model.Chat, error) {
+	return s.repo.RestoreFor(ctx, publicId, userId, time.Now().Add(-s.restoreFor))
+}
+
+func (s *chatsService) ListDeletedChats(ctx context.Context, limit int) ([]*model.Chat, error) {
+	return s.repo.ListDeleted(ctx, time.Now().Add(-s.restoreFor), limit)
+}
+
+func (s *chatsService) ListDeletedChatsFor(ctx context.Context, userId string, limit int) ([]*model.Chat, error) {
+	return s.repo.ListDeletedFor(ctx, userId, time.Now().Add(-s.restoreFor), limit)
+}
+
+func (s *chatsService) PurgeDeletedChats(ctx context.Context) (int64, error) {
+	return s.repo.PurgeDeleted(ctx, time.Now().Add(-s.restoreFor), s.purgeBatch)
+}
+
+func (s *chatsService) SetRetention(ctx context.Context, id int64, policy model.RetentionPolicy) (*model.Chat, error) {
+	var fields []FieldError
+	if fieldErr := validateSeconds("max_age_seconds", policy.MaxAgeSeconds); fieldErr != nil {
+		fields = append(fields, *fieldErr)
+	}
+	if policy.MaxMessages != nil && *policy.MaxMessages <= 0 {
+		fields = append(fields, FieldError{Field: "max_messages", Code: CodeNotPositive, Message: "max_messages must be positive"})
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	return s.repo.SetRetention(ctx, id, policy)
+}
+
+func (s *chatsService) SetExpiresIn(ctx context.Context, id int64, expiresIn *int64) (*model.Chat, error) {
+	if field := validateSeconds("expires_in", expiresIn); field != nil {
+		return nil, &ValidationError{Fields: []FieldError{*field}}
+	}
+
+	return s.repo.SetExpiresIn(ctx, id, expiresIn)
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"bytes"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	BotSignatureHeader = "X-Bot-Signature"
+	BotTimestampHeader = "X-Bot-Timestamp"
+
+	// SystemSenderId authors the replies of built-in commands.
+	SystemSenderId = model.SystemSenderId
+
+	MaxTopicLen       = 250
+	maxPollOptions    = 10
+	maxPollOptionLen  = 200
+	maxBotReplyLength = 64 << 10
+
+	pollUsage = `/poll "question" "option" "option" ...`
+)
+
+var (
+	commandsDispatched = expvar.NewInt("commands_dispatched_total")
+	botCommandsFailed  = expvar.NewInt("bot_commands_failed_total")
+)
+
+// CommandsService runs the slash command a message starts with. Built-in
+// commands are answered by the server; the others are forwarded to the bot
+// that registered them, and the bot's reply is posted in its name.
+type CommandsService interface {
+	// Dispatch returns the reply posted to the chat, or nil when the message
+	// is not a command or the command has nothing to say.
+	Dispatch(ctx context.Context, message *model.Message) (*model.Message, error)
+}
+
+// Command is a parsed "/name args" message. Raw is everything after the name.
+type Command struct {
+	Name string
+	Args []string
+	Raw  string
+}
+
+// BotCommandRequest is the body forwarded to a bot's endpoint.
+type BotCommandRequest struct {
+	Command string         `json:"command"`
+	Args    []string       `json:"args"`
+	Text    string         `json:"text"`
+	ChatId  string         `json:"chat_id"`
+	Message *model.Message `json:"message"`
+}
+
+// BotCommandReply is what a bot answers with; an empty reply posts nothing.
+type BotCommandReply struct {
+	Text        string   `json:"text"`
+	Attachments []string `json:"attachments"`
+}
+
+type builtinCommand struct {
+	name        string
+	usage       string
+	description string
+}
+
+// builtinCommands are answered by the server, in the order /help lists them.
+var builtinCommands = []builtinCommand{
+	{name: "help", usage: "/help", description: "list the commands"},
+	{name: "topic", usage: "/topic [text]", description: "show or set the chat topic"},
+	{name: "poll", usage: pollUsage, description: "start a poll"},
+	{name: "vote", usage: "/vote <number>", description: "vote in the latest poll"},
+}
+
+func isBuiltinCommand(name string) bool {
+	return slices.ContainsFunc(builtinCommands, func(c builtinCommand) bool { return c.name == name })
+}
+
+type commandsService struct {
+	bots     repository.BotsRepository
+	chats    repository.ChatsRepository
+	polls    repository.PollsRepository
+	messages MessagesService
+	client   *http.Client
+}
+
+// NewCommandsService forwards commands to bots with the given timeout.
+func NewCommandsService(bots repository.BotsRepository, chats repository.ChatsRepository, polls repository.PollsRepository,
+	messages MessagesService, timeout time.Duration) CommandsService {
+	return &commandsService{
+		bots:     bots,
+		chats:    chats,
+		polls:    polls,
+		messages: messages,
+		client: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// ParseCommand reads "/name args" or "/name@bot args". Text that merely starts
+// with a slash, like a path, is not a command.
+func ParseCommand(text string) (*Command, bool) {
+	rest, ok := strings.CutPrefix(text, "/")
+	if !ok {
+		return nil, false
+	}
+
+	end := strings.IndexFunc(rest, unicode.IsSpace)
+	if end < 0 {
+		end = len(rest)
+	}
+	name, raw := rest[:end], rest[end:]
+	name, _, _ = strings.Cut(strings.ToLower(name), "@")
+	if !commandNamePattern.MatchString(name) {
+		return nil, false
+	}
+
+	raw = strings.TrimSpace(raw)
+	return &Command{Name: name, Args: splitArgs(raw), Raw: raw}, true
+}
+
+// splitArgs splits on whitespace, keeping "double quoted" arguments whole.
+func splitArgs(s string) []string {
+	args := []string{}
+	var arg strings.Builder
+	inQuotes, started := false, false
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuotes, started = !inQuotes, true
+		case unicode.IsSpace(r) && !inQuotes:
+			if started {
+				args = append(args, arg.String())
+				arg.Reset()
+				started = false
+			}
+		default:
+			arg.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+func (s *commandsService) Dispatch(ctx context.Context, message *model.Message) (*model.Message, error) {
+	cmd, ok := ParseCommand(message.Text)
+	if !ok {
+		return nil, nil
+	}
+	commandsDispatched.Add(1)
+
+	if isBuiltinCommand(cmd.Name) {
+		text, err := s.runBuiltin(ctx, message, cmd)
+		if err != nil {
+			return nil, err
+		}
+		return s.reply(ctx, message, SystemSenderId, "", text, nil)
+	}
+
+	bot, err := s.bots.GetByCommand(ctx, cmd.Name)
+	if errors.Is(err, repository.ErrBotNotFound) {
+		return s.reply(ctx, message, SystemSenderId, "", fmt.Sprintf("Unknown command /%s. Send /help to list the commands.", cmd.Name), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	answer, err := s.forward(ctx, bot, message, cmd)
+	if err != nil {
+		botCommandsFailed.Add(1)
+		text := fmt.Sprintf("/%s failed: bot %s did not answer.", cmd.Name, bot.Name)
+		if _, replyErr := s.reply(ctx, message, SystemSenderId, "", text, nil); replyErr != nil {
+			return nil, replyErr
+		}
+		return nil, fmt.Errorf("forward /%s to bot %s: %w", cmd.Name, bot.Name, err)
+	}
+
+	if answer.Text == "" && len(answer.Attachments) == 0 {
+		return nil, nil
+	}
+	if answer.Text != "" {
+		text, err := s.messages.ValidateMessageCreate(answer.Text)
+		if err != nil {
+			botCommandsFailed.Add(1)
+			return nil, fmt.Errorf("bot %s answered /%s with an invalid message: %w", bot.Name, cmd.Name, err)
+		}
+		answer.Text = text
+	}
+	return s.reply(ctx, message, bot.SenderId(), bot.Name, answer.Text, answer.Attachments)
+}
+
+func (s *commandsService) runBuiltin(ctx context.Context, message *model.Message, cmd *Command) (string, error) {
+	switch cmd.Name {
+	case "help":
+		return s.help(ctx)
+	case "topic":
+		return s.topic(ctx, message, cmd)
+	case "poll":
+		return s.poll(ctx, message, cmd)
+	default:
+		return s.vote(ctx, message, cmd)
+	}
+}
+
+func (s *commandsService) reply(ctx context.Context, to *model.Message, senderId, senderName, text string, attachmentIds []string) (*model.Message, error) {
+	if text == "" && len(attachmentIds) == 0 {
+		return nil, nil
+	}
+	return s.messages.CreateMessage(ctx, NewMessage{
+		ChatId:        to.ChatId,
+		SenderId:      senderId,
+		SenderName:    senderName,
+		Text:          text,
+		AttachmentIds: attachmentIds,
+	})
+}
+
+// forward posts the command to the bot's endpoint, signed like webhook
+// deliveries but with the bot's secret.
+func (s *commandsService) forward(ctx context.Context, bot *model.Bot, message *model.Message, cmd *Command) (*BotCommandReply, error) {
+	if bot.Endpoint == nil {
+		return nil, errors.New("bot has no endpoint")
+	}
+
+	body, err := json.Marshal(BotCommandRequest{
+		Command: cmd.Name,
+		Args:    cmd.Args,
+		Text:    cmd.Raw,
+		ChatId:  message.ChatPublicId,
+		Message: message,
+	})
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *bot.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chats-api-commands")
+	req.Header.Set(BotTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(BotSignatureHeader, SignWebhook(bot.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var answer BotCommandReply
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBotReplyLength))
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &answer); err != nil {
+			return nil, fmt.Errorf("invalid reply: %w", err)
+		}
+	}
+	return &answer, nil
+}
+
+func (s *commandsService) help(ctx context.Context) (string, error) {
+	var b strings.Builder
+	b.WriteString("Commands:")
+	for _, c := range builtinCommands {
+		fmt.Fprintf(&b, "\n%s — %s", c.usage, c.description)
+	}
+
+	commands, err := s.bots.Commands(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range commands {
+		if c.Description != "" {
+			fmt.Fprintf(&b, "\n/%s — %s", c.Command, c.Description)
+		} else {
+			fmt.Fprintf(&b, "\n/%s", c.Command)
+		}
+	}
+	return b.String(), nil
+}
+
+func (s *commandsService) topic(ctx context.Context, message *model.Message, cmd *Command) (string, error) {
+	if cmd.Raw == "" {
+		chat, err := s.chats.Get(message.ChatId)
+		if err != nil {
+			return "", err
+		}
+		if chat.Topic == "" {
+			return "No topic is set. Set one with /topic <text>.", nil
+		}
+		return "Topic: " + chat.Topic, nil
+	}
+
+	topic, fieldErr := validateText("topic", "topic", cmd.Raw, MaxTopicLen, false)
+	if fieldErr != nil {
+		return fieldErr.Message + ".", nil
+	}
+	if _, err := s.chats.SetTopic(ctx, message.ChatId, topic); err != nil {
+		return "", err
+	}
+	return "Topic set: " + topic, nil
+}
+
+func (s *commandsService) poll(ctx context.Context, message *model.Message, cmd *Command) (string, error) {
+	usage := "Usage: " + pollUsage
+	if len(cmd.Args) < 3 || len(cmd.Args) > maxPollOptions+1 {
+		return fmt.Sprintf("%s with 2 to %d options.", usage, maxPollOptions), nil
+	}
+	for _, arg := range cmd.Args {
+		if strings.TrimSpace(arg) == "" || utf8.RuneCountInString(arg) > maxPollOptionLen {
+			return fmt.Sprintf("%s; the question and options must not be empty or longer than %d characters.", usage, maxPollOptionLen), nil
+		}
+	}
+
+	poll := &model.Poll{ChatId: message.ChatId, Question: cmd.Args[0], Options: cmd.Args[1:], CreatedBy: message.SenderId}
+	if err := s.polls.Create(ctx, poll); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("Poll: " + poll.Question)
+	for i, option := range poll.Options {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, option)
+	}
+	b.WriteString("\nVote with /vote <number>.")
+	return b.String(), nil
+}
+
+func (s *commandsService) vote(ctx context.Context, message *model.Message, cmd *Command) (string, error) {
+	poll, err := s.polls.Latest(ctx, message.ChatId)
+	if errors.Is(err, repository.ErrPollNotFound) {
+		return "There is no poll in this chat. Start one with /poll.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	option, err := strconv.Atoi(strings.TrimSpace(cmd.Raw))
+	if err != nil || option < 1 || option > len(poll.Options) {
+		return fmt.Sprintf("Usage: /vote <number from 1 to %d>.", len(poll.Options)), nil
+	}
+
+	tally, err := s.polls.Vote(ctx, poll, message.SenderId, option-1)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("Poll: " + poll.Question)
+	for i, option := range poll.Options {
+		fmt.Fprintf(&b, "\n%d. %s — %d", i+1, option, tally[i])
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,225 @@
+package services_test
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBotsRepo struct {
+	repository.BotsRepository
+	bots []*model.Bot
+}
+
+func (r *fakeBotsRepo) GetByCommand(ctx context.Context, command string) (*model.Bot, error) {
+	for _, bot := range r.bots {
+		for _, c := range bot.Commands {
+			if c.Command == command {
+				return bot, nil
+			}
+		}
+	}
+	return nil, repository.ErrBotNotFound
+}
+
+func (r *fakeBotsRepo) Commands(ctx context.Context) ([]*model.BotCommand, error) {
+	var commands []*model.BotCommand
+	for _, bot := range r.bots {
+		for i := range bot.Commands {
+			commands = append(commands, &bot.Commands[i])
+		}
+	}
+	return commands, nil
+}
+
+type fakeChatsRepo struct {
+	repository.ChatsRepository
+	chat *model.Chat
+}
+
+func (r *fakeChatsRepo) Get(id int64) (*model.Chat, error) {
+	return r.chat, nil
+}
+
+func (r *fakeChatsRepo) SetTopic(ctx context.Context, id int64, topic string) (*model.Chat, error) {
+	r.chat.Topic = topic
+	return r.chat, nil
+}
+
+type fakePollsRepo struct {
+	polls []*model.Poll
+	votes map[string]int
+}
+
+func (r *fakePollsRepo) Create(ctx context.Context, poll *model.Poll) error {
+	poll.Id = int64(len(r.polls) + 1)
+	r.polls = append(r.polls, poll)
+	r.votes = map[string]int{}
+	return nil
+}
+
+func (r *fakePollsRepo) Latest(ctx context.Context, chatId int64) (*model.Poll, error) {
+	if len(r.polls) == 0 {
+		return nil, repository.ErrPollNotFound
+	}
+	return r.polls[len(r.polls)-1], nil
+}
+
+func (r *fakePollsRepo) Vote(ctx context.Context, poll *model.Poll, userId string, option int) ([]int, error) {
+	r.votes[userId] = option
+	tally := make([]int, len(poll.Options))
+	for _, o := range r.votes {
+		tally[o]++
+	}
+	return tally, nil
+}
+
+// postedMessages records the replies instead of storing them.
+type postedMessages struct {
+	services.MessagesService
+	posted []services.NewMessage
+}
+
+func (m *postedMessages) ValidateMessageCreate(text string) (string, error) {
+	return text, nil
+}
+
+func (m *postedMessages) CreateMessage(ctx context.Context, input services.NewMessage) (*model.Message, error) {
+	m.posted = append(m.posted, input)
+	return &model.Message{PublicId: model.NewPublicId(), ChatId: input.ChatId, SenderId: input.SenderId, Text: input.Text}, nil
+}
+
+func newCommands(bots ...*model.Bot) (services.CommandsService, *postedMessages, *fakeChatsRepo) {
+	messages := &postedMessages{}
+	chats := &fakeChatsRepo{chat: &model.Chat{Id: 1, PublicId: "c-1"}}
+	commands := services.NewCommandsService(&fakeBotsRepo{bots: bots}, chats, &fakePollsRepo{}, messages, time.Second)
+	return commands, messages, chats
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		ok   bool
+		name string
+		args []string
+	}{
+		{text: "hello", ok: false},
+		{text: "/usr/bin/env", ok: false},
+		{text: "/ help", ok: false},
+		{text: "/help", ok: true, name: "help", args: []string{}},
+		{text: "/Deploy@deploybot prod  now", ok: true, name: "deploy", args: []string{"prod", "now"}},
+		{text: "/poll\n\"Where to eat?\" Pizza \"Thai food\"", ok: true, name: "poll", args: []string{"Where to eat?", "Pizza", "Thai food"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			cmd, ok := services.ParseCommand(test.text)
+			require.Equal(t, test.ok, ok)
+			if ok {
+				require.Equal(t, test.name, cmd.Name)
+				require.Equal(t, test.args, cmd.Args)
+			}
+		})
+	}
+}
+
+func TestCommands_NotACommand(t *testing.T) {
+	commands, messages, _ := newCommands()
+
+	reply, err := commands.Dispatch(context.Background(), &model.Message{ChatId: 1, Text: "hello /help"})
+	require.NoError(t, err)
+	require.Nil(t, reply)
+	require.Empty(t, messages.posted)
+}
+
+func TestCommands_Topic(t *testing.T) {
+	commands, messages, chats := newCommands()
+
+	_, err := commands.Dispatch(context.Background(), &model.Message{ChatId: 1, Text: "/topic  Release 2.0 "})
+	require.NoError(t, err)
+	require.Equal(t, "Release 2.0", chats.chat.Topic)
+
+	_, err = commands.Dispatch(context.Background(), &model.Message{ChatId: 1, Text: "/topic"})
+	require.NoError(t, err)
+	require.Len(t, messages.posted, 2)
+	require.Equal(t, services.SystemSenderId, messages.posted[1].SenderId)
+	require.Equal(t, "Topic: Release 2.0", messages.posted[1].Text)
+}
+
+func TestCommands_PollAndVote(t *testing.T) {
+	commands, messages, _ := newCommands()
+
+	_, err := commands.Dispatch(context.Background(), &model.Message{ChatId: 1, SenderId: "alice", Text: `/poll "Lunch?" Pizza Sushi`})
+	require.NoError(t, err)
+	require.Equal(t, "Poll: Lunch?\n1. Pizza\n2. Sushi\nVote with /vote <number>.", messages.posted[0].Text)
+
+	_, err = commands.Dispatch(context.Background(), &model.Message{ChatId: 1, SenderId: "alice", Text: "/vote 2"})
+	require.NoError(t, err)
+	_, err = commands.Dispatch(context.Background(), &model.Message{ChatId: 1, SenderId: "bob", Text: "/vote 2"})
+	require.NoError(t, err)
+	require.Equal(t, "Poll: Lunch?\n1. Pizza — 0\n2. Sushi — 2", messages.posted[2].Text)
+
+	_, err = commands.Dispatch(context.Background(), &model.Message{ChatId: 1, SenderId: "bob", Text: "/vote 3"})
+	require.NoError(t, err)
+	require.Equal(t, "Usage: /vote <number from 1 to 2>.", messages.posted[3].Text)
+}
+
+func TestCommands_UnknownCommand(t *testing.T) {
+	commands, messages, _ := newCommands()
+
+	_, err := commands.Dispatch(context.Background(), &model.Message{ChatId: 1, Text: "/deploy prod"})
+	require.NoError(t, err)
+	require.Contains(t, messages.posted[0].Text, "Unknown command /deploy")
+}
+
+func TestCommands_ForwardsToBot(t *testing.T) {
+	var received services.BotCommandRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(services.BotTimestampHeader), 10, 64)
+		if r.Header.Get(services.BotSignatureHeader) != services.SignWebhook("bsec_1", ts, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.Unmarshal(body, &received)
+		json.NewEncoder(w).Encode(services.BotCommandReply{Text: "Deploying " + received.Args[0]})
+	}))
+	defer server.Close()
+
+	bot := &model.Bot{PublicId: "b-1", Name: "deploybot", Endpoint: &server.URL, Secret: "bsec_1",
+		Commands: []model.BotCommand{{Command: "deploy"}}}
+	commands, messages, _ := newCommands(bot)
+
+	reply, err := commands.Dispatch(context.Background(), &model.Message{ChatId: 1, ChatPublicId: "c-1", SenderId: "alice", Text: "/deploy prod"})
+	require.NoError(t, err)
+	require.NotNil(t, reply)
+	require.Equal(t, "deploy", received.Command)
+	require.Equal(t, "c-1", received.ChatId)
+	require.Equal(t, services.NewMessage{ChatId: 1, SenderId: "bot:b-1", SenderName: "deploybot", Text: "Deploying prod"}, messages.posted[0])
+}
+
+func TestCommands_BotFailureIsReported(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	bot := &model.Bot{PublicId: "b-1", Name: "deploybot", Endpoint: &server.URL, Secret: "bsec_1",
+		Commands: []model.BotCommand{{Command: "deploy"}}}
+	commands, messages, _ := newCommands(bot)
+
+	_, err := commands.Dispatch(context.Background(), &model.Message{ChatId: 1, Text: "/deploy"})
+	require.Error(t, err)
+	require.Equal(t, "/deploy failed: bot deploybot did not answer.", messages.posted[0].Text)
+	require.Equal(t, services.SystemSenderId, messages.posted[0].SenderId)
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"chats-api/internal/importer"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	importBatchSize      = 1000
+	maxImportClientMsgId = 100
+)
+
+type ImportService interface {
+	// Import reads an export from r and stores it, or only checks it on a dry
+	// run. An export that cannot be read fails with an *ImportError, and
+	// nothing of it is stored.
+	Import(ctx context.Context, format string, r io.Reader, title string, dryRun bool) (*ImportReport, error)
+}
+
+type ImportReport struct {
+	DryRun   bool                   `json:"dry_run"`
+	Chats    int                    `json:"chats"`
+	Messages int                    `json:"messages"`
+	Errors   []importer.RecordError `json:"errors"`
+}
+
+// ImportError is an export that could not be read, as opposed to one that
+// could not be stored.
+type ImportError struct {
+	Err error
+}
+
+func (e *ImportError) Error() string { return e.Err.Error() }
+func (e *ImportError) Unwrap() error { return e.Err }
+
+type importService struct {
+	repo repository.ChatsRepository
+}
+
+func NewImportService(repo repository.ChatsRepository) ImportService {
+	return &importService{repo: repo}
+}
+
+// Import validates every chat and message with the same rules as the API and
+// stores the valid ones as they are read, in batches, in one transaction.
+// Invalid records are reported and skipped; a chat whose title is invalid or
+// taken is skipped with all of its messages. A dry run reports taken titles
+// too, whether by existing chats or earlier in the same import.
+func (s *importService) Import(ctx context.Context, format string, r io.Reader, title string, dryRun bool) (*ImportReport, error) {
+	imp := &chatsImport{
+		ctx:     ctx,
+		repo:    s.repo,
+		report:  &ImportReport{DryRun: dryRun, Errors: []importer.RecordError{}},
+		planned: make(map[string]bool),
+	}
+	if !dryRun {
+		tx, err := s.repo.BeginImport(ctx, importBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		imp.tx = tx
+	}
+
+	err := importer.Parse(format, r, title, imp)
+	if err == nil {
+		err = imp.endChat()
+	}
+	switch {
+	case imp.err != nil:
+		err = imp.err
+	case err != nil:
+		err = &ImportError{Err: err}
+	case imp.tx != nil:
+		if err := imp.tx.Commit(); err != nil {
+			return nil, err
+		}
+		return imp.report, nil
+	default:
+		return imp.report, nil
+	}
+
+	if imp.tx != nil {
+		err = errors.Join(err, imp.tx.Rollback())
+	}
+	return nil, err
+}
+
+// chatsImport receives an export from the importer. Of a chat it holds only
+// the batch of messages not stored yet and the client message ids seen, which
+// the unique index would otherwise reject with the whole import.
+type chatsImport struct {
+	ctx     context.Context
+	repo    repository.ChatsRepository
+	tx      repository.ChatImport
+	report  *ImportReport
+	planned map[string]bool
+	// err is the first error storing the import; it stops the parse.
+	err error
+
+	src      importer.Chat
+	chat     *model.Chat
+	started  bool
+	batch    []*model.Message
+	messages int
+	seen     map[[2]string]bool
+}
+
+func (i *chatsImport) Chat(src importer.Chat) error {
+	if err := i.endChat(); err != nil {
+		return err
+	}
+	i.src = src
+
+	title, fieldErr := validateText("title", "chat title", src.Title, MaxChatTitleLen, false)
+	if fieldErr != nil {
+		i.skipChat(src.Title, fieldErr.Message)
+		return nil
+	}
+
+	if i.tx == nil {
+		taken := i.planned[title]
+		if !taken {
+			var err error
+			if taken, err = i.repo.TitleTaken(i.ctx, title); err != nil {
+				return i.fail(err)
+			}
+		}
+		if taken {
+			i.skipChat(title, repository.ErrChatTitleTaken.Error())
+			return nil
+		}
+		i.planned[title] = true
+	}
+
+	i.chat = &model.Chat{Title: title, CreatedAt: src.CreatedAt}
+	i.started, i.messages = false, 0
+	i.seen = make(map[[2]string]bool)
+	return nil
+}
+
+func (i *chatsImport) Message(m importer.Message) error {
+	if i.chat == nil {
+		return nil
+	}
+
+	message := i.convert(m)
+	if message == nil {
+		return nil
+	}
+	i.batch = append(i.batch, message)
+	if len(i.batch) < importBatchSize {
+		return nil
+	}
+	return i.flush()
+}
+
+func (i *chatsImport) Skip(err importer.RecordError) {
+	i.report.Errors = append(i.report.Errors, err)
+}
+
+// flush stores the batch, creating the chat with the first one: a chat
+// without a creation time of its own takes that of its first message.
+func (i *chatsImport) flush() error {
+	if !i.started {
+		if i.chat.CreatedAt.IsZero() && len(i.batch) > 0 {
+			i.chat.CreatedAt = i.batch[0].CreatedAt
+		}
+		if !i.chat.CreatedAt.IsZero() {
+			i.chat.PublicId = model.NewPublicIdAt(i.chat.CreatedAt)
+		}
+		if i.tx != nil {
+			err := i.tx.Chat(i.chat)
+			if errors.Is(err, repository.ErrChatTitleTaken) {
+				i.skipChat(i.chat.Title, err.Error())
+				return nil
+			}
+			if err != nil {
+				return i.fail(err)
+			}
+		}
+		i.started = true
+	}
+
+	if i.tx != nil {
+		if err := i.tx.Messages(i.batch); err != nil {
+			return i.fail(err)
+		}
+	}
+	i.messages += len(i.batch)
+	i.batch = nil
+	return nil
+}
+
+func (i *chatsImport) endChat() error {
+	if i.chat == nil {
+		return nil
+	}
+	if err := i.flush(); err != nil {
+		return err
+	}
+	if i.chat != nil {
+		i.report.Chats++
+		i.report.Messages += i.messages
+	}
+	i.chat = nil
+	return nil
+}
+
+func (i *chatsImport) skipChat(title, msg string) {
+	i.report.Errors = append(i.report.Errors, importer.RecordError{Record: "chat", Chat: title, Error: msg})
+	i.chat, i.batch = nil, nil
+}
+
+func (i *chatsImport) fail(err error) error {
+	i.err = err
+	return err
+}
+
+// convert validates a message of the chat; an invalid one is reported and nil
+// is returned.
+func (i *chatsImport) convert(m importer.Message) *model.Message {
+	fail := func(msg string) *model.Message {
+		i.report.Errors = append(i.report.Errors, importer.RecordError{Record: m.Record, Chat: i.src.Title, Error: msg})
+		return nil
+	}
+
+	text, fieldErr := validateText("text", "message", m.Text, MaxMessageTextLen, true)
+	switch {
+	case fieldErr != nil:
+		return fail(fieldErr.Message)
+	case m.SenderId == "":
+		return fail("sender is missing")
+	case model.IsReservedSenderId(m.SenderId):
+		return fail("sender id is reserved for the service, bots and incoming hooks")
+	case m.CreatedAt.IsZero():
+		return fail("creation time is missing")
+	case len(m.ClientMsgId) > maxImportClientMsgId:
+		return fail("client_msg_id is too long")
+	}
+
+	message := &model.Message{
+		PublicId:  model.NewPublicIdAt(m.CreatedAt),
+		SenderId:  m.SenderId,
+		Text:      text,
+		CreatedAt: m.CreatedAt.UTC().Truncate(time.Microsecond),
+	}
+	if m.ClientMsgId != "" {
+		key := [2]string{m.SenderId, m.ClientMsgId}
+		if i.seen[key] {
+			return fail("duplicate client_msg_id")
+		}
+		i.seen[key] = true
+		message.ClientMsgId = &m.ClientMsgId
+	}
+	return message
+}
@@ -0,0 +1,128 @@
+package services_test
+
+import (
+	"chats-api/internal/importer"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeImportChatsRepo struct {
+	repository.ChatsRepository
+	titles map[string]bool
+	tx     *fakeChatImport
+}
+
+func (r *fakeImportChatsRepo) TitleTaken(ctx context.Context, title string) (bool, error) {
+	return r.titles[title], nil
+}
+
+func (r *fakeImportChatsRepo) BeginImport(ctx context.Context, batchSize int) (repository.ChatImport, error) {
+	r.tx = &fakeChatImport{titles: r.titles}
+	return r.tx, nil
+}
+
+type fakeChatImport struct {
+	titles     map[string]bool
+	chats      []*model.Chat
+	batches    []int
+	committed  bool
+	rolledBack bool
+}
+
+func (i *fakeChatImport) Chat(chat *model.Chat) error {
+	if i.titles[chat.Title] {
+		return repository.ErrChatTitleTaken
+	}
+	i.chats = append(i.chats, chat)
+	return nil
+}
+
+func (i *fakeChatImport) Messages(messages []*model.Message) error {
+	i.batches = append(i.batches, len(messages))
+	return nil
+}
+
+func (i *fakeChatImport) Commit() error {
+	i.committed = true
+	return nil
+}
+
+func (i *fakeChatImport) Rollback() error {
+	i.rolledBack = true
+	return nil
+}
+
+func ndjsonChat(title string, senders ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `{"type":"chat","chat":{"Title":%q}}`+"\n", title)
+	for _, sender := range senders {
+		fmt.Fprintf(&b, `{"type":"message","message":{"SenderId":%q,"Text":"hi","CreatedAt":"2026-01-02T15:04:05Z"}}`+"\n", sender)
+	}
+	return b.String()
+}
+
+func TestImportService_DryRunReportsTakenTitles(t *testing.T) {
+	repo := &fakeImportChatsRepo{titles: map[string]bool{"general": true}}
+	s := services.NewImportService(repo)
+
+	input := ndjsonChat("general", "U1") + ndjsonChat("random", "U1") + ndjsonChat("random", "U1")
+	report, err := s.Import(context.Background(), importer.FormatNDJSON, strings.NewReader(input), "", true)
+	require.NoError(t, err)
+
+	require.Nil(t, repo.tx)
+	require.Equal(t, 1, report.Chats)
+	require.Equal(t, 1, report.Messages)
+	require.Equal(t, []importer.RecordError{
+		{Record: "chat", Chat: "general", Error: repository.ErrChatTitleTaken.Error()},
+		{Record: "chat", Chat: "random", Error: repository.ErrChatTitleTaken.Error()},
+	}, report.Errors)
+}
+
+func TestImportService_StoresInBatches(t *testing.T) {
+	repo := &fakeImportChatsRepo{titles: map[string]bool{"general": true}}
+	s := services.NewImportService(repo)
+
+	senders := make([]string, 2500)
+	for i := range senders {
+		senders[i] = "U1"
+	}
+	senders[1] = "bot:echo"
+	input := ndjsonChat("general", "U1") + ndjsonChat("random", senders...)
+
+	report, err := s.Import(context.Background(), importer.FormatNDJSON, strings.NewReader(input), "", false)
+	require.NoError(t, err)
+
+	require.True(t, repo.tx.committed)
+	require.Len(t, repo.tx.chats, 1)
+	require.Equal(t, "random", repo.tx.chats[0].Title)
+	require.Equal(t, []int{1000, 1000, 499}, repo.tx.batches)
+	require.Equal(t, 1, report.Chats)
+	require.Equal(t, 2499, report.Messages)
+	require.Equal(t, []importer.RecordError{
+		{Record: "chat", Chat: "general", Error: repository.ErrChatTitleTaken.Error()},
+		{Record: "line 5", Chat: "random", Error: "sender id is reserved for the service, bots and incoming hooks"},
+	}, report.Errors)
+}
+
+func TestImportService_BrokenExportStoresNothing(t *testing.T) {
+	repo := &fakeImportChatsRepo{}
+	s := services.NewImportService(repo)
+
+	input := `{"name": "Family", "messages": [
+		{"id": 1, "type": "message", "date_unixtime": "1767366245", "from_id": "user1", "text": "hi"},
+		{"id": 2, "type": "message", "date_unixtime": `
+	_, err := s.Import(context.Background(), importer.FormatTelegram, strings.NewReader(input), "", false)
+
+	var invalid *services.ImportError
+	require.True(t, errors.As(err, &invalid))
+	require.True(t, repo.tx.rolledBack)
+	require.False(t, repo.tx.committed)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MaxHookNameLen      = 100
+	MaxSenderNameLen    = 80
+	incomingTokenBytes  = 32
+	incomingTokenPrefix = "ih_"
+)
+
+type IncomingHooksService interface {
+	Create(ctx context.Context, chatId int64, name, createdBy string) (*model.IncomingHook, error)
+	List(ctx context.Context, chatId int64) ([]*model.IncomingHook, error)
+	Revoke(ctx context.Context, chatId int64, publicId string) error
+	Authorize(ctx context.Context, token string) (*model.IncomingHook, error)
+	ValidateSenderName(name string) (string, error)
+}
+
+type incomingHooksService struct {
+	repo    repository.IncomingHooksRepository
+	limiter *rateLimiter
+}
+
+// NewIncomingHooksService limits every hook to perMinute requests with bursts
+// of up to burst.
+func NewIncomingHooksService(repo repository.IncomingHooksRepository, perMinute, burst int) IncomingHooksService {
+	return &incomingHooksService{repo: repo, limiter: newRateLimiter(perMinute, burst)}
+}
+
+func (s *incomingHooksService) Create(ctx context.Context, chatId int64, name, createdBy string) (*model.IncomingHook, error) {
+	name = normalizeText(name, false)
+	if utf8.RuneCountInString(name) > MaxHookNameLen {
+		return nil, &ValidationError{Fields: []FieldError{{
+			Field:   "name",
+			Code:    CodeTooLong,
+			Message: fmt.Sprintf("name cannot be longer than %d characters", MaxHookNameLen),
+			Limit:   MaxHookNameLen,
+		}}}
+	}
+
+	secret := make([]byte, incomingTokenBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := incomingTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	hook := &model.IncomingHook{ChatId: chatId, Name: name, TokenHash: hashToken(token), CreatedBy: createdBy}
+	if err := s.repo.Create(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	hook.Token = token
+	return hook, nil
+}
+
+func (s *incomingHooksService) List(ctx context.Context, chatId int64) ([]*model.IncomingHook, error) {
+	return s.repo.List(ctx, chatId)
+}
+
+func (s *incomingHooksService) Revoke(ctx context.Context, chatId int64, publicId string) error {
+	return s.repo.Revoke(ctx, chatId, publicId)
+}
+
+// Authorize finds the active hook of a token and takes one request from its
+// rate limit, returning *RateLimitError when it is used up.
+func (s *incomingHooksService) Authorize(ctx context.Context, token string) (*model.IncomingHook, error) {
+	hook, err := s.repo.GetByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.limiter.allow(hook.PublicId, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Touch(ctx, hook.Id); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// ValidateSenderName checks the name an integration asks its messages to be
+// shown under.
+func (s *incomingHooksService) ValidateSenderName(name string) (string, error) {
+	str, fieldErr := validateText("username", "username", name, MaxSenderNameLen, false)
+	if fieldErr != nil {
+		return "", &ValidationError{Fields: []FieldError{*fieldErr}}
+	}
+	return str, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
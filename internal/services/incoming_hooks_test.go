@@ -0,0 +1,115 @@
+package services_test
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeIncomingHooksRepo struct {
+	hooks   []*model.IncomingHook
+	touched int
+}
+
+func (r *fakeIncomingHooksRepo) Create(ctx context.Context, hook *model.IncomingHook) error {
+	hook.Id = int64(len(r.hooks) + 1)
+	hook.PublicId = model.NewPublicId()
+	r.hooks = append(r.hooks, hook)
+	return nil
+}
+
+func (r *fakeIncomingHooksRepo) List(ctx context.Context, chatId int64) ([]*model.IncomingHook, error) {
+	return r.hooks, nil
+}
+
+func (r *fakeIncomingHooksRepo) Revoke(ctx context.Context, chatId int64, publicId string) error {
+	for _, hook := range r.hooks {
+		if hook.PublicId == publicId && hook.RevokedAt == nil {
+			now := time.Now()
+			hook.RevokedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrHookNotFound
+}
+
+func (r *fakeIncomingHooksRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*model.IncomingHook, error) {
+	for _, hook := range r.hooks {
+		if hook.TokenHash == tokenHash && hook.RevokedAt == nil {
+			return hook, nil
+		}
+	}
+	return nil, repository.ErrHookNotFound
+}
+
+func (r *fakeIncomingHooksRepo) Touch(ctx context.Context, id int64) error {
+	r.touched++
+	return nil
+}
+
+func TestIncomingHooks_TokenIsShownOnceAndStoredHashed(t *testing.T) {
+	repo := &fakeIncomingHooksRepo{}
+	hooks := services.NewIncomingHooksService(repo, 60, 10)
+
+	hook, err := hooks.Create(context.Background(), 1, "  CI  ", "alice")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hook.Token, "ih_"))
+	require.Equal(t, "CI", hook.Name)
+	require.NotContains(t, repo.hooks[0].TokenHash, hook.Token)
+
+	found, err := hooks.Authorize(context.Background(), hook.Token)
+	require.NoError(t, err)
+	require.Equal(t, hook.PublicId, found.PublicId)
+	require.Equal(t, 1, repo.touched)
+
+	_, err = hooks.Authorize(context.Background(), hook.Token+"x")
+	require.ErrorIs(t, err, repository.ErrHookNotFound)
+
+	require.NoError(t, hooks.Revoke(context.Background(), 1, hook.PublicId))
+	_, err = hooks.Authorize(context.Background(), hook.Token)
+	require.ErrorIs(t, err, repository.ErrHookNotFound)
+}
+
+func TestIncomingHooks_RateLimit(t *testing.T) {
+	repo := &fakeIncomingHooksRepo{}
+	hooks := services.NewIncomingHooksService(repo, 1, 2)
+
+	first, err := hooks.Create(context.Background(), 1, "CI", "alice")
+	require.NoError(t, err)
+	second, err := hooks.Create(context.Background(), 1, "monitoring", "alice")
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err := hooks.Authorize(context.Background(), first.Token)
+		require.NoError(t, err)
+	}
+
+	_, err = hooks.Authorize(context.Background(), first.Token)
+	var limited *services.RateLimitError
+	require.ErrorAs(t, err, &limited)
+	require.Greater(t, limited.RetryAfter, 50*time.Second)
+	require.LessOrEqual(t, limited.RetryAfter, time.Minute)
+
+	// Every hook has its own budget.
+	_, err = hooks.Authorize(context.Background(), second.Token)
+	require.NoError(t, err)
+}
+
+func TestIncomingHooks_ValidateSenderName(t *testing.T) {
+	hooks := services.NewIncomingHooksService(&fakeIncomingHooksRepo{}, 60, 10)
+
+	name, err := hooks.ValidateSenderName("  Deploy bot ")
+	require.NoError(t, err)
+	require.Equal(t, "Deploy bot", name)
+
+	_, err = hooks.ValidateSenderName(strings.Repeat("a", services.MaxSenderNameLen+1))
+	var validationErr *services.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Equal(t, "username", validationErr.Fields[0].Field)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"fmt"
+)
+
+const maxUserIdLen = 100
+
+type MembersService interface {
+	Role(ctx context.Context, chatId int64, userId string) (string, error)
+	IsAdmin(ctx context.Context, chatId int64, userId string) (bool, error)
+	SetRole(ctx context.Context, chatId int64, userId, role string) (*model.ChatMember, error)
+	Remove(ctx context.Context, chatId int64, userId string) error
+	List(ctx context.Context, chatId int64) ([]*model.ChatMember, error)
+}
+
+type membersService struct {
+	repo repository.MembersRepository
+}
+
+func NewMembersService(repo repository.MembersRepository) MembersService {
+	return &membersService{repo: repo}
+}
+
+func (s *membersService) Role(ctx context.Context, chatId int64, userId string) (string, error) {
+	if userId == "" {
+		return "", nil
+	}
+	return s.repo.Role(ctx, chatId, userId)
+}
+
+func (s *membersService) IsAdmin(ctx context.Context, chatId int64, userId string) (bool, error) {
+	role, err := s.Role(ctx, chatId, userId)
+	return role == model.RoleAdmin, err
+}
+
+func (s *membersService) SetRole(ctx context.Context, chatId int64, userId, role string) (*model.ChatMember, error) {
+	var fields []FieldError
+	if len(userId) > maxUserIdLen {
+		fields = append(fields, FieldError{Field: "user_id", Code: CodeTooLong, Message: fmt.Sprintf("user_id cannot be longer than %d characters", maxUserIdLen), Limit: maxUserIdLen})
+	}
+	if role != model.RoleAdmin && role != model.RoleMember {
+		fields = append(fields, FieldError{Field: "role", Code: CodeInvalid, Message: "role must be admin or member"})
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	return s.repo.Set(ctx, chatId, userId, role)
+}
+
+func (s *membersService) Remove(ctx context.Context, chatId int64, userId string) error {
+	return s.repo.Remove(ctx, chatId, userId)
+}
+
+func (s *membersService) List(ctx context.Context, chatId int64) ([]*model.ChatMember, error) {
+	return s.repo.List(ctx, chatId)
+}
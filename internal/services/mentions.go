@@ -0,0 +1,40 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxMentionsPerMessage bounds the mentions kept from one message; the rest
+// of the text is still posted as is.
+const maxMentionsPerMessage = 50
+
+// mentionRe matches "@name" at the start of the text or after a character
+// that cannot be part of a name, so e-mail addresses are not mentions.
+var mentionRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@-])@([\p{L}\p{N}_][\p{L}\p{N}_.:-]*)`)
+
+// ParseMentions finds the @mentions in a message text. "@all" and "@here"
+// address the chat, any other name is a user id. Trailing dots, dashes and
+// colons belong to the sentence, not to the name: "@bob: hi".
+func ParseMentions(text string) []model.Mention {
+	var mentions []model.Mention
+	for _, match := range mentionRe.FindAllStringSubmatchIndex(text, maxMentionsPerMessage) {
+		name := strings.TrimRight(text[match[2]:match[3]], ".:-")
+		at := match[2] - 1
+
+		mention := model.Mention{
+			Type:   model.MentionUser,
+			UserId: name,
+			Offset: utf8.RuneCountInString(text[:at]),
+			Length: 1 + utf8.RuneCountInString(name),
+		}
+		switch strings.ToLower(name) {
+		case model.MentionAll, model.MentionHere:
+			mention.Type, mention.UserId = strings.ToLower(name), ""
+		}
+		mentions = append(mentions, mention)
+	}
+	return mentions
+}
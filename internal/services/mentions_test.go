@@ -0,0 +1,35 @@
+package services_test
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/services"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMentions(t *testing.T) {
+	tests := []struct {
+		text     string
+		mentions []model.Mention
+	}{
+		{text: "no mentions here", mentions: nil},
+		{text: "mail alice@example.com", mentions: nil},
+		{text: "@bob: look", mentions: []model.Mention{{Type: model.MentionUser, UserId: "bob", Offset: 0, Length: 4}}},
+		{text: "ping @alice.smith and @bob.", mentions: []model.Mention{
+			{Type: model.MentionUser, UserId: "alice.smith", Offset: 5, Length: 12},
+			{Type: model.MentionUser, UserId: "bob", Offset: 22, Length: 4},
+		}},
+		{text: "Привет, @ALL и @here!", mentions: []model.Mention{
+			{Type: model.MentionAll, Offset: 8, Length: 4},
+			{Type: model.MentionHere, Offset: 15, Length: 5},
+		}},
+		{text: "(@bot:b-1)", mentions: []model.Mention{{Type: model.MentionUser, UserId: "bot:b-1", Offset: 1, Length: 8}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			require.Equal(t, test.mentions, services.ParseMentions(test.text))
+		})
+	}
+}
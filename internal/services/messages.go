@@ -5,49 +5,128 @@ import (
 	"chats-api/internal/repository"
 	"context"
 	"errors"
+	"slices"
+	"time"
 )
 
+const exportBatchSize = 500
+
+// maxReadBy caps the readers listed on each message; ReadCount counts all.
+const maxReadBy = 20
+
 type messagesService struct {
-	repo repository.MessagesRepository
+	repo   repository.MessagesRepository
+	reads  repository.ReadsRepository
+	online OnlineUsers
+	urls   *URLSigner
+}
+
+// OnlineUsers tells who is online in a chat, the users @here notifies. It
+// knows only the connections of this instance.
+type OnlineUsers interface {
+	Online(chatPublicId string, now time.Time) []string
 }
 
 type MessagesService interface {
-	ValidateMessageCreate(text string) error
-	CreateMessage(ctx context.Context, text string, chatId int) (*model.Message, error)
-	GetAllMessagesFromChat(id int, limit int) ([]*model.Message, error)
+	ValidateMessageCreate(text string) (string, error)
+	CreateMessage(ctx context.Context, input NewMessage) (*model.Message, error)
+	GetAllMessagesFromChat(ctx context.Context, id int64, page repository.MessagesPage) ([]*model.Message, error)
+	ExportMessages(ctx context.Context, chatId int64, filter repository.MessagesFilter, fn func(*model.Message) error) error
 }
 
-func NewMessagesRepository(repo repository.MessagesRepository) MessagesService {
-	return &messagesService{repo: repo}
+type NewMessage struct {
+	ChatId      int64
+	SenderId    string
+	SenderName  string
+	ClientMsgId string
+	Text        string
+	// AttachmentIds are public ids of the sender's unattached uploads to the chat.
+	AttachmentIds []string
+	// ReplyTo is the public id of the message in the chat this one answers.
+	ReplyTo string
+	// ExpiresIn makes the message disappear after it; zero leaves that to the
+	// chat.
+	ExpiresIn time.Duration
 }
 
-func (s *messagesService) ValidateMessageCreate(text string) error {
-	if len(text) == 0 {
-		return errors.New("message is empty")
-	}
+func NewMessagesRepository(repo repository.MessagesRepository, reads repository.ReadsRepository, online OnlineUsers, urls *URLSigner) MessagesService {
+	return &messagesService{repo: repo, reads: reads, online: online, urls: urls}
+}
 
-	if len(text) > 5000 {
-		return errors.New("message is too long")
+func (s *messagesService) ValidateMessageCreate(text string) (string, error) {
+	str, fieldErr := validateText("text", "message", text, MaxMessageTextLen, true)
+	if fieldErr != nil {
+		return "", &ValidationError{Fields: []FieldError{*fieldErr}}
 	}
 
-	return nil
+	return str, nil
 }
 
-func (s *messagesService) CreateMessage(ctx context.Context, text string, chatId int) (*model.Message, error) {
-	message := &model.Message{Text: text, ChatId: chatId}
+// CreateMessage stores a new message with the @mentions of its text and
+// notifies the users it mentions or replies to. When the sender already posted
+// a message with the same ClientMsgId to the chat, that message is returned
+// together with repository.ErrDuplicateMessage.
+func (s *messagesService) CreateMessage(ctx context.Context, input NewMessage) (*model.Message, error) {
+	message := &model.Message{
+		Text:     input.Text,
+		ChatId:   input.ChatId,
+		SenderId: input.SenderId,
+		Mentions: ParseMentions(input.Text),
+	}
+	if input.ClientMsgId != "" {
+		message.ClientMsgId = &input.ClientMsgId
+	}
+	if input.SenderName != "" {
+		message.SenderName = &input.SenderName
+	}
+	if input.ReplyTo != "" {
+		message.ReplyTo = &input.ReplyTo
+	}
+	if input.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(input.ExpiresIn).UTC()
+		message.ExpiresAt = &expiresAt
+	}
 
-	if err := s.repo.Create(ctx, message); err != nil {
+	var online repository.Online
+	if s.online != nil {
+		online = func(chatPublicId string) []string { return s.online.Online(chatPublicId, time.Now()) }
+	}
+
+	attachmentIds := slices.Compact(slices.Sorted(slices.Values(input.AttachmentIds)))
+	if err := s.repo.Create(ctx, message, attachmentIds, online); errors.Is(err, repository.ErrDuplicateMessage) {
+		s.urls.signMessages(message)
+		return message, err
+	} else if err != nil {
 		return nil, err
 	}
 
+	s.urls.signMessages(message)
 	return message, nil
 }
 
-func (s *messagesService) GetAllMessagesFromChat(id int, limit int) ([]*model.Message, error) {
-	messages, err := s.repo.GetAll(id, limit)
+func (s *messagesService) GetAllMessagesFromChat(ctx context.Context, id int64, page repository.MessagesPage) ([]*model.Message, error) {
+	messages, err := s.repo.GetAll(ctx, id, page)
+	if err != nil {
+		return nil, err
+	}
+	s.urls.signMessages(messages...)
+	if len(messages) == 0 {
+		return messages, nil
+	}
+
+	readers, err := s.reads.Readers(ctx, id, messages, maxReadBy)
 	if err != nil {
 		return nil, err
 	}
+	for _, m := range messages {
+		if r := readers[m.Seq]; r != nil {
+			m.ReadCount, m.ReadBy = r.Count, r.UserIds
+		}
+	}
 
 	return messages, nil
 }
+
+func (s *messagesService) ExportMessages(ctx context.Context, chatId int64, filter repository.MessagesFilter, fn func(*model.Message) error) error {
+	return s.repo.Each(ctx, chatId, filter, exportBatchSize, fn)
+}
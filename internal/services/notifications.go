@@ -0,0 +1,69 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+)
+
+type NotificationsService interface {
+	// ListNotifications returns the user's unread mentions and replies across
+	// all chats, newest first, with the messages they point to.
+	ListNotifications(ctx context.Context, userId, beforePublicId string, limit int) ([]*model.Notification, error)
+	MarkNotificationsRead(ctx context.Context, userId string, publicIds []string) (int64, error)
+	// NotifyPendingAll finishes the @all fan-outs that failed or were left by
+	// a crashed instance and returns how many it finished.
+	NotifyPendingAll(ctx context.Context) (int, error)
+}
+
+type notificationsService struct {
+	repo      repository.NotificationsRepository
+	messages  repository.MessagesRepository
+	urls      *URLSigner
+	batchSize int
+}
+
+func NewNotificationsService(repo repository.NotificationsRepository, messages repository.MessagesRepository, urls *URLSigner, batchSize int) NotificationsService {
+	return &notificationsService{repo: repo, messages: messages, urls: urls, batchSize: batchSize}
+}
+
+func (s *notificationsService) ListNotifications(ctx context.Context, userId, beforePublicId string, limit int) ([]*model.Notification, error) {
+	notifications, err := s.repo.ListUnread(ctx, userId, beforePublicId, limit)
+	if err != nil || len(notifications) == 0 {
+		return notifications, err
+	}
+
+	ids := make([]int64, len(notifications))
+	for i, n := range notifications {
+		ids[i] = n.MessageId
+	}
+	messages, err := s.messages.GetByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	s.urls.signMessages(messages...)
+
+	byId := make(map[int64]*model.Message, len(messages))
+	for _, m := range messages {
+		byId[m.Id] = m
+	}
+	for _, n := range notifications {
+		n.Message = byId[n.MessageId]
+	}
+	return notifications, nil
+}
+
+func (s *notificationsService) MarkNotificationsRead(ctx context.Context, userId string, publicIds []string) (int64, error) {
+	return s.repo.MarkRead(ctx, userId, publicIds)
+}
+
+func (s *notificationsService) NotifyPendingAll(ctx context.Context) (int, error) {
+	var notified int
+	for {
+		n, err := s.repo.NotifyPendingAll(ctx, s.batchSize)
+		notified += n
+		if err != nil || n < s.batchSize {
+			return notified, err
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"chats-api/internal/broker"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"expvar"
+	"strconv"
+	"time"
+)
+
+var (
+	outboxPublished = expvar.NewInt("outbox_events_published_total")
+	outboxFailures  = expvar.NewInt("outbox_publish_failures_total")
+)
+
+type OutboxService interface {
+	Relay(ctx context.Context) (int, error)
+	Cleanup(ctx context.Context) (int64, error)
+}
+
+// OutboxSettings: events are published to Subject + "." + event type, e.g.
+// "chats.message.created", BatchSize at a time, and published events are
+// kept for KeepFor.
+type OutboxSettings struct {
+	Subject   string
+	BatchSize int
+	KeepFor   time.Duration
+}
+
+type outboxService struct {
+	repo     repository.OutboxRepository
+	broker   broker.Broker
+	settings OutboxSettings
+}
+
+// NewOutboxService publishes to b. Without a broker events are never marked
+// published; they only feed real-time clients and are removed KeepFor after
+// they were created.
+func NewOutboxService(repo repository.OutboxRepository, b broker.Broker, settings OutboxSettings) OutboxService {
+	return &outboxService{repo: repo, broker: b, settings: settings}
+}
+
+// Relay publishes pending outbox events until none are left.
+func (s *outboxService) Relay(ctx context.Context) (int, error) {
+	if s.broker == nil {
+		return 0, nil
+	}
+
+	var total int
+	for {
+		published, err := s.repo.Publish(ctx, s.settings.BatchSize, func(events []*model.OutboxEvent) error {
+			messages := make([]broker.Message, len(events))
+			for i, e := range events {
+				messages[i] = broker.Message{
+					Id:      strconv.FormatInt(e.Id, 10),
+					Subject: s.settings.Subject + "." + e.Type,
+					Key:     e.Key,
+					Payload: []byte(e.Payload),
+				}
+			}
+			return s.broker.Publish(ctx, messages)
+		})
+		total += published
+		outboxPublished.Add(int64(published))
+		if err != nil {
+			outboxFailures.Add(1)
+			return total, err
+		}
+		if published < s.settings.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (s *outboxService) Cleanup(ctx context.Context) (int64, error) {
+	if s.broker == nil {
+		return s.repo.DeleteCreatedBefore(ctx, time.Now().Add(-s.settings.KeepFor))
+	}
+	return s.repo.DeletePublished(ctx, time.Now().Add(-s.settings.KeepFor))
+}
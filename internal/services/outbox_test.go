@@ -0,0 +1,130 @@
+package services_test
+
+import (
+	"chats-api/internal/broker"
+	"chats-api/internal/model"
+	"chats-api/internal/services"
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOutboxRepo struct {
+	events []*model.OutboxEvent
+}
+
+func (r *fakeOutboxRepo) Publish(ctx context.Context, limit int, publish func([]*model.OutboxEvent) error) (int, error) {
+	var pending []*model.OutboxEvent
+	for _, e := range r.events {
+		if e.PublishedAt == nil && len(pending) < limit {
+			pending = append(pending, e)
+		}
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	if err := publish(pending); err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	for _, e := range pending {
+		e.PublishedAt = &now
+	}
+	return len(pending), nil
+}
+
+func (r *fakeOutboxRepo) DeletePublished(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeOutboxRepo) DeleteCreatedBefore(ctx context.Context, createdBefore time.Time) (int64, error) {
+	var kept []*model.OutboxEvent
+	for _, e := range r.events {
+		if !e.CreatedAt.Before(createdBefore) {
+			kept = append(kept, e)
+		}
+	}
+	deleted := int64(len(r.events) - len(kept))
+	r.events = kept
+	return deleted, nil
+}
+
+func (r *fakeOutboxRepo) ListAfter(ctx context.Context, afterId int64, limit int) ([]*model.OutboxEvent, error) {
+	var events []*model.OutboxEvent
+	for _, e := range r.events {
+		if e.Position > afterId && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r *fakeOutboxRepo) LastId(ctx context.Context) (int64, error) {
+	if len(r.events) == 0 {
+		return 0, nil
+	}
+	return r.events[len(r.events)-1].Position, nil
+}
+
+type failingBroker struct {
+	broker.Broker
+	fail bool
+}
+
+func (b *failingBroker) Publish(ctx context.Context, messages []broker.Message) error {
+	if b.fail {
+		return errors.New("broker is down")
+	}
+	return b.Broker.Publish(ctx, messages)
+}
+
+func TestOutboxService_RelayPublishesAtLeastOnce(t *testing.T) {
+	repo := &fakeOutboxRepo{}
+	for i := range 5 {
+		repo.events = append(repo.events, &model.OutboxEvent{Id: int64(i + 1), Position: int64(i + 1), Type: "message.created", Key: "c-1", Payload: `{}`})
+	}
+
+	memory := broker.NewMemory()
+	var got []broker.Message
+	memory.Subscribe(func(m broker.Message) { got = append(got, m) })
+	b := &failingBroker{Broker: memory, fail: true}
+
+	svc := services.NewOutboxService(repo, b, services.OutboxSettings{Subject: "chats", BatchSize: 2, KeepFor: time.Hour})
+
+	published, err := svc.Relay(context.Background())
+	require.Error(t, err)
+	require.Zero(t, published)
+
+	b.fail = false
+	published, err = svc.Relay(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 5, published)
+
+	require.Len(t, got, 5)
+	for i, m := range got {
+		require.Equal(t, "chats.message.created", m.Subject)
+		require.Equal(t, "c-1", m.Key)
+		require.Equal(t, strconv.Itoa(i+1), m.Id)
+	}
+}
+
+func TestOutboxService_WithoutBrokerKeepsEventsUnpublished(t *testing.T) {
+	old := &model.OutboxEvent{Id: 1, Type: "message.created", Key: "c-1", Payload: `{}`, CreatedAt: time.Now().Add(-2 * time.Hour)}
+	fresh := &model.OutboxEvent{Id: 2, Type: "message.created", Key: "c-1", Payload: `{}`, CreatedAt: time.Now()}
+	repo := &fakeOutboxRepo{events: []*model.OutboxEvent{old, fresh}}
+	svc := services.NewOutboxService(repo, nil, services.OutboxSettings{Subject: "chats", BatchSize: 10, KeepFor: time.Hour})
+
+	published, err := svc.Relay(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, published)
+	require.Nil(t, fresh.PublishedAt)
+
+	deleted, err := svc.Cleanup(context.Background())
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted)
+	require.Equal(t, []*model.OutboxEvent{fresh}, repo.events)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+)
+
+type PinsService interface {
+	Pin(ctx context.Context, chatId int64, messagePublicId, userId string) (*model.Message, error)
+	Unpin(ctx context.Context, chatId int64, messagePublicId string) (*model.Message, error)
+	// ListPins returns the pinned messages of the chat in the order they were
+	// pinned.
+	ListPins(ctx context.Context, chatId int64) ([]*model.Message, error)
+}
+
+type TooManyPinsError struct {
+	Limit int
+}
+
+func (e *TooManyPinsError) Error() string {
+	return fmt.Sprintf("chat already has %d pinned messages", e.Limit)
+}
+
+type pinsService struct {
+	repo    repository.PinsRepository
+	maxPins int
+	urls    *URLSigner
+}
+
+func NewPinsService(repo repository.PinsRepository, maxPins int, urls *URLSigner) PinsService {
+	return &pinsService{repo: repo, maxPins: maxPins, urls: urls}
+}
+
+func (s *pinsService) Pin(ctx context.Context, chatId int64, messagePublicId, userId string) (*model.Message, error) {
+	message, err := s.repo.Pin(ctx, chatId, messagePublicId, userId, s.maxPins)
+	if errors.Is(err, repository.ErrTooManyPins) {
+		return nil, &TooManyPinsError{Limit: s.maxPins}
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.urls.signMessages(message)
+	return message, nil
+}
+
+func (s *pinsService) Unpin(ctx context.Context, chatId int64, messagePublicId string) (*model.Message, error) {
+	message, err := s.repo.Unpin(ctx, chatId, messagePublicId)
+	if err != nil {
+		return nil, err
+	}
+	s.urls.signMessages(message)
+	return message, nil
+}
+
+func (s *pinsService) ListPins(ctx context.Context, chatId int64) ([]*model.Message, error) {
+	messages, err := s.repo.List(ctx, chatId)
+	if err != nil {
+		return nil, err
+	}
+	s.urls.signMessages(messages...)
+	return messages, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// maxRateBuckets bounds the memory of a rateLimiter; full buckets carry no
+// state and are dropped first.
+const maxRateBuckets = 10_000
+
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limit exceeded, retry in %s", e.RetryAfter)
+}
+
+// rateLimiter is a token bucket per key: it allows burst requests at once and
+// refills at rate per second. Limits are per process.
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*rateBucket
+}
+
+type rateBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: map[string]*rateBucket{},
+	}
+}
+
+// allow takes a token for key, or reports how long until one is available.
+func (l *rateLimiter) allow(key string, now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRateBuckets {
+			l.dropFull(now)
+		}
+		b = &rateBucket{tokens: l.burst, at: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.at).Seconds()*l.rate)
+	b.at = now
+	if b.tokens < 1 {
+		wait := math.Ceil((1 - b.tokens) / l.rate)
+		return &RateLimitError{RetryAfter: time.Duration(wait) * time.Second}
+	}
+	b.tokens--
+	return nil
+}
+
+func (l *rateLimiter) dropFull(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.at).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
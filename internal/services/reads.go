@@ -0,0 +1,25 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+)
+
+type ReadsService interface {
+	// MarkRead moves the user's read receipt in the chat forward to seq, to
+	// the last message when seq is 0, and returns it with the unread count.
+	MarkRead(ctx context.Context, chatId int64, userId string, seq int64) (*model.ReadReceipt, int64, error)
+}
+
+type readsService struct {
+	repo repository.ReadsRepository
+}
+
+func NewReadsService(repo repository.ReadsRepository) ReadsService {
+	return &readsService{repo: repo}
+}
+
+func (s *readsService) MarkRead(ctx context.Context, chatId int64, userId string, seq int64) (*model.ReadReceipt, int64, error) {
+	return s.repo.MarkRead(ctx, chatId, userId, seq)
+}
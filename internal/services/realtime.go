@@ -0,0 +1,60 @@
+package services
+
+import (
+	"chats-api/internal/realtime"
+	"chats-api/internal/repository"
+	"context"
+	"sync"
+)
+
+type RealtimeService interface {
+	// Feed pushes the outbox events committed since the last call to the
+	// clients connected to this process and returns how many it pushed.
+	Feed(ctx context.Context) (int, error)
+}
+
+// realtimeService tails the outbox rather than the broker, so every replica
+// sees every event whichever replica wrote it. The cursor starts at the
+// newest event: clients catch up on older ones through sync.
+type realtimeService struct {
+	repo      repository.OutboxRepository
+	hub       *realtime.Hub
+	batchSize int
+
+	mu     sync.Mutex
+	cursor int64
+	ready  bool
+}
+
+func NewRealtimeService(repo repository.OutboxRepository, hub *realtime.Hub, batchSize int) RealtimeService {
+	return &realtimeService{repo: repo, hub: hub, batchSize: batchSize}
+}
+
+func (s *realtimeService) Feed(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.ready {
+		last, err := s.repo.LastId(ctx)
+		if err != nil {
+			return 0, err
+		}
+		s.cursor, s.ready = last, true
+	}
+
+	var total int
+	for {
+		events, err := s.repo.ListAfter(ctx, s.cursor, s.batchSize)
+		if err != nil {
+			return total, err
+		}
+		for _, e := range events {
+			s.hub.Publish(e.Key, []byte(e.Payload), nil)
+			s.cursor = e.Position
+		}
+		total += len(events)
+		if len(events) < s.batchSize {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package services_test
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/realtime"
+	"chats-api/internal/services"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRealtimeService_FeedStartsAtTheNewestEvent(t *testing.T) {
+	repo := &fakeOutboxRepo{events: []*model.OutboxEvent{{Id: 1, Position: 1, Key: "c-1", Payload: `"old"`}}}
+	hub := realtime.NewHub(8)
+	client := hub.Register("alice")
+	hub.Subscribe(client, "c-1")
+	feed := services.NewRealtimeService(repo, hub, 2)
+
+	fed, err := feed.Feed(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, fed)
+
+	for i := range 3 {
+		repo.events = append(repo.events, &model.OutboxEvent{Id: int64(i + 2), Position: int64(i + 2), Key: "c-1", Payload: `"new"`})
+	}
+	repo.events = append(repo.events, &model.OutboxEvent{Id: 5, Position: 5, Key: "c-2", Payload: `"elsewhere"`})
+
+	fed, err = feed.Feed(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 4, fed)
+	require.Len(t, client.Send(), 3)
+	require.Equal(t, []byte(`"new"`), <-client.Send())
+
+	fed, err = feed.Feed(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, fed)
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"expvar"
+	"time"
+)
+
+const (
+	RetentionReasonMaxAge      = "max_age"
+	RetentionReasonMaxMessages = "max_messages"
+)
+
+var (
+	retentionRuns           = expvar.NewInt("retention_runs_total")
+	retentionPurgedMessages = expvar.NewInt("retention_purged_messages_total")
+	retentionFailures       = expvar.NewInt("retention_failures_total")
+	expiredMessagesSwept    = expvar.NewInt("expired_messages_swept_total")
+)
+
+type RetentionService interface {
+	PurgeExpiredMessages(ctx context.Context) ([]RetentionPurge, error)
+	// SweepExpiredMessages deletes the messages past their expires_at, which
+	// readers stopped seeing the moment they expired.
+	SweepExpiredMessages(ctx context.Context) (int64, error)
+}
+
+// RetentionPurge reports messages removed from one chat by one rule.
+type RetentionPurge struct {
+	ChatPublicId string
+	Reason       string
+	Purged       int64
+}
+
+type retentionService struct {
+	chats     repository.ChatsRepository
+	messages  repository.MessagesRepository
+	batchSize int
+}
+
+func NewRetentionService(chats repository.ChatsRepository, messages repository.MessagesRepository, batchSize int) RetentionService {
+	return &retentionService{chats: chats, messages: messages, batchSize: batchSize}
+}
+
+func (s *retentionService) PurgeExpiredMessages(ctx context.Context) ([]RetentionPurge, error) {
+	retentionRuns.Add(1)
+
+	var purges []RetentionPurge
+	var afterId int64
+	for {
+		chats, err := s.chats.ListWithRetention(ctx, afterId, 100)
+		if err != nil {
+			retentionFailures.Add(1)
+			return purges, err
+		}
+
+		for _, chat := range chats {
+			chatPurges, err := s.purgeChat(ctx, chat)
+			purges = append(purges, chatPurges...)
+			if err != nil {
+				retentionFailures.Add(1)
+				return purges, err
+			}
+		}
+
+		if len(chats) < 100 {
+			return purges, nil
+		}
+		afterId = chats[len(chats)-1].Id
+	}
+}
+
+func (s *retentionService) purgeChat(ctx context.Context, chat *model.Chat) ([]RetentionPurge, error) {
+	var purges []RetentionPurge
+	record := func(reason string, purged int64) {
+		if purged > 0 {
+			retentionPurgedMessages.Add(purged)
+			purges = append(purges, RetentionPurge{ChatPublicId: chat.PublicId, Reason: reason, Purged: purged})
+		}
+	}
+
+	if maxAge := chat.Retention.MaxAgeSeconds; maxAge != nil {
+		// Policies stored before the limit was enforced may exceed it.
+		before := time.Now().Add(-time.Duration(min(*maxAge, MaxSeconds)) * time.Second)
+		purged, err := s.messages.PurgeOlderThan(ctx, chat.Id, before, s.batchSize)
+		record(RetentionReasonMaxAge, purged)
+		if err != nil {
+			return purges, err
+		}
+	}
+
+	if maxMessages := chat.Retention.MaxMessages; maxMessages != nil {
+		purged, err := s.messages.PurgeBeyondCount(ctx, chat.Id, *maxMessages, s.batchSize)
+		record(RetentionReasonMaxMessages, purged)
+		if err != nil {
+			return purges, err
+		}
+	}
+
+	return purges, nil
+}
+
+func (s *retentionService) SweepExpiredMessages(ctx context.Context) (int64, error) {
+	swept, err := s.messages.PurgeExpired(ctx, s.batchSize)
+	expiredMessagesSwept.Add(swept)
+	return swept, err
+}
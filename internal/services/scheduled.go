@@ -0,0 +1,172 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type ScheduledService interface {
+	// Schedule stores a message to be posted at sendAt. Attachments cannot be
+	// scheduled. When the sender already scheduled a message with the same
+	// ClientMsgId in the chat, that one is returned together with
+	// repository.ErrDuplicateMessage.
+	Schedule(ctx context.Context, input NewMessage, sendAt time.Time) (*model.ScheduledMessage, error)
+	ListScheduled(ctx context.Context, chatId int64, senderId string) ([]*model.ScheduledMessage, error)
+	UpdateScheduled(ctx context.Context, chatId int64, senderId, publicId string, update repository.ScheduledUpdate) (*model.ScheduledMessage, error)
+	CancelScheduled(ctx context.Context, chatId int64, senderId, publicId string) error
+	// DispatchScheduled posts the messages that are due and returns how many
+	// it posted.
+	DispatchScheduled(ctx context.Context) (int, error)
+}
+
+type scheduledService struct {
+	repo      repository.ScheduledRepository
+	messages  MessagesService
+	commands  CommandsService
+	lease     time.Duration
+	batchSize int
+}
+
+// NewScheduledService runs the commands of scheduled messages through
+// commands once they are posted; nil leaves them unanswered.
+func NewScheduledService(repo repository.ScheduledRepository, messages MessagesService, commands CommandsService, lease time.Duration, batchSize int) ScheduledService {
+	return &scheduledService{repo: repo, messages: messages, commands: commands, lease: lease, batchSize: batchSize}
+}
+
+func (s *scheduledService) Schedule(ctx context.Context, input NewMessage, sendAt time.Time) (*model.ScheduledMessage, error) {
+	message := &model.ScheduledMessage{
+		ChatId:   input.ChatId,
+		SenderId: input.SenderId,
+		Text:     input.Text,
+		SendAt:   sendAt.UTC(),
+	}
+	if input.SenderName != "" {
+		message.SenderName = &input.SenderName
+	}
+	if input.ClientMsgId != "" {
+		message.ClientMsgId = &input.ClientMsgId
+	}
+	if input.ReplyTo != "" {
+		message.ReplyTo = &input.ReplyTo
+	}
+	if input.ExpiresIn > 0 {
+		expiresIn := int64(input.ExpiresIn / time.Second)
+		message.ExpiresIn = &expiresIn
+	}
+
+	if err := s.repo.Create(ctx, message); errors.Is(err, repository.ErrDuplicateMessage) {
+		return message, err
+	} else if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (s *scheduledService) ListScheduled(ctx context.Context, chatId int64, senderId string) ([]*model.ScheduledMessage, error) {
+	return s.repo.List(ctx, chatId, senderId)
+}
+
+func (s *scheduledService) UpdateScheduled(ctx context.Context, chatId int64, senderId, publicId string, update repository.ScheduledUpdate) (*model.ScheduledMessage, error) {
+	return s.repo.Update(ctx, chatId, senderId, publicId, update)
+}
+
+func (s *scheduledService) CancelScheduled(ctx context.Context, chatId int64, senderId, publicId string) error {
+	return s.repo.Delete(ctx, chatId, senderId, publicId)
+}
+
+// DispatchScheduled posts due messages batch by batch and then runs their
+// commands, as the API does for messages posted right away. A message that
+// fails stays leased and is retried once the lease ends; a command that fails
+// is not, as its message is posted. The first error is returned after the
+// rest of the batch has been tried.
+func (s *scheduledService) DispatchScheduled(ctx context.Context) (int, error) {
+	var posted int
+	for {
+		due, err := s.repo.ClaimDue(ctx, time.Now(), s.lease, s.batchSize)
+		if err != nil || len(due) == 0 {
+			return posted, err
+		}
+
+		var firstErr error
+		for _, m := range due {
+			message, ok, err := s.post(ctx, m)
+			if err == nil && ok {
+				err = s.repo.Sent(ctx, m.Id)
+			}
+			if err == nil && ok {
+				posted++
+				err = s.runCommand(ctx, message)
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		if firstErr != nil || len(due) < s.batchSize {
+			return posted, firstErr
+		}
+	}
+}
+
+// post posts a scheduled message through the messages service, so it gets
+// its seq, mentions and notifications like any other. The client message id
+// makes a second attempt after a crash find the first one instead of posting
+// again. It reports false when the chat went to the trash meanwhile, and drops
+// the message when its sender may no longer post to the chat. The message is
+// returned only when this attempt posted it.
+func (s *scheduledService) post(ctx context.Context, m *model.ScheduledMessage) (*model.Message, bool, error) {
+	input := NewMessage{
+		ChatId:      m.ChatId,
+		SenderId:    m.SenderId,
+		ClientMsgId: m.PublicId,
+		Text:        m.Text,
+	}
+	if m.SenderName != nil {
+		input.SenderName = *m.SenderName
+	}
+	if m.ClientMsgId != nil {
+		input.ClientMsgId = *m.ClientMsgId
+	}
+	if m.ReplyTo != nil {
+		input.ReplyTo = *m.ReplyTo
+	}
+	if m.ExpiresIn != nil {
+		input.ExpiresIn = time.Duration(*m.ExpiresIn) * time.Second
+	}
+
+	message, err := s.messages.CreateMessage(ctx, input)
+	// The message replied to may have been deleted while this one waited;
+	// it is still worth posting.
+	if errors.Is(err, repository.ErrReplyNotFound) {
+		input.ReplyTo = ""
+		message, err = s.messages.CreateMessage(ctx, input)
+	}
+	switch {
+	case err == nil:
+		return message, true, nil
+	case errors.Is(err, repository.ErrDuplicateMessage):
+		return nil, true, nil
+	case errors.Is(err, repository.ErrChatNotFound):
+		return nil, false, nil
+	case errors.Is(err, repository.ErrChatReadOnly):
+		return nil, false, s.repo.Sent(ctx, m.Id)
+	default:
+		return nil, false, err
+	}
+}
+
+// runCommand answers the command a posted message starts with. A message
+// found posted by an earlier attempt is not answered again.
+func (s *scheduledService) runCommand(ctx context.Context, message *model.Message) error {
+	if s.commands == nil || message == nil {
+		return nil
+	}
+	if _, err := s.commands.Dispatch(ctx, message); err != nil {
+		return fmt.Errorf("command of message %s: %w", message.PublicId, err)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package services_test
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeScheduledRepo struct {
+	repository.ScheduledRepository
+	pending []*model.ScheduledMessage
+	sentErr error
+}
+
+func (r *fakeScheduledRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.ScheduledMessage, error) {
+	var due []*model.ScheduledMessage
+	for _, m := range r.pending {
+		if len(due) == limit || m.SendAt.After(now) || (m.ClaimedUntil != nil && m.ClaimedUntil.After(now)) {
+			continue
+		}
+		until := now.Add(lease)
+		m.ClaimedUntil = &until
+		claimed := *m
+		due = append(due, &claimed)
+	}
+	return due, nil
+}
+
+func (r *fakeScheduledRepo) Sent(ctx context.Context, id int64) error {
+	if r.sentErr != nil {
+		return r.sentErr
+	}
+	for i, m := range r.pending {
+		if m.Id == id {
+			r.pending = append(r.pending[:i], r.pending[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// dedupMessages posts messages the way the repository does: once per sender
+// and client message id.
+type dedupMessages struct {
+	services.MessagesService
+	posted []services.NewMessage
+	fail   map[string]error
+}
+
+func (m *dedupMessages) CreateMessage(ctx context.Context, input services.NewMessage) (*model.Message, error) {
+	if err := m.fail[input.Text]; err != nil {
+		if errors.Is(err, repository.ErrReplyNotFound) && input.ReplyTo == "" {
+			err = nil
+		} else {
+			return nil, err
+		}
+	}
+	for _, p := range m.posted {
+		if p.SenderId == input.SenderId && p.ClientMsgId == input.ClientMsgId {
+			return &model.Message{Text: p.Text}, repository.ErrDuplicateMessage
+		}
+	}
+	m.posted = append(m.posted, input)
+	return &model.Message{Text: input.Text}, nil
+}
+
+func scheduledAt(id int64, text string, sendAt time.Time) *model.ScheduledMessage {
+	return &model.ScheduledMessage{Id: id, PublicId: model.NewPublicId(), ChatId: 1, SenderId: "alice", Text: text, SendAt: sendAt}
+}
+
+func TestScheduledService_DispatchScheduled(t *testing.T) {
+	now := time.Now()
+	clientMsgId := "c-42"
+	replyTo := model.NewPublicId()
+	due := scheduledAt(1, "due", now.Add(-time.Minute))
+	expiresIn := int64(60)
+	due.ExpiresIn = &expiresIn
+	withClientId := scheduledAt(2, "with client id", now.Add(-time.Second))
+	withClientId.ClientMsgId = &clientMsgId
+	orphanReply := scheduledAt(3, "orphan reply", now.Add(-time.Second))
+	orphanReply.ReplyTo = &replyTo
+	later := scheduledAt(4, "later", now.Add(time.Hour))
+
+	repo := &fakeScheduledRepo{pending: []*model.ScheduledMessage{due, withClientId, orphanReply, later}}
+	messages := &dedupMessages{fail: map[string]error{"orphan reply": repository.ErrReplyNotFound}}
+	scheduled := services.NewScheduledService(repo, messages, nil, time.Minute, 2)
+
+	posted, err := scheduled.DispatchScheduled(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, posted)
+	require.Equal(t, []services.NewMessage{
+		{ChatId: 1, SenderId: "alice", ClientMsgId: due.PublicId, Text: "due", ExpiresIn: time.Minute},
+		{ChatId: 1, SenderId: "alice", ClientMsgId: "c-42", Text: "with client id"},
+		{ChatId: 1, SenderId: "alice", ClientMsgId: orphanReply.PublicId, Text: "orphan reply"},
+	}, messages.posted)
+	require.Equal(t, []*model.ScheduledMessage{later}, repo.pending)
+}
+
+func TestScheduledService_DispatchScheduled_RetriesWithoutPostingTwice(t *testing.T) {
+	now := time.Now()
+	repo := &fakeScheduledRepo{pending: []*model.ScheduledMessage{scheduledAt(1, "hello", now.Add(-time.Minute))}}
+	messages := &dedupMessages{}
+	scheduled := services.NewScheduledService(repo, messages, nil, time.Minute, 10)
+
+	// Posted, but the instance fails before dropping it from the schedule.
+	repo.sentErr = errors.New("connection lost")
+	_, err := scheduled.DispatchScheduled(context.Background())
+	require.ErrorIs(t, err, repo.sentErr)
+	require.Len(t, repo.pending, 1)
+
+	// Leased, so neither this nor another instance retries it yet.
+	repo.sentErr = nil
+	posted, err := scheduled.DispatchScheduled(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, posted)
+
+	// Once the lease is over the retry finds the posted message.
+	repo.pending[0].ClaimedUntil = nil
+	posted, err = scheduled.DispatchScheduled(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, posted)
+	require.Len(t, messages.posted, 1)
+	require.Empty(t, repo.pending)
+}
+
+func TestScheduledService_DispatchScheduled_KeepsFailedMessages(t *testing.T) {
+	now := time.Now()
+	broken := scheduledAt(1, "broken", now.Add(-time.Minute))
+	trashed := scheduledAt(2, "trashed", now.Add(-time.Minute))
+	fine := scheduledAt(3, "fine", now.Add(-time.Minute))
+	repo := &fakeScheduledRepo{pending: []*model.ScheduledMessage{broken, trashed, fine}}
+	failure := errors.New("boom")
+	messages := &dedupMessages{fail: map[string]error{"broken": failure, "trashed": repository.ErrChatNotFound}}
+	scheduled := services.NewScheduledService(repo, messages, nil, time.Minute, 10)
+
+	posted, err := scheduled.DispatchScheduled(context.Background())
+	require.ErrorIs(t, err, failure)
+	require.Equal(t, 1, posted)
+	require.Equal(t, []*model.ScheduledMessage{broken, trashed}, repo.pending)
+}
+
+func TestScheduledService_DispatchScheduled_DropsMessagesToReadOnlyChats(t *testing.T) {
+	now := time.Now()
+	demoted := scheduledAt(1, "demoted", now.Add(-time.Minute))
+	repo := &fakeScheduledRepo{pending: []*model.ScheduledMessage{demoted}}
+	messages := &dedupMessages{fail: map[string]error{"demoted": repository.ErrChatReadOnly}}
+	scheduled := services.NewScheduledService(repo, messages, nil, time.Minute, 10)
+
+	posted, err := scheduled.DispatchScheduled(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, posted)
+	require.Empty(t, repo.pending)
+}
+
+type recordingCommands struct {
+	texts []string
+	err   error
+}
+
+func (c *recordingCommands) Dispatch(ctx context.Context, message *model.Message) (*model.Message, error) {
+	c.texts = append(c.texts, message.Text)
+	return nil, c.err
+}
+
+func TestScheduledService_DispatchScheduled_RunsCommands(t *testing.T) {
+	now := time.Now()
+	poll := scheduledAt(1, `/poll "lunch?" "yes" "no"`, now.Add(-time.Minute))
+	again := scheduledAt(2, "/help", now.Add(-time.Minute))
+	repo := &fakeScheduledRepo{pending: []*model.ScheduledMessage{poll, again}}
+	messages := &dedupMessages{}
+	// Posted by an instance that died before dropping it from the schedule.
+	_, err := messages.CreateMessage(context.Background(), services.NewMessage{SenderId: "alice", ClientMsgId: again.PublicId, Text: "/help"})
+	require.NoError(t, err)
+	commands := &recordingCommands{err: errors.New("bot is down")}
+	scheduled := services.NewScheduledService(repo, messages, commands, time.Minute, 10)
+
+	posted, err := scheduled.DispatchScheduled(context.Background())
+	require.ErrorIs(t, err, commands.err)
+	require.Equal(t, 2, posted)
+	require.Equal(t, []string{poll.Text}, commands.texts)
+	require.Empty(t, repo.pending)
+}
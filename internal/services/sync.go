@@ -0,0 +1,176 @@
+package services
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"slices"
+	"time"
+)
+
+var ErrInvalidSyncToken = errors.New("invalid sync token")
+
+type SyncService interface {
+	// Sync pages through the changes after token that userId may see.
+	Sync(ctx context.Context, userId, token string, limit int) (*SyncResult, error)
+	PruneChanges(ctx context.Context) (int64, error)
+	// SequenceChanges makes committed changes and outbox events visible to
+	// their readers.
+	SequenceChanges(ctx context.Context) (int64, error)
+}
+
+type SyncResult struct {
+	Chats          SyncChats    `json:"chats"`
+	Messages       SyncMessages `json:"messages"`
+	Next           string       `json:"next"`
+	HasMore        bool         `json:"has_more"`
+	ResyncRequired bool         `json:"resync_required,omitempty"`
+}
+
+type SyncChats struct {
+	Upserted []*model.Chat `json:"upserted"`
+	Deleted  []string      `json:"deleted"`
+}
+
+type SyncMessages struct {
+	Created []*model.Message `json:"created"`
+	Edited  []*model.Message `json:"edited"`
+	Deleted []DeletedMessage `json:"deleted"`
+}
+
+type DeletedMessage struct {
+	Id     string
+	ChatId string
+}
+
+type syncService struct {
+	changes   repository.ChangesRepository
+	chats     repository.ChatsRepository
+	messages  repository.MessagesRepository
+	urls      *URLSigner
+	retention time.Duration
+}
+
+func NewSyncService(changes repository.ChangesRepository, chats repository.ChatsRepository,
+	messages repository.MessagesRepository, urls *URLSigner, retention time.Duration) SyncService {
+	return &syncService{
+		changes:   changes,
+		chats:     chats,
+		messages:  messages,
+		urls:      urls,
+		retention: retention,
+	}
+}
+
+func EncodeSyncToken(changeId int64) string {
+	return base64.RawURLEncoding.EncodeToString(binary.AppendUvarint(nil, uint64(changeId)))
+}
+
+func DecodeSyncToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, ErrInvalidSyncToken
+	}
+	id, n := binary.Uvarint(b)
+	if n != len(b) || id > 1<<63-1 {
+		return 0, ErrInvalidSyncToken
+	}
+
+	return int64(id), nil
+}
+
+func (s *syncService) Sync(ctx context.Context, userId, token string, limit int) (*SyncResult, error) {
+	since, err := DecodeSyncToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	prunedThrough, err := s.changes.PrunedThrough(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if since < prunedThrough {
+		lastId, err := s.changes.LastId(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &SyncResult{Next: EncodeSyncToken(lastId), ResyncRequired: true}, nil
+	}
+
+	changes, err := s.changes.ListSinceFor(ctx, userId, since, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SyncResult{
+		Chats:    SyncChats{Upserted: []*model.Chat{}, Deleted: []string{}},
+		Messages: SyncMessages{Created: []*model.Message{}, Edited: []*model.Message{}, Deleted: []DeletedMessage{}},
+		HasMore:  len(changes) > limit,
+	}
+	if result.HasMore {
+		changes = changes[:limit]
+	}
+
+	next := since
+	if len(changes) > 0 {
+		next = changes[len(changes)-1].Position
+	}
+	result.Next = EncodeSyncToken(next)
+
+	var upsertedChats, createdMessages, editedMessages []int64
+	for _, c := range changes {
+		switch {
+		case c.Entity == model.ChangeEntityChat && c.Action == model.ChangeDeleted:
+			upsertedChats = slices.DeleteFunc(upsertedChats, func(id int64) bool { return id == c.ChatId })
+			result.Chats.Deleted = append(result.Chats.Deleted, c.ChatPublicId)
+		case c.Entity == model.ChangeEntityChat:
+			if !slices.Contains(upsertedChats, c.ChatId) {
+				upsertedChats = append(upsertedChats, c.ChatId)
+			}
+		case c.MessageId == nil || c.MessagePublicId == nil:
+			continue
+		case c.Action == model.ChangeCreated:
+			createdMessages = append(createdMessages, *c.MessageId)
+		case c.Action == model.ChangeUpdated:
+			if !slices.Contains(createdMessages, *c.MessageId) && !slices.Contains(editedMessages, *c.MessageId) {
+				editedMessages = append(editedMessages, *c.MessageId)
+			}
+		case c.Action == model.ChangeDeleted:
+			isDeleted := func(id int64) bool { return id == *c.MessageId }
+			createdMessages = slices.DeleteFunc(createdMessages, isDeleted)
+			editedMessages = slices.DeleteFunc(editedMessages, isDeleted)
+			result.Messages.Deleted = append(result.Messages.Deleted, DeletedMessage{Id: *c.MessagePublicId, ChatId: c.ChatPublicId})
+		}
+	}
+
+	// Rows that no longer exist were deleted after this page; the deletion
+	// shows up in a later page, so they are simply left out here.
+	if result.Chats.Upserted, err = s.chats.GetByIds(ctx, upsertedChats); err != nil {
+		return nil, err
+	}
+	if result.Messages.Created, err = s.messages.GetByIds(ctx, createdMessages); err != nil {
+		return nil, err
+	}
+	if result.Messages.Edited, err = s.messages.GetByIds(ctx, editedMessages); err != nil {
+		return nil, err
+	}
+	s.urls.signMessages(result.Messages.Created...)
+	s.urls.signMessages(result.Messages.Edited...)
+
+	return result, nil
+}
+
+func (s *syncService) PruneChanges(ctx context.Context) (int64, error) {
+	return s.changes.Prune(ctx, time.Now().Add(-s.retention))
+}
+
+func (s *syncService) SequenceChanges(ctx context.Context) (int64, error) {
+	return s.changes.Sequence(ctx)
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"chats-api/internal/i18n"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Lengths are counted in characters (runes) after normalization, the same
+// unit as char_length() in the CHECK constraints of the migrations.
+const (
+	MaxChatTitleLen   = 200
+	MaxMessageTextLen = 5000
+)
+
+// MaxSeconds bounds durations given in seconds, like retention ages, to 100
+// years, far below where time.Duration overflows.
+const MaxSeconds = 100 * 365 * 24 * 60 * 60
+
+const (
+	zeroWidthNonJoiner = '\u200c'
+	zeroWidthJoiner    = '\u200d'
+)
+
+const (
+	CodeRequired    = i18n.ValidationRequired
+	CodeTooLong     = i18n.ValidationTooLong
+	CodeInvalidUTF8 = i18n.ValidationBadUTF8
+	CodeNotPositive = i18n.ValidationNotPositive
+	CodeInvalid     = i18n.ValidationInvalid
+	CodeTooLarge    = i18n.ValidationTooLarge
+)
+
+// FieldError describes one invalid field. Message is the English text used in
+// logs; handlers render a localized one from Code.
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+	Limit   int
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// validateSeconds checks an optional duration in seconds; nil passes.
+func validateSeconds(field string, seconds *int64) *FieldError {
+	switch {
+	case seconds == nil:
+		return nil
+	case *seconds <= 0:
+		return &FieldError{Field: field, Code: CodeNotPositive, Message: field + " must be positive"}
+	case *seconds > MaxSeconds:
+		return &FieldError{Field: field, Code: CodeTooLarge, Message: field + " cannot be greater than " + strconv.Itoa(MaxSeconds), Limit: MaxSeconds}
+	}
+	return nil
+}
+
+// normalizeText applies NFC normalization, drops control and invisible
+// zero-width characters and trims surrounding whitespace. Line breaks and tabs
+// survive only when multiline is set.
+func normalizeText(s string, multiline bool) string {
+	runes := []rune(s)
+	out := make([]rune, 0, len(runes))
+
+	for i, r := range runes {
+		switch {
+		case multiline && (r == '\n' || r == '\t'):
+		case r == '\r' && multiline:
+			continue
+		case r == '\n' || r == '\t' || r == '\r':
+			r = ' '
+		case unicode.IsControl(r), isZeroWidth(r):
+			continue
+		case r == zeroWidthNonJoiner || r == zeroWidthJoiner:
+			// Joiners shape emoji sequences and some scripts, so they are kept
+			// between visible characters and dropped anywhere else.
+			if i == 0 || i == len(runes)-1 || !isVisible(runes[i-1]) || !isVisible(runes[i+1]) {
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+
+	return strings.TrimSpace(norm.NFC.String(string(out)))
+}
+
+func isZeroWidth(r rune) bool {
+	switch r {
+	case '\u200b', '\u200e', '\u200f', '\u2060', '\u180e', '\ufeff':
+		return true
+	}
+	return false
+}
+
+func isVisible(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsControl(r) && !isZeroWidth(r) && r != zeroWidthNonJoiner && r != zeroWidthJoiner
+}
+
+func validateText(field, label, raw string, max int, multiline bool) (string, *FieldError) {
+	if !utf8.ValidString(raw) {
+		return "", &FieldError{Field: field, Code: CodeInvalidUTF8, Message: label + " is not valid UTF-8"}
+	}
+
+	text := normalizeText(raw, multiline)
+	if text == "" {
+		return "", &FieldError{Field: field, Code: CodeRequired, Message: label + " is required"}
+	}
+	if utf8.RuneCountInString(text) > max {
+		return "", &FieldError{
+			Field:   field,
+			Code:    CodeTooLong,
+			Message: label + " cannot be longer than " + strconv.Itoa(max) + " characters",
+			Limit:   max,
+		}
+	}
+
+	return text, nil
+}
@@ -0,0 +1,89 @@
+package services_test
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/services"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatsService_ValidateChatCreate(t *testing.T) {
+	tests := []struct {
+		name         string
+		title        string
+		expected     string
+		expectedCode string
+	}{
+		{name: "trims spaces", title: "  Family  ", expected: "Family"},
+		{name: "cyrillic title counted in characters", title: strings.Repeat("ж", 200), expected: strings.Repeat("ж", 200)},
+		{name: "too long", title: strings.Repeat("ж", 201), expectedCode: services.CodeTooLong},
+		{name: "only zero-width characters", title: "\u200b\ufeff", expectedCode: services.CodeRequired},
+		{name: "control characters removed", title: "Fam\x00i\x07ly\nchat", expected: "Family chat"},
+		{name: "NFC normalization", title: "Cafe\u0301", expected: "Caf\u00e9"},
+		{name: "zero-width space inside word removed", title: "Fa\u200bmily", expected: "Family"},
+		{name: "emoji joiner kept", title: "\U0001F468\u200d\U0001F469\u200d\U0001F467", expected: "\U0001F468\u200d\U0001F469\u200d\U0001F467"},
+		{name: "dangling joiner removed", title: "Chat\u200d", expected: "Chat"},
+	}
+
+	s := services.NewChatsRepository(nil, 0)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			title, err := s.ValidateChatCreate(test.title)
+
+			if test.expectedCode == "" {
+				require.NoError(t, err)
+				require.Equal(t, test.expected, title)
+				return
+			}
+
+			var validationErr *services.ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			require.Equal(t, "title", validationErr.Fields[0].Field)
+			require.Equal(t, test.expectedCode, validationErr.Fields[0].Code)
+		})
+	}
+}
+
+func TestMessagesService_ValidateMessageCreate(t *testing.T) {
+	s := services.NewMessagesRepository(nil, nil, nil, nil)
+
+	text, err := s.ValidateMessageCreate("line one\r\n\tline two\u200b ")
+	require.NoError(t, err)
+	require.Equal(t, "line one\n\tline two", text)
+
+	text, err = s.ValidateMessageCreate(strings.Repeat("я", 5000))
+	require.NoError(t, err)
+	require.Len(t, []rune(text), 5000)
+
+	_, err = s.ValidateMessageCreate(strings.Repeat("я", 5001))
+	require.ErrorContains(t, err, "message cannot be longer than 5000 characters")
+}
+
+func TestChatsService_SetRetention_BoundsMaxAge(t *testing.T) {
+	s := services.NewChatsRepository(nil, 0)
+
+	for _, maxAge := range []int64{0, services.MaxSeconds + 1, 10_000_000_000} {
+		_, err := s.SetRetention(context.Background(), 1, model.RetentionPolicy{MaxAgeSeconds: &maxAge})
+
+		var validationErr *services.ValidationError
+		require.True(t, errors.As(err, &validationErr), "max_age_seconds %d", maxAge)
+		require.Equal(t, "max_age_seconds", validationErr.Fields[0].Field)
+	}
+}
+
+func TestChatsService_SetExpiresIn_Bounds(t *testing.T) {
+	s := services.NewChatsRepository(nil, 0)
+
+	for _, expiresIn := range []int64{-1, services.MaxSeconds + 1} {
+		_, err := s.SetExpiresIn(context.Background(), 1, &expiresIn)
+
+		var validationErr *services.ValidationError
+		require.True(t, errors.As(err, &validationErr), "expires_in %d", expiresIn)
+		require.Equal(t, "expires_in", validationErr.Fields[0].Field)
+	}
+}
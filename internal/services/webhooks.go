@@ -0,0 +1,423 @@
+package services
+
+import (
+	"bytes"
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	mathrand "math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookEventHeader     = "X-Webhook-Event"
+
+	maxWebhookUrlLen     = 2000
+	webhookFanOutBatch   = 500
+	webhookClaimBatch    = 100
+	webhookParallelism   = 8
+	maxWebhookErrorLen   = 500
+	maxWebhookDeliveries = 100
+)
+
+// WebhookEvents lists the event types a webhook can subscribe to.
+var WebhookEvents = []string{
+	model.ChangeEntityChat + "." + model.ChangeCreated,
+	model.ChangeEntityChat + "." + model.ChangeUpdated,
+	model.ChangeEntityChat + "." + model.ChangeDeleted,
+	model.ChangeEntityMessage + "." + model.ChangeCreated,
+	model.ChangeEntityMessage + "." + model.ChangeUpdated,
+	model.ChangeEntityMessage + "." + model.ChangeDeleted,
+}
+
+var (
+	webhookDeliveriesQueued    = expvar.NewInt("webhook_deliveries_queued_total")
+	webhookDeliveriesSucceeded = expvar.NewInt("webhook_deliveries_succeeded_total")
+	webhookAttemptsFailed      = expvar.NewInt("webhook_attempts_failed_total")
+	webhookDeliveriesFailed    = expvar.NewInt("webhook_deliveries_failed_total")
+)
+
+type WebhooksService interface {
+	Create(ctx context.Context, input NewWebhook) (*model.Webhook, error)
+	List(ctx context.Context) ([]*model.Webhook, error)
+	Delete(ctx context.Context, publicId string) error
+	Enable(ctx context.Context, publicId string) (*model.Webhook, error)
+	ListDeliveries(ctx context.Context, publicId string, limit int) ([]*model.WebhookDelivery, error)
+	Dispatch(ctx context.Context) (WebhookDispatch, error)
+	PruneDeliveries(ctx context.Context) (int64, error)
+}
+
+type NewWebhook struct {
+	ChatPublicId string
+	Url          string
+	Events       []string
+}
+
+// WebhookSettings tune delivery. A delivery is retried MaxAttempts times with
+// exponential backoff from BackoffBase up to BackoffMax; a webhook is disabled
+// after DisableAfter failed attempts in a row.
+type WebhookSettings struct {
+	Timeout      time.Duration
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	DisableAfter int
+	KeepFor      time.Duration
+}
+
+// WebhookDispatch reports one dispatcher run.
+type WebhookDispatch struct {
+	Queued    int
+	Delivered int
+	Failed    int
+}
+
+type webhooksService struct {
+	repo     repository.WebhooksRepository
+	changes  repository.ChangesRepository
+	chats    repository.ChatsRepository
+	messages repository.MessagesRepository
+	client   *http.Client
+	settings WebhookSettings
+}
+
+func NewWebhooksService(repo repository.WebhooksRepository, changes repository.ChangesRepository,
+	chats repository.ChatsRepository, messages repository.MessagesRepository, settings WebhookSettings) WebhooksService {
+	return &webhooksService{
+		repo:     repo,
+		changes:  changes,
+		chats:    chats,
+		messages: messages,
+		client: &http.Client{
+			Timeout: settings.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		settings: settings,
+	}
+}
+
+func (s *webhooksService) Create(ctx context.Context, input NewWebhook) (*model.Webhook, error) {
+	var fields []FieldError
+	if input.Url == "" {
+		fields = append(fields, FieldError{Field: "url", Code: CodeRequired, Message: "url is required"})
+	} else if u, err := url.Parse(input.Url); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(input.Url) > maxWebhookUrlLen {
+		fields = append(fields, FieldError{Field: "url", Code: CodeInvalid, Message: "url must be an absolute http or https URL"})
+	}
+	for _, e := range input.Events {
+		if !slices.Contains(WebhookEvents, e) {
+			fields = append(fields, FieldError{Field: "events", Code: CodeInvalid, Message: fmt.Sprintf("unknown event %q", e)})
+			break
+		}
+	}
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	webhook := &model.Webhook{Url: input.Url, Events: slices.Compact(slices.Sorted(slices.Values(input.Events)))}
+	if webhook.Events == nil {
+		webhook.Events = []string{}
+	}
+	if input.ChatPublicId != "" {
+		chatId, err := s.chats.ResolveId(ctx, input.ChatPublicId)
+		if err != nil {
+			return nil, err
+		}
+		webhook.ChatId, webhook.ChatPublicId = &chatId, &input.ChatPublicId
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	webhook.Secret = "whsec_" + hex.EncodeToString(secret)
+
+	if err := s.repo.Create(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// List never shows secrets: they are returned once, on creation.
+func (s *webhooksService) List(ctx context.Context) ([]*model.Webhook, error) {
+	webhooks, err := s.repo.List(ctx)
+	for _, w := range webhooks {
+		w.Secret = ""
+	}
+	return webhooks, err
+}
+
+func (s *webhooksService) Delete(ctx context.Context, publicId string) error {
+	return s.repo.Delete(ctx, publicId)
+}
+
+func (s *webhooksService) Enable(ctx context.Context, publicId string) (*model.Webhook, error) {
+	webhook, err := s.repo.Enable(ctx, publicId)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func (s *webhooksService) ListDeliveries(ctx context.Context, publicId string, limit int) ([]*model.WebhookDelivery, error) {
+	webhook, err := s.repo.Get(ctx, publicId)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(ctx, webhook.Id, min(limit, maxWebhookDeliveries))
+}
+
+// Dispatch queues deliveries for new changes and then sends those that are
+// due. Events are delivered at least once and not necessarily in order.
+func (s *webhooksService) Dispatch(ctx context.Context) (WebhookDispatch, error) {
+	var result WebhookDispatch
+
+	for more := true; more; {
+		more = false
+		queued, err := s.repo.FanOut(ctx, func(lastChangeId int64) ([]*model.WebhookDelivery, int64, error) {
+			changes, err := s.changes.ListSince(ctx, lastChangeId, webhookFanOutBatch)
+			more = err == nil && len(changes) == webhookFanOutBatch
+			if err != nil || len(changes) == 0 {
+				return nil, lastChangeId, err
+			}
+			deliveries, err := s.buildDeliveries(ctx, changes)
+			return deliveries, changes[len(changes)-1].Position, err
+		})
+		result.Queued += queued
+		webhookDeliveriesQueued.Add(int64(queued))
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// A claimed batch is sent webhookParallelism at a time, each attempt
+	// within Timeout, so the lease covers every round plus one to spare.
+	// Outliving the lease would let another replica send the same deliveries.
+	rounds := (webhookClaimBatch + webhookParallelism - 1) / webhookParallelism
+	lease := time.Duration(rounds+1) * s.settings.Timeout
+
+	for {
+		deliveries, err := s.repo.ClaimDue(ctx, time.Now(), lease, webhookClaimBatch)
+		if err != nil || len(deliveries) == 0 {
+			return result, err
+		}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var firstErr error
+		sem := make(chan struct{}, webhookParallelism)
+		for _, d := range deliveries {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer func() { <-sem; wg.Done() }()
+				ok, err := s.deliver(ctx, d)
+
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if ok {
+					result.Delivered++
+				} else {
+					result.Failed++
+				}
+			}()
+		}
+		wg.Wait()
+
+		if firstErr != nil || len(deliveries) < webhookClaimBatch {
+			return result, firstErr
+		}
+	}
+}
+
+// buildDeliveries turns changes into deliveries for the webhooks that want
+// them.
+func (s *webhooksService) buildDeliveries(ctx context.Context, changes []*model.Change) ([]*model.WebhookDelivery, error) {
+	webhooks, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var chatIds, messageIds []int64
+	for _, c := range changes {
+		if c.Action == model.ChangeDeleted {
+			continue
+		}
+		if c.Entity == model.ChangeEntityChat {
+			chatIds = append(chatIds, c.ChatId)
+		} else if c.MessageId != nil {
+			messageIds = append(messageIds, *c.MessageId)
+		}
+	}
+	chats, err := s.chats.GetByIds(ctx, chatIds)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.messages.GetByIds(ctx, messageIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []*model.WebhookDelivery
+	now := time.Now()
+	for _, c := range changes {
+		var payload []byte
+		for _, w := range webhooks {
+			if !w.Matches(c) {
+				continue
+			}
+			if payload == nil {
+				if payload, err = json.Marshal(webhookEvent(c, chats, messages)); err != nil {
+					return nil, err
+				}
+			}
+			deliveries = append(deliveries, &model.WebhookDelivery{
+				WebhookId:     w.Id,
+				ChangeId:      c.Id,
+				EventType:     c.EventType(),
+				Payload:       string(payload),
+				Status:        model.DeliveryPending,
+				NextAttemptAt: &now,
+			})
+		}
+	}
+
+	return deliveries, nil
+}
+
+// webhookEvent describes a change. Entities deleted since the change are
+// left out, only their ids are sent.
+func webhookEvent(c *model.Change, chats []*model.Chat, messages []*model.Message) *model.Event {
+	if c.Action == model.ChangeDeleted {
+		return model.NewEvent(c, nil)
+	}
+
+	if c.Entity == model.ChangeEntityChat {
+		if i := slices.IndexFunc(chats, func(chat *model.Chat) bool { return chat.Id == c.ChatId }); i >= 0 {
+			return model.NewEvent(c, chats[i])
+		}
+	} else if c.MessageId != nil {
+		if i := slices.IndexFunc(messages, func(m *model.Message) bool { return m.Id == *c.MessageId }); i >= 0 {
+			return model.NewEvent(c, messages[i])
+		}
+	}
+	return model.NewEvent(c, nil)
+}
+
+// deliver makes one attempt and records it. It reports whether the endpoint
+// accepted the event.
+func (s *webhooksService) deliver(ctx context.Context, d *model.WebhookDelivery) (bool, error) {
+	started := time.Now()
+	attempt := &model.WebhookAttempt{CreatedAt: started.UTC()}
+
+	statusCode, err := s.post(ctx, d)
+	attempt.DurationMs = time.Since(started).Milliseconds()
+	if statusCode != 0 {
+		attempt.StatusCode = &statusCode
+	}
+
+	result := repository.DeliveryResult{DisableAfter: s.settings.DisableAfter}
+	switch {
+	case err != nil:
+		attempt.Error = truncate(err.Error(), maxWebhookErrorLen)
+	case statusCode < 200 || statusCode > 299:
+		attempt.Error = fmt.Sprintf("endpoint responded with status %d", statusCode)
+	default:
+		result.Succeeded = true
+	}
+
+	if result.Succeeded {
+		webhookDeliveriesSucceeded.Add(1)
+	} else {
+		webhookAttemptsFailed.Add(1)
+		if d.Attempts+1 < s.settings.MaxAttempts {
+			next := time.Now().Add(WebhookBackoff(d.Attempts+1, s.settings.BackoffBase, s.settings.BackoffMax))
+			result.NextAttemptAt = &next
+		} else {
+			webhookDeliveriesFailed.Add(1)
+		}
+	}
+
+	return result.Succeeded, s.repo.RecordAttempt(ctx, d, attempt, result)
+}
+
+func (s *webhooksService) post(ctx context.Context, d *model.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "chats-api-webhooks")
+	req.Header.Set(WebhookDeliveryHeader, d.PublicId)
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(d.Webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, nil
+}
+
+func (s *webhooksService) PruneDeliveries(ctx context.Context) (int64, error) {
+	return s.repo.PruneDeliveries(ctx, time.Now().Add(-s.settings.KeepFor))
+}
+
+// SignWebhook computes the signature header of a delivery: HMAC-SHA256 of
+// "timestamp.body" keyed with the webhook secret. Receivers recompute it and
+// reject stale timestamps to stop replays.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookBackoff is the delay before retry number attempt (from 1): base
+// doubled with every attempt, capped at limit, with up to 20% jitter so
+// retries of many deliveries spread out.
+func WebhookBackoff(attempt int, base, limit time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(delay, limit)
+	return delay - time.Duration(mathrand.Int64N(int64(delay)/5+1))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,167 @@
+package services_test
+
+import (
+	"chats-api/internal/model"
+	"chats-api/internal/repository"
+	"chats-api/internal/services"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeWebhooksRepo struct {
+	due      []*model.WebhookDelivery
+	attempts []*model.WebhookAttempt
+	results  []repository.DeliveryResult
+	lease    time.Duration
+}
+
+func (r *fakeWebhooksRepo) Create(ctx context.Context, webhook *model.Webhook) error { return nil }
+func (r *fakeWebhooksRepo) List(ctx context.Context) ([]*model.Webhook, error)       { return nil, nil }
+func (r *fakeWebhooksRepo) Get(ctx context.Context, publicId string) (*model.Webhook, error) {
+	return nil, repository.ErrWebhookNotFound
+}
+func (r *fakeWebhooksRepo) Delete(ctx context.Context, publicId string) error { return nil }
+func (r *fakeWebhooksRepo) Enable(ctx context.Context, publicId string) (*model.Webhook, error) {
+	return nil, nil
+}
+
+func (r *fakeWebhooksRepo) FanOut(ctx context.Context, fn func(int64) ([]*model.WebhookDelivery, int64, error)) (int, error) {
+	return 0, nil
+}
+
+func (r *fakeWebhooksRepo) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	due := r.due
+	r.due = nil
+	r.lease = lease
+	return due, nil
+}
+
+func (r *fakeWebhooksRepo) RecordAttempt(ctx context.Context, delivery *model.WebhookDelivery, attempt *model.WebhookAttempt, result repository.DeliveryResult) error {
+	r.attempts = append(r.attempts, attempt)
+	r.results = append(r.results, result)
+	return nil
+}
+
+func (r *fakeWebhooksRepo) ListDeliveries(ctx context.Context, webhookId int64, limit int) ([]*model.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (r *fakeWebhooksRepo) PruneDeliveries(ctx context.Context, createdBefore time.Time) (int64, error) {
+	return 0, nil
+}
+
+var webhookSettings = services.WebhookSettings{
+	Timeout:      time.Second,
+	MaxAttempts:  3,
+	BackoffBase:  time.Second,
+	BackoffMax:   time.Minute,
+	DisableAfter: 5,
+	KeepFor:      time.Hour,
+}
+
+func newDelivery(url string, attempts int) *model.WebhookDelivery {
+	return &model.WebhookDelivery{
+		Id:        1,
+		PublicId:  model.NewPublicId(),
+		WebhookId: 7,
+		EventType: "message.created",
+		Payload:   `{"id":"42","type":"message.created"}`,
+		Status:    model.DeliveryPending,
+		Attempts:  attempts,
+		Webhook:   &model.Webhook{Id: 7, Url: url, Secret: "whsec_test"},
+	}
+}
+
+func TestWebhooksService_DispatchSignsPayload(t *testing.T) {
+	var got *http.Request
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	repo := &fakeWebhooksRepo{due: []*model.WebhookDelivery{newDelivery(srv.URL, 0)}}
+	svc := services.NewWebhooksService(repo, nil, nil, nil, webhookSettings)
+
+	result, err := svc.Dispatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, services.WebhookDispatch{Delivered: 1}, result)
+
+	require.JSONEq(t, `{"id":"42","type":"message.created"}`, string(body))
+	require.Equal(t, "message.created", got.Header.Get(services.WebhookEventHeader))
+	timestamp, err := strconv.ParseInt(got.Header.Get(services.WebhookTimestampHeader), 10, 64)
+	require.NoError(t, err)
+	require.Equal(t, services.SignWebhook("whsec_test", timestamp, body), got.Header.Get(services.WebhookSignatureHeader))
+
+	require.Len(t, repo.results, 1)
+	require.True(t, repo.results[0].Succeeded)
+	// A full batch takes ceil(100/8) rounds of Timeout at most.
+	require.Greater(t, repo.lease, 13*webhookSettings.Timeout)
+	require.Equal(t, http.StatusNoContent, *repo.attempts[0].StatusCode)
+}
+
+func TestWebhooksService_DispatchSchedulesRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	repo := &fakeWebhooksRepo{due: []*model.WebhookDelivery{newDelivery(srv.URL, 0)}}
+	svc := services.NewWebhooksService(repo, nil, nil, nil, webhookSettings)
+
+	result, err := svc.Dispatch(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Failed)
+
+	res := repo.results[0]
+	require.False(t, res.Succeeded)
+	require.NotNil(t, res.NextAttemptAt)
+	require.WithinDuration(t, time.Now().Add(time.Second), *res.NextAttemptAt, time.Second)
+	require.Equal(t, 5, res.DisableAfter)
+	require.Contains(t, repo.attempts[0].Error, "500")
+}
+
+func TestWebhooksService_DispatchGivesUpAfterMaxAttempts(t *testing.T) {
+	repo := &fakeWebhooksRepo{due: []*model.WebhookDelivery{newDelivery("http://127.0.0.1:1", 2)}}
+	svc := services.NewWebhooksService(repo, nil, nil, nil, webhookSettings)
+
+	_, err := svc.Dispatch(context.Background())
+	require.NoError(t, err)
+
+	require.Nil(t, repo.results[0].NextAttemptAt)
+	require.Nil(t, repo.attempts[0].StatusCode)
+	require.NotEmpty(t, repo.attempts[0].Error)
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	base, limit := 10*time.Second, time.Hour
+
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 4: 80 * time.Second, 20: time.Hour, 100: time.Hour} {
+		got := services.WebhookBackoff(attempt, base, limit)
+		require.LessOrEqual(t, got, want, "attempt %d", attempt)
+		require.GreaterOrEqual(t, got, want*4/5, "attempt %d", attempt)
+	}
+}
+
+func TestWebhooksService_CreateValidates(t *testing.T) {
+	svc := services.NewWebhooksService(&fakeWebhooksRepo{}, nil, nil, nil, webhookSettings)
+
+	_, err := svc.Create(context.Background(), services.NewWebhook{Url: "ftp://example.com", Events: []string{"message.exploded"}})
+	var validationErr *services.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	require.Len(t, validationErr.Fields, 2)
+
+	webhook, err := svc.Create(context.Background(), services.NewWebhook{Url: "https://example.com/hook", Events: []string{"message.created", "chat.deleted", "message.created"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"chat.deleted", "message.created"}, webhook.Events)
+	require.Regexp(t, `^whsec_[0-9a-f]{64}$`, webhook.Secret)
+}
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS changes
+(
+    id         BIGSERIAL PRIMARY KEY,
+    entity     TEXT   NOT NULL,
+    action     TEXT   NOT NULL,
+    chat_id    BIGINT NOT NULL,
+    message_id BIGINT,
+    created_at TIMESTAMP DEFAULT now()
+);
+
+CREATE INDEX changes_created_at_idx ON changes (created_at);
+
+CREATE TABLE IF NOT EXISTS change_log_state
+(
+    id             BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK ( id ),
+    pruned_through BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO change_log_state DEFAULT VALUES;
+
+INSERT INTO changes (entity, action, chat_id, created_at)
+SELECT 'chat', 'created', id, created_at
+FROM chats
+ORDER BY id;
+
+INSERT INTO changes (entity, action, chat_id, message_id, created_at)
+SELECT 'message', 'created', chat_id, id, created_at
+FROM messages
+ORDER BY id;
+
+-- +goose Down
+DROP TABLE change_log_state;
+DROP TABLE changes;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE messages
+    ADD COLUMN sender_id     TEXT NOT NULL DEFAULT '',
+    ADD COLUMN client_msg_id TEXT;
+
+CREATE UNIQUE INDEX messages_client_msg_id_idx ON messages (chat_id, sender_id, client_msg_id)
+    WHERE client_msg_id IS NOT NULL;
+
+-- +goose Down
+DROP INDEX messages_client_msg_id_idx;
+
+ALTER TABLE messages
+    DROP COLUMN client_msg_id,
+    DROP COLUMN sender_id;
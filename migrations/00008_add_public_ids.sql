@@ -0,0 +1,70 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION uuid_v7_at(ts TIMESTAMPTZ) RETURNS UUID AS
+$$
+SELECT encode(
+               set_bit(
+                       set_bit(
+                               overlay(uuid_send(gen_random_uuid())
+                                       PLACING substring(int8send(floor(extract(EPOCH FROM ts) * 1000)::BIGINT) FROM 3)
+                                       FROM 1 FOR 6),
+                               52, 1),
+                       53, 1),
+               'hex')::UUID;
+$$ LANGUAGE SQL VOLATILE;
+-- +goose StatementEnd
+
+ALTER TABLE chats
+    ADD COLUMN public_id UUID DEFAULT uuid_v7_at(now());
+
+UPDATE chats
+SET public_id = uuid_v7_at(COALESCE(created_at, now()));
+
+ALTER TABLE chats
+    ALTER COLUMN public_id SET NOT NULL;
+
+CREATE UNIQUE INDEX chats_public_id_idx ON chats (public_id);
+
+ALTER TABLE messages
+    ADD COLUMN public_id UUID DEFAULT uuid_v7_at(now());
+
+UPDATE messages
+SET public_id = uuid_v7_at(COALESCE(created_at, now()));
+
+ALTER TABLE messages
+    ALTER COLUMN public_id SET NOT NULL,
+    ALTER COLUMN chat_id TYPE BIGINT;
+
+CREATE UNIQUE INDEX messages_public_id_idx ON messages (public_id);
+
+ALTER TABLE changes
+    ADD COLUMN chat_public_id    UUID,
+    ADD COLUMN message_public_id UUID;
+
+UPDATE changes
+SET chat_public_id = chats.public_id
+FROM chats
+WHERE chats.id = changes.chat_id;
+
+UPDATE changes
+SET message_public_id = messages.public_id
+FROM messages
+WHERE messages.id = changes.message_id;
+
+-- +goose Down
+ALTER TABLE changes
+    DROP COLUMN message_public_id,
+    DROP COLUMN chat_public_id;
+
+DROP INDEX messages_public_id_idx;
+
+ALTER TABLE messages
+    DROP COLUMN public_id,
+    ALTER COLUMN chat_id TYPE INT;
+
+DROP INDEX chats_public_id_idx;
+
+ALTER TABLE chats
+    DROP COLUMN public_id;
+
+DROP FUNCTION uuid_v7_at(TIMESTAMPTZ);
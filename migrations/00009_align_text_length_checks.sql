@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+DO
+$$
+    DECLARE
+        c RECORD;
+    BEGIN
+        FOR c IN SELECT conrelid::regclass AS tbl, conname
+                 FROM pg_constraint
+                 WHERE conrelid IN ('chats'::regclass, 'messages'::regclass)
+                   AND contype = 'c'
+            LOOP
+                EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', c.tbl, c.conname);
+            END LOOP;
+    END
+$$;
+-- +goose StatementEnd
+
+ALTER TABLE chats
+    ADD CONSTRAINT chats_title_length_check CHECK ( char_length(title) BETWEEN 1 AND 200 );
+
+ALTER TABLE messages
+    ADD CONSTRAINT messages_text_length_check CHECK ( char_length(text) BETWEEN 1 AND 5000 );
+
+-- +goose Down
+ALTER TABLE messages
+    DROP CONSTRAINT messages_text_length_check,
+    ADD CHECK ( length(text) > 0 AND length(text) < 5000 );
+
+ALTER TABLE chats
+    DROP CONSTRAINT chats_title_length_check,
+    ADD CHECK ( length(title) > 0 AND length(title) < 200 );
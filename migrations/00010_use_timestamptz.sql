@@ -0,0 +1,40 @@
+-- +goose Up
+-- Existing values were written as wall-clock time of the application server.
+-- They are interpreted in the session time zone, which the application sets
+-- from db.timezone, so configure it to the zone the servers used to run in.
+UPDATE chats
+SET created_at = now()::TIMESTAMP
+WHERE created_at IS NULL;
+
+ALTER TABLE chats
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN created_at SET DEFAULT now(),
+    ALTER COLUMN created_at SET NOT NULL;
+
+UPDATE messages
+SET created_at = now()::TIMESTAMP
+WHERE created_at IS NULL;
+
+ALTER TABLE messages
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN created_at SET DEFAULT now(),
+    ALTER COLUMN created_at SET NOT NULL;
+
+ALTER TABLE changes
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE current_setting('TimeZone'),
+    ALTER COLUMN created_at SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE changes
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE messages
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN created_at DROP DEFAULT,
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE chats
+    ALTER COLUMN created_at DROP NOT NULL,
+    ALTER COLUMN created_at DROP DEFAULT,
+    ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE current_setting('TimeZone');
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE chats
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+ALTER TABLE chats
+    DROP CONSTRAINT chats_title_key;
+
+CREATE UNIQUE INDEX chats_title_active_idx ON chats (title) WHERE deleted_at IS NULL;
+
+CREATE INDEX chats_deleted_at_idx ON chats (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- +goose Down
+DELETE
+FROM chats
+WHERE deleted_at IS NOT NULL;
+
+DROP INDEX chats_deleted_at_idx;
+
+DROP INDEX chats_title_active_idx;
+
+ALTER TABLE chats
+    ADD CONSTRAINT chats_title_key UNIQUE (title);
+
+ALTER TABLE chats
+    DROP COLUMN deleted_at;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE chats
+    ADD COLUMN retention_max_age_seconds BIGINT CHECK ( retention_max_age_seconds > 0 ),
+    ADD COLUMN retention_max_messages    BIGINT CHECK ( retention_max_messages > 0 );
+
+CREATE INDEX chats_retention_idx ON chats (id)
+    WHERE retention_max_age_seconds IS NOT NULL OR retention_max_messages IS NOT NULL;
+
+CREATE INDEX messages_chat_id_created_at_idx ON messages (chat_id, created_at);
+
+-- +goose Down
+DROP INDEX messages_chat_id_created_at_idx;
+
+DROP INDEX chats_retention_idx;
+
+ALTER TABLE chats
+    DROP COLUMN retention_max_messages,
+    DROP COLUMN retention_max_age_seconds;
@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE blobs (
+    key          TEXT PRIMARY KEY,
+    size         BIGINT      NOT NULL,
+    content_type TEXT        NOT NULL,
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE attachments (
+    id           BIGSERIAL PRIMARY KEY,
+    public_id    UUID        NOT NULL UNIQUE,
+    chat_id      BIGINT      NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    message_id   BIGINT REFERENCES messages (id) ON DELETE CASCADE,
+    uploader_id  TEXT        NOT NULL,
+    blob_key     TEXT        NOT NULL REFERENCES blobs (key),
+    file_name    TEXT        NOT NULL CHECK ( char_length(file_name) BETWEEN 1 AND 255 ),
+    content_type TEXT        NOT NULL,
+    size         BIGINT      NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX attachments_message_id_idx ON attachments (message_id);
+CREATE INDEX attachments_blob_key_idx ON attachments (blob_key);
+CREATE INDEX attachments_unattached_idx ON attachments (created_at) WHERE message_id IS NULL;
+
+-- A message may consist of attachments only.
+ALTER TABLE messages
+    DROP CONSTRAINT messages_text_length_check,
+    ADD CONSTRAINT messages_text_length_check CHECK ( char_length(text) <= 5000 );
+
+-- +goose Down
+ALTER TABLE messages
+    DROP CONSTRAINT messages_text_length_check,
+    ADD CONSTRAINT messages_text_length_check CHECK ( char_length(text) BETWEEN 1 AND 5000 ) NOT VALID;
+
+DROP TABLE attachments;
+
+DROP TABLE blobs;
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE attachments
+    ADD COLUMN width  INT,
+    ADD COLUMN height INT;
+
+CREATE TABLE attachment_variants (
+    id            BIGSERIAL PRIMARY KEY,
+    attachment_id BIGINT NOT NULL REFERENCES attachments (id) ON DELETE CASCADE,
+    name          TEXT   NOT NULL,
+    blob_key      TEXT   NOT NULL REFERENCES blobs (key),
+    content_type  TEXT   NOT NULL,
+    width         INT    NOT NULL,
+    height        INT    NOT NULL,
+    size          BIGINT NOT NULL,
+    UNIQUE (attachment_id, name)
+);
+
+CREATE INDEX attachment_variants_blob_key_idx ON attachment_variants (blob_key);
+
+-- +goose Down
+DROP TABLE attachment_variants;
+
+ALTER TABLE attachments
+    DROP COLUMN height,
+    DROP COLUMN width;
@@ -0,0 +1,58 @@
+-- +goose Up
+CREATE TABLE webhooks (
+    id                   BIGSERIAL PRIMARY KEY,
+    public_id            UUID        NOT NULL UNIQUE,
+    chat_id              BIGINT REFERENCES chats (id) ON DELETE CASCADE,
+    url                  TEXT        NOT NULL,
+    secret               TEXT        NOT NULL,
+    events               JSONB       NOT NULL DEFAULT '[]',
+    consecutive_failures INT         NOT NULL DEFAULT 0,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT now(),
+    disabled_at          TIMESTAMPTZ
+);
+
+-- Webhooks see changes made after they are fanned out, never the history.
+CREATE TABLE webhook_state (
+    id             BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK ( id ),
+    last_change_id BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO webhook_state (last_change_id)
+SELECT coalesce(max(id), 0)
+FROM changes;
+
+CREATE TABLE webhook_deliveries (
+    id              BIGSERIAL PRIMARY KEY,
+    public_id       UUID        NOT NULL UNIQUE,
+    webhook_id      BIGINT      NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    change_id       BIGINT      NOT NULL,
+    event_type      TEXT        NOT NULL,
+    payload         TEXT        NOT NULL,
+    status          TEXT        NOT NULL CHECK ( status IN ('pending', 'succeeded', 'failed') ),
+    attempts        INT         NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    last_error      TEXT        NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at    TIMESTAMPTZ,
+    UNIQUE (webhook_id, change_id)
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_created_at_idx ON webhook_deliveries (created_at);
+
+CREATE TABLE webhook_attempts (
+    id          BIGSERIAL PRIMARY KEY,
+    delivery_id BIGINT      NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+    status_code INT,
+    error       TEXT        NOT NULL DEFAULT '',
+    duration_ms BIGINT      NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_attempts_delivery_id_idx ON webhook_attempts (delivery_id);
+
+-- +goose Down
+DROP TABLE webhook_attempts;
+DROP TABLE webhook_deliveries;
+DROP TABLE webhook_state;
+DROP TABLE webhooks;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE outbox_events (
+    id           BIGSERIAL PRIMARY KEY,
+    type         TEXT        NOT NULL,
+    key          TEXT        NOT NULL,
+    payload      TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ
+);
+
+CREATE INDEX outbox_events_unpublished_idx ON outbox_events (id) WHERE published_at IS NULL;
+CREATE INDEX outbox_events_published_at_idx ON outbox_events (published_at) WHERE published_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE outbox_events;
@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE chat_members (
+    chat_id    BIGINT      NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    user_id    TEXT        NOT NULL,
+    role       TEXT        NOT NULL CHECK ( role IN ('admin', 'member') ),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX chat_members_user_id_idx ON chat_members (user_id);
+
+CREATE TABLE incoming_hooks (
+    id           BIGSERIAL PRIMARY KEY,
+    public_id    UUID        NOT NULL UNIQUE,
+    chat_id      BIGINT      NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    name         TEXT        NOT NULL CHECK ( char_length(name) <= 100 ),
+    token_hash   TEXT        NOT NULL UNIQUE,
+    created_by   TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX incoming_hooks_chat_id_idx ON incoming_hooks (chat_id);
+
+-- Messages posted by integrations carry the name they asked to be shown as.
+ALTER TABLE messages
+    ADD COLUMN sender_name TEXT CHECK ( char_length(sender_name) <= 80 );
+
+-- +goose Down
+ALTER TABLE messages
+    DROP COLUMN sender_name;
+
+DROP TABLE incoming_hooks;
+
+DROP TABLE chat_members;
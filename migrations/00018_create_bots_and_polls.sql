@@ -0,0 +1,52 @@
+-- +goose Up
+CREATE TABLE bots (
+    id         BIGSERIAL PRIMARY KEY,
+    public_id  UUID        NOT NULL UNIQUE,
+    name       TEXT        NOT NULL UNIQUE CHECK ( name ~ '^[a-z][a-z0-9_]{2,31}$' ),
+    endpoint   TEXT,
+    secret     TEXT        NOT NULL,
+    key_hash   TEXT        NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A command belongs to at most one bot, so dispatch is never ambiguous.
+CREATE TABLE bot_commands (
+    command     TEXT   PRIMARY KEY CHECK ( command ~ '^[a-z][a-z0-9_]{0,31}$' ),
+    bot_id      BIGINT NOT NULL REFERENCES bots (id) ON DELETE CASCADE,
+    description TEXT   NOT NULL DEFAULT '' CHECK ( char_length(description) <= 200 )
+);
+
+CREATE INDEX bot_commands_bot_id_idx ON bot_commands (bot_id);
+
+ALTER TABLE chats
+    ADD COLUMN topic TEXT NOT NULL DEFAULT '' CHECK ( char_length(topic) <= 250 );
+
+CREATE TABLE polls (
+    id         BIGSERIAL PRIMARY KEY,
+    chat_id    BIGINT      NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    question   TEXT        NOT NULL,
+    options    JSONB       NOT NULL,
+    created_by TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX polls_chat_id_idx ON polls (chat_id, id);
+
+CREATE TABLE poll_votes (
+    poll_id BIGINT NOT NULL REFERENCES polls (id) ON DELETE CASCADE,
+    user_id TEXT   NOT NULL,
+    option  INT    NOT NULL,
+    PRIMARY KEY (poll_id, user_id)
+);
+
+-- +goose Down
+DROP TABLE poll_votes;
+
+DROP TABLE polls;
+
+ALTER TABLE chats
+    DROP COLUMN topic;
+
+DROP TABLE bot_commands;
+
+DROP TABLE bots;
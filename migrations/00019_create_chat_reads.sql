@@ -0,0 +1,15 @@
+-- +goose Up
+-- Messages of a chat have gap-free seqs, so the unread count of a user is
+-- chats.last_seq - last_read_seq and never needs to count messages.
+CREATE TABLE chat_reads (
+    chat_id       BIGINT      NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    user_id       TEXT        NOT NULL,
+    last_read_seq BIGINT      NOT NULL CHECK ( last_read_seq >= 0 ),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX chat_reads_user_id_idx ON chat_reads (user_id);
+
+-- +goose Down
+DROP TABLE chat_reads;
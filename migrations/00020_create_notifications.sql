@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE messages
+    ADD COLUMN reply_to UUID,
+    ADD COLUMN mentions JSONB;
+
+-- A user gets one notification per message, whether mentioned, replied to
+-- or both.
+CREATE TABLE notifications (
+    id         BIGSERIAL PRIMARY KEY,
+    public_id  UUID        NOT NULL UNIQUE,
+    user_id    TEXT        NOT NULL,
+    chat_id    BIGINT      NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    message_id BIGINT      NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    kind       TEXT        NOT NULL CHECK ( kind IN ('mention', 'reply') ),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    read_at    TIMESTAMPTZ,
+    UNIQUE (user_id, message_id)
+);
+
+CREATE INDEX notifications_unread_idx ON notifications (user_id, public_id) WHERE read_at IS NULL;
+CREATE INDEX notifications_message_id_idx ON notifications (message_id);
+
+-- +goose Down
+DROP TABLE notifications;
+
+ALTER TABLE messages
+    DROP COLUMN mentions,
+    DROP COLUMN reply_to;
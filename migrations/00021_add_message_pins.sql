@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE messages
+    ADD COLUMN pinned_at TIMESTAMPTZ,
+    ADD COLUMN pinned_by TEXT;
+
+CREATE INDEX messages_pinned_idx ON messages (chat_id, pinned_at, id) WHERE pinned_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX messages_pinned_idx;
+
+ALTER TABLE messages
+    DROP COLUMN pinned_by,
+    DROP COLUMN pinned_at;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Messages waiting for their send_at. A dispatcher leases due ones by moving
+-- claimed_until forward and deletes them once posted.
+CREATE TABLE scheduled_messages (
+    id            BIGSERIAL PRIMARY KEY,
+    public_id     UUID        NOT NULL UNIQUE,
+    chat_id       BIGINT      NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    sender_id     TEXT        NOT NULL,
+    sender_name   TEXT,
+    client_msg_id TEXT,
+    text          TEXT        NOT NULL,
+    reply_to      UUID,
+    send_at       TIMESTAMPTZ NOT NULL,
+    claimed_until TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX scheduled_messages_due_idx ON scheduled_messages (send_at, id);
+CREATE INDEX scheduled_messages_sender_idx ON scheduled_messages (chat_id, sender_id, send_at);
+CREATE UNIQUE INDEX scheduled_messages_client_msg_id_idx ON scheduled_messages (chat_id, sender_id, client_msg_id)
+    WHERE client_msg_id IS NOT NULL;
+
+-- +goose Down
+DROP TABLE scheduled_messages;
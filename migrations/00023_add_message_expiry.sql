@@ -0,0 +1,25 @@
+-- +goose Up
+-- expires_in is in seconds. A chat's value applies to messages posted while it
+-- is set; each message keeps the expires_at it was posted with.
+ALTER TABLE chats
+    ADD COLUMN expires_in BIGINT CHECK ( expires_in > 0 );
+
+ALTER TABLE messages
+    ADD COLUMN expires_at TIMESTAMPTZ;
+
+ALTER TABLE scheduled_messages
+    ADD COLUMN expires_in BIGINT CHECK ( expires_in > 0 );
+
+CREATE INDEX messages_expires_at_idx ON messages (expires_at) WHERE expires_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX messages_expires_at_idx;
+
+ALTER TABLE scheduled_messages
+    DROP COLUMN expires_in;
+
+ALTER TABLE messages
+    DROP COLUMN expires_at;
+
+ALTER TABLE chats
+    DROP COLUMN expires_in;
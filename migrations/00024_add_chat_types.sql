@@ -0,0 +1,36 @@
+-- +goose Up
+-- Direct chats have no title; direct_key names their two users so there is
+-- one active direct chat per pair.
+ALTER TABLE chats
+    ADD COLUMN type       TEXT NOT NULL DEFAULT 'group' CHECK ( type IN ('group', 'direct', 'broadcast') ),
+    ADD COLUMN direct_key TEXT,
+    ADD CONSTRAINT chats_direct_key_check CHECK ( (type = 'direct') = (direct_key IS NOT NULL) );
+
+ALTER TABLE chats
+    DROP CONSTRAINT chats_title_length_check,
+    ADD CONSTRAINT chats_title_length_check CHECK ( type = 'direct' OR char_length(title) BETWEEN 1 AND 200 );
+
+-- Titles stay unique among the active chats of one type.
+DROP INDEX chats_title_active_idx;
+
+CREATE UNIQUE INDEX chats_title_active_idx ON chats (type, title) WHERE deleted_at IS NULL AND type <> 'direct';
+CREATE UNIQUE INDEX chats_direct_key_active_idx ON chats (direct_key) WHERE deleted_at IS NULL;
+
+-- +goose Down
+DELETE
+FROM chats
+WHERE type <> 'group';
+
+DROP INDEX chats_direct_key_active_idx;
+DROP INDEX chats_title_active_idx;
+
+CREATE UNIQUE INDEX chats_title_active_idx ON chats (title) WHERE deleted_at IS NULL;
+
+ALTER TABLE chats
+    DROP CONSTRAINT chats_title_length_check,
+    ADD CONSTRAINT chats_title_length_check CHECK ( char_length(title) BETWEEN 1 AND 200 );
+
+ALTER TABLE chats
+    DROP CONSTRAINT chats_direct_key_check,
+    DROP COLUMN direct_key,
+    DROP COLUMN type;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Durations are capped at 100 years, which keeps now() + interval and Go's
+-- time.Duration clear of overflow.
+UPDATE chats SET expires_in = 3153600000 WHERE expires_in > 3153600000;
+UPDATE chats SET retention_max_age_seconds = 3153600000 WHERE retention_max_age_seconds > 3153600000;
+UPDATE scheduled_messages SET expires_in = 3153600000 WHERE expires_in > 3153600000;
+
+ALTER TABLE chats
+    ADD CONSTRAINT chats_expires_in_max CHECK ( expires_in <= 3153600000 ),
+    ADD CONSTRAINT chats_retention_max_age_seconds_max CHECK ( retention_max_age_seconds <= 3153600000 );
+
+ALTER TABLE scheduled_messages
+    ADD CONSTRAINT scheduled_messages_expires_in_max CHECK ( expires_in <= 3153600000 );
+
+-- +goose Down
+ALTER TABLE scheduled_messages
+    DROP CONSTRAINT scheduled_messages_expires_in_max;
+
+ALTER TABLE chats
+    DROP CONSTRAINT chats_retention_max_age_seconds_max,
+    DROP CONSTRAINT chats_expires_in_max;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Lets a page of messages read only the receipts that reach it.
+CREATE INDEX chat_reads_chat_id_last_read_seq_idx ON chat_reads (chat_id, last_read_seq);
+
+-- +goose Down
+DROP INDEX chat_reads_chat_id_last_read_seq_idx;
@@ -0,0 +1,40 @@
+-- +goose Up
+-- Writers no longer serialize on a lock to keep ids in commit order. Instead
+-- one sequencer at a time gives committed rows a position, and readers page
+-- by position: a row that commits late gets a later position rather than
+-- slipping in behind one already read. Existing rows keep their id, so sync
+-- tokens and reader cursors stay valid.
+ALTER TABLE changes
+    ADD COLUMN position BIGINT;
+
+ALTER TABLE outbox_events
+    ADD COLUMN position BIGINT;
+
+UPDATE changes SET position = id;
+UPDATE outbox_events SET position = id;
+
+CREATE SEQUENCE changes_position_seq;
+CREATE SEQUENCE outbox_events_position_seq;
+
+SELECT setval('changes_position_seq', (SELECT GREATEST(max(id), 1) FROM changes), (SELECT max(id) IS NOT NULL FROM changes));
+SELECT setval('outbox_events_position_seq', (SELECT GREATEST(max(id), 1) FROM outbox_events), (SELECT max(id) IS NOT NULL FROM outbox_events));
+
+CREATE UNIQUE INDEX changes_position_idx ON changes (position);
+CREATE INDEX changes_unsequenced_idx ON changes (id) WHERE position IS NULL;
+CREATE UNIQUE INDEX outbox_events_position_idx ON outbox_events (position);
+CREATE INDEX outbox_events_unsequenced_idx ON outbox_events (id) WHERE position IS NULL;
+
+-- +goose Down
+DROP INDEX outbox_events_unsequenced_idx;
+DROP INDEX outbox_events_position_idx;
+DROP INDEX changes_unsequenced_idx;
+DROP INDEX changes_position_idx;
+
+DROP SEQUENCE outbox_events_position_seq;
+DROP SEQUENCE changes_position_seq;
+
+ALTER TABLE outbox_events
+    DROP COLUMN position;
+
+ALTER TABLE changes
+    DROP COLUMN position;
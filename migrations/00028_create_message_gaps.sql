@@ -0,0 +1,25 @@
+-- +goose Up
+-- Seqs are handed out without gaps, so a chat's messages past a receipt are
+-- chats.last_seq - last_read_seq less the seqs whose messages were deleted,
+-- kept here, and less the expired messages the sweeper has not deleted yet.
+-- Counting unread messages thus reads only gaps and disappearing messages.
+-- This replaces the note in 00019: seqs stay gap-free as they are handed
+-- out, but deleted messages leave holes.
+CREATE TABLE message_gaps (
+    chat_id BIGINT NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    seq     BIGINT NOT NULL,
+    PRIMARY KEY (chat_id, seq)
+);
+
+INSERT INTO message_gaps (chat_id, seq)
+SELECT c.id, s.seq
+FROM chats c
+         CROSS JOIN LATERAL generate_series(1, c.last_seq) AS s(seq)
+WHERE NOT EXISTS (SELECT 1 FROM messages m WHERE m.chat_id = c.id AND m.seq = s.seq);
+
+CREATE INDEX messages_chat_id_seq_expiring_idx ON messages (chat_id, seq) WHERE expires_at IS NOT NULL;
+
+-- +goose Down
+DROP INDEX messages_chat_id_seq_expiring_idx;
+
+DROP TABLE message_gaps;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- Retention and expiry mostly delete the oldest messages, so every seq up to
+-- purged_seq is gone and only the gaps above it are kept in message_gaps.
+-- Unread counts then read just the gaps in the middle of the history.
+ALTER TABLE chats
+    ADD COLUMN purged_seq BIGINT NOT NULL DEFAULT 0 CHECK ( purged_seq >= 0 );
+
+UPDATE chats
+SET purged_seq = COALESCE((SELECT min(m.seq) FROM messages m WHERE m.chat_id = chats.id) - 1, last_seq);
+
+DELETE FROM message_gaps
+USING chats
+WHERE chats.id = message_gaps.chat_id
+  AND message_gaps.seq <= chats.purged_seq;
+
+-- +goose Down
+INSERT INTO message_gaps (chat_id, seq)
+SELECT c.id, s.seq
+FROM chats c
+         CROSS JOIN LATERAL generate_series(1, c.purged_seq) AS s(seq)
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE chats
+    DROP COLUMN purged_seq;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- An @all is fanned out after its message is committed. The message waits
+-- here until everyone is notified, so a fan-out cut short by an error or a
+-- crash is retried by the notifier instead of being lost.
+CREATE TABLE pending_all_mentions (
+    message_id    BIGINT PRIMARY KEY REFERENCES messages (id) ON DELETE CASCADE,
+    claimed_until TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX pending_all_mentions_claimed_until_idx ON pending_all_mentions (claimed_until);
+
+-- +goose Down
+DROP TABLE pending_all_mentions;